@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// apiKeyRes is what clients see of a stored key. The hash never leaves the
+// server; the plaintext key is only included once, on creation.
+type apiKeyRes struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	Key        string     `json:"key,omitempty"`
+}
+
+func newAPIKeyRes(key database.ApiKey) apiKeyRes {
+	res := apiKeyRes{
+		ID:        key.ID,
+		CreatedAt: key.CreatedAt,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+	}
+	if key.LastUsedAt.Valid {
+		res.LastUsedAt = &key.LastUsedAt.Time
+	}
+	if key.RevokedAt.Valid {
+		res.RevokedAt = &key.RevokedAt.Time
+	}
+	return res
+}
+
+func (cfg *apiConfig) handleCreateAPIKey(w http.ResponseWriter, r *http.Request, caller principal) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	type parameters struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil || params.Name == "" {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	if err := auth.ValidateScopes(params.Scopes); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	key, prefix, hash, err := auth.MakeAPIKey()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not generate API key")
+		return
+	}
+
+	apiKey, err := cfg.database.CreateAPIKey(r.Context(), database.CreateAPIKeyParams{
+		UserID:  caller.UserID,
+		Name:    params.Name,
+		Prefix:  prefix,
+		KeyHash: hash,
+		Scopes:  params.Scopes,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not save API key")
+		return
+	}
+
+	res := newAPIKeyRes(apiKey)
+	res.Key = key
+	respondWithJSON(w, http.StatusCreated, res)
+}
+
+func (cfg *apiConfig) handleGetAPIKeys(w http.ResponseWriter, r *http.Request, caller principal) {
+	keys, err := cfg.database.GetAPIKeysForUser(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not list API keys")
+		return
+	}
+
+	res := make([]apiKeyRes, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, newAPIKeyRes(key))
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func (cfg *apiConfig) handleRevokeAPIKey(w http.ResponseWriter, r *http.Request, caller principal) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	revoked, err := cfg.database.RevokeAPIKey(r.Context(), database.RevokeAPIKeyParams{
+		ID:     id,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not revoke API key")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
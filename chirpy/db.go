@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// replicaReads are the queries safe to serve from a read replica. They back
+// the public timeline, which is where most of the read traffic goes.
+var replicaReads = []string{"GetChirps", "GetChirp", "GetChirpPreviews"}
+
+// poolConfig holds connection pool limits, applied to the primary and the
+// replica alike. Zero values keep database/sql's defaults.
+type poolConfig struct {
+	maxOpen     int
+	maxIdle     int
+	maxLifetime time.Duration
+	maxIdleTime time.Duration
+}
+
+func loadPoolConfig() (poolConfig, error) {
+	cfg := poolConfig{}
+
+	for _, setting := range []struct {
+		env string
+		dst *int
+	}{
+		{"DB_MAX_OPEN_CONNS", &cfg.maxOpen},
+		{"DB_MAX_IDLE_CONNS", &cfg.maxIdle},
+	} {
+		if v := os.Getenv(setting.env); v != "" {
+			parsed, err := strconv.Atoi(v)
+			if err != nil || parsed < 0 {
+				return poolConfig{}, fmt.Errorf("invalid %s: %q", setting.env, v)
+			}
+			*setting.dst = parsed
+		}
+	}
+
+	for _, setting := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", &cfg.maxLifetime},
+		{"DB_CONN_MAX_IDLE_TIME", &cfg.maxIdleTime},
+	} {
+		if v := os.Getenv(setting.env); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed < 0 {
+				return poolConfig{}, fmt.Errorf("invalid %s: %q", setting.env, v)
+			}
+			*setting.dst = parsed
+		}
+	}
+
+	return cfg, nil
+}
+
+func openDB(dsn string, pool poolConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if pool.maxOpen > 0 {
+		db.SetMaxOpenConns(pool.maxOpen)
+	}
+	if pool.maxIdle > 0 {
+		db.SetMaxIdleConns(pool.maxIdle)
+	}
+	if pool.maxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.maxLifetime)
+	}
+	if pool.maxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.maxIdleTime)
+	}
+
+	return db, nil
+}
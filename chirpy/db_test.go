@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// testDBEnv names a Postgres database the tests may migrate and write to.
+// Tests that need one are skipped when it isn't set.
+const testDBEnv = "CHIRPY_TEST_DB_URL"
+
+// testDB returns the migrated test database.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbURL := os.Getenv(testDBEnv)
+	if dbURL == "" {
+		t.Skipf("%s is not set", testDBEnv)
+	}
+
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("unexpected error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider, err := newMigrationProvider(db)
+	if err != nil {
+		t.Fatalf("unexpected error loading migrations: %v", err)
+	}
+	if err := prepareSchema(context.Background(), provider, true); err != nil {
+		t.Fatalf("unexpected error migrating: %v", err)
+	}
+	return db
+}
+
+// testTx returns queries inside a transaction on the test database that is
+// rolled back when the test ends, so tests leave nothing behind.
+func testTx(t *testing.T) (*sql.Tx, *database.Queries) {
+	t.Helper()
+
+	tx, err := testDB(t).Begin()
+	if err != nil {
+		t.Fatalf("unexpected error starting transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx, database.New(tx)
+}
+
+// createTestTenant adds a tenant on a host of its own.
+func createTestTenant(t *testing.T, q *database.Queries) database.Tenant {
+	t.Helper()
+
+	tenant, err := q.CreateTenant(context.Background(), database.CreateTenantParams{
+		Host:     uuid.NewString() + ".test",
+		Name:     "test",
+		Platform: "dev",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating tenant: %v", err)
+	}
+	return tenant
+}
+
+func createTestUser(t *testing.T, q *database.Queries, tenant database.Tenant, username string) database.User {
+	t.Helper()
+
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{
+		Email:          username + "@example.com",
+		HashedPassword: "unused",
+		TenantID:       tenant.ID,
+		Username:       sql.NullString{String: username, Valid: username != ""},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+	return user
+}
+
+// stubDB is a database that accepts every statement without storing
+// anything, counting what it was asked to do.
+type stubDB struct {
+	execs     atomic.Int64
+	commits   atomic.Int64
+	rollbacks atomic.Int64
+}
+
+func (s *stubDB) open(t *testing.T) *sql.DB {
+	db := sql.OpenDB(stubConnector{s})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type stubConnector struct {
+	db *stubDB
+}
+
+func (c stubConnector) Connect(context.Context) (driver.Conn, error) { return stubConn(c), nil }
+func (c stubConnector) Driver() driver.Driver                        { return nil }
+
+type stubConn struct {
+	db *stubDB
+}
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt(c), nil }
+func (c stubConn) Close() error                              { return nil }
+func (c stubConn) Begin() (driver.Tx, error)                 { return stubTx(c), nil }
+
+type stubStmt struct {
+	db *stubDB
+}
+
+func (s stubStmt) Close() error  { return nil }
+func (s stubStmt) NumInput() int { return -1 }
+
+func (s stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.execs.Add(1)
+	return driver.RowsAffected(0), nil
+}
+
+// Query finds nothing.
+func (s stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stubRows{}, nil
+}
+
+type stubRows struct{}
+
+func (stubRows) Columns() []string              { return nil }
+func (stubRows) Close() error                   { return nil }
+func (stubRows) Next(dest []driver.Value) error { return io.EOF }
+
+type stubTx struct {
+	db *stubDB
+}
+
+func (tx stubTx) Commit() error {
+	tx.db.commits.Add(1)
+	return nil
+}
+
+func (tx stubTx) Rollback() error {
+	tx.db.rollbacks.Add(1)
+	return nil
+}
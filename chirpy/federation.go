@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/activitypub"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxInboxBody    = 1 << 20
+	deliveryTimeout = 10 * time.Second
+)
+
+func (cfg *apiConfig) baseURL(tenant database.Tenant) string {
+	return cfg.publicScheme + "://" + tenant.Host
+}
+
+func (cfg *apiConfig) actorURI(tenant database.Tenant, username string) string {
+	return cfg.baseURL(tenant) + "/ap/users/" + username
+}
+
+func (cfg *apiConfig) noteURI(tenant database.Tenant, chirp database.Chirp) string {
+	return cfg.baseURL(tenant) + "/ap/notes/" + chirp.ID.String()
+}
+
+func respondWithActivity(w http.ResponseWriter, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", activitypub.ContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// federatedUser looks up the user named in the request path. Only users who
+// picked a username are visible to other servers.
+func (cfg *apiConfig) federatedUser(w http.ResponseWriter, r *http.Request, tenant database.Tenant) (database.User, bool) {
+	user, err := cfg.database.GetUserByUsername(r.Context(), database.GetUserByUsernameParams{
+		Username: sql.NullString{String: r.PathValue("username"), Valid: true},
+		TenantID: tenant.ID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "User not found")
+			return database.User{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return database.User{}, false
+	}
+
+	return user, true
+}
+
+// actorKey returns the user's signing key, creating one the first time the
+// user is seen by the fediverse.
+func (cfg *apiConfig) actorKey(ctx context.Context, user database.User) (database.ActorKey, error) {
+	key, err := cfg.database.GetActorKey(ctx, user.ID)
+	if err == nil {
+		return key, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.ActorKey{}, err
+	}
+
+	privatePEM, publicPEM, err := activitypub.GenerateKeyPair()
+	if err != nil {
+		return database.ActorKey{}, err
+	}
+
+	return cfg.database.EnsureActorKey(ctx, database.EnsureActorKeyParams{
+		UserID:        user.ID,
+		PublicKeyPem:  publicPEM,
+		PrivateKeyPem: privatePEM,
+	})
+}
+
+func (cfg *apiConfig) chirpToNote(tenant database.Tenant, username string, chirp database.Chirp) activitypub.Note {
+	actor := cfg.actorURI(tenant, username)
+	return activitypub.Note{
+		ID:           cfg.noteURI(tenant, chirp),
+		Type:         activitypub.TypeNote,
+		AttributedTo: actor,
+		Content:      "<p>" + html.EscapeString(chirp.Body) + "</p>",
+		Published:    chirp.CreatedAt.UTC().Format(time.RFC3339),
+		To:           []string{activitypub.Public},
+		Cc:           []string{actor + "/followers"},
+		URL:          cfg.baseURL(tenant) + "/api/chirps/" + chirp.ID.String(),
+	}
+}
+
+func (cfg *apiConfig) noteToCreate(note activitypub.Note) activitypub.Activity {
+	object, _ := json.Marshal(note)
+	return activitypub.Activity{
+		Context: activitypub.ActivityStreamsContext,
+		ID:      note.ID + "/activity",
+		Type:    activitypub.TypeCreate,
+		Actor:   note.AttributedTo,
+		Object:  object,
+		To:      note.To,
+		Cc:      note.Cc,
+	}
+}
+
+func (cfg *apiConfig) handleWebFinger(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	username, host, err := activitypub.ParseAcct(r.URL.Query().Get("resource"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if host != tenant.Host {
+		respondWithError(w, http.StatusNotFound, "User not found")
+		return
+	}
+
+	r.SetPathValue("username", username)
+	if _, ok := cfg.federatedUser(w, r, tenant); !ok {
+		return
+	}
+
+	data, err := json.Marshal(activitypub.NewWebFinger(
+		username+"@"+tenant.Host,
+		cfg.actorURI(tenant, username),
+		cfg.baseURL(tenant)+"/app/",
+	))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.Header().Set("Content-Type", activitypub.WebFingerContentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func (cfg *apiConfig) handleActor(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	user, ok := cfg.federatedUser(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	key, err := cfg.actorKey(r.Context(), user)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	id := cfg.actorURI(tenant, user.Username.String)
+	respondWithActivity(w, activitypub.Actor{
+		Context:           []string{activitypub.ActivityStreamsContext, activitypub.SecurityContext},
+		ID:                id,
+		Type:              activitypub.TypePerson,
+		PreferredUsername: user.Username.String,
+		Inbox:             id + "/inbox",
+		Outbox:            id + "/outbox",
+		Followers:         id + "/followers",
+		URL:               cfg.baseURL(tenant) + "/app/",
+		PublicKey: activitypub.PublicKey{
+			ID:           id + "#main-key",
+			Owner:        id,
+			PublicKeyPem: key.PublicKeyPem,
+		},
+	})
+}
+
+func (cfg *apiConfig) handleOutbox(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	user, ok := cfg.federatedUser(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	chirps, err := cfg.database.GetChirpsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	items := make([]interface{}, 0, len(chirps))
+	for _, chirp := range chirps {
+		items = append(items, cfg.noteToCreate(cfg.chirpToNote(tenant, user.Username.String, chirp)))
+	}
+
+	respondWithActivity(w, activitypub.NewOrderedCollection(cfg.actorURI(tenant, user.Username.String)+"/outbox", items))
+}
+
+func (cfg *apiConfig) handleFollowers(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	user, ok := cfg.federatedUser(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	followers, err := cfg.database.GetFollowers(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	items := make([]interface{}, 0, len(followers))
+	for _, follower := range followers {
+		items = append(items, follower.ActorUri)
+	}
+
+	respondWithActivity(w, activitypub.NewOrderedCollection(cfg.actorURI(tenant, user.Username.String)+"/followers", items))
+}
+
+func (cfg *apiConfig) handleNote(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	chirp, ok := cfg.getChirpForRequest(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	author, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       chirp.UserID,
+		TenantID: tenant.ID,
+	})
+	if err != nil || !author.Username.Valid {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
+
+	note := cfg.chirpToNote(tenant, author.Username.String, chirp)
+	note.Context = activitypub.ActivityStreamsContext
+	respondWithActivity(w, note)
+}
+
+func (cfg *apiConfig) handleInbox(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	user, ok := cfg.federatedUser(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxInboxBody))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	signer, err := activitypub.Verify(r, body, cfg.apClient)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	activity := activitypub.Activity{}
+	if err := json.Unmarshal(body, &activity); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if activity.Actor != signer {
+		respondWithError(w, http.StatusUnauthorized, "activity actor does not match signature")
+		return
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		err = cfg.acceptFollow(r.Context(), tenant, user, activity)
+	case activitypub.TypeUndo:
+		err = cfg.undoActivity(r.Context(), user, activity)
+	case activitypub.TypeCreate:
+		err = cfg.storeRemoteNote(r.Context(), tenant, user, activity)
+	case activitypub.TypeLike:
+		err = cfg.likeChirp(r.Context(), tenant, activity)
+	}
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (cfg *apiConfig) acceptFollow(ctx context.Context, tenant database.Tenant, user database.User, follow activitypub.Activity) error {
+	actorURI := cfg.actorURI(tenant, user.Username.String)
+	if follow.ObjectID() != actorURI {
+		return errors.New("follow is not addressed to this actor")
+	}
+
+	remote, err := cfg.apClient.FetchActor(ctx, follow.Actor)
+	if err != nil {
+		return err
+	}
+
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		if err := q.CreateFollower(ctx, database.CreateFollowerParams{
+			UserID:           user.ID,
+			ActorUri:         remote.ID,
+			InboxUri:         remote.Inbox,
+			FollowActivityID: follow.ID,
+		}); err != nil {
+			return err
+		}
+
+		return q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID: user.ID,
+			Type:   notificationFollow,
+			Actor:  remote.ID,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	key, err := cfg.actorKey(ctx, user)
+	if err != nil {
+		return err
+	}
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		return err
+	}
+
+	followObject, _ := json.Marshal(follow)
+	accept := activitypub.Activity{
+		Context: activitypub.ActivityStreamsContext,
+		ID:      actorURI + "#accepts/" + follow.ID,
+		Type:    activitypub.TypeAccept,
+		Actor:   actorURI,
+		Object:  followObject,
+	}
+
+	deliverCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+	defer cancel()
+	if err := cfg.apClient.Deliver(deliverCtx, remote.Inbox, actorURI+"#main-key", privateKey, accept); err != nil {
+		log.Printf("could not deliver Accept to %s: %s", remote.Inbox, err)
+	}
+
+	return nil
+}
+
+func (cfg *apiConfig) undoActivity(ctx context.Context, user database.User, undo activitypub.Activity) error {
+	// Only undoing a follow means anything to us, and only the follow we
+	// stored: the object is matched by id whether it was embedded or sent
+	// as a bare id, which could as well be a Like's.
+	switch undo.ObjectType() {
+	case activitypub.TypeFollow, "":
+		if undo.ObjectID() == "" {
+			return nil
+		}
+		_, err := cfg.database.DeleteFollowerByActivity(ctx, database.DeleteFollowerByActivityParams{
+			UserID:           user.ID,
+			ActorUri:         undo.Actor,
+			FollowActivityID: undo.ObjectID(),
+		})
+		return err
+	}
+	return nil
+}
+
+func (cfg *apiConfig) storeRemoteNote(ctx context.Context, tenant database.Tenant, user database.User, create activitypub.Activity) error {
+	note := activitypub.Note{}
+	if err := json.Unmarshal(create.Object, &note); err != nil || note.Type != activitypub.TypeNote {
+		// Creates of other object types are accepted and ignored.
+		return nil
+	}
+	if note.ID == "" || note.AttributedTo != create.Actor {
+		return errors.New("note must have an id and be attributed to the sender")
+	}
+
+	return cfg.withTx(ctx, func(q *database.Queries) error {
+		if err := q.CreateRemoteNote(ctx, database.CreateRemoteNoteParams{
+			UserID:    user.ID,
+			NoteUri:   note.ID,
+			ActorUri:  create.Actor,
+			Content:   note.Content,
+			InReplyTo: sql.NullString{String: note.InReplyTo, Valid: note.InReplyTo != ""},
+		}); err != nil {
+			return err
+		}
+
+		chirp, ok := cfg.localChirp(ctx, q, tenant, note.InReplyTo)
+		if !ok {
+			return nil
+		}
+
+		return q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:    chirp.UserID,
+			Type:      notificationReply,
+			Actor:     create.Actor,
+			ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+			RemoteUri: sql.NullString{String: note.ID, Valid: true},
+		})
+	})
+}
+
+func (cfg *apiConfig) likeChirp(ctx context.Context, tenant database.Tenant, like activitypub.Activity) error {
+	chirp, ok := cfg.localChirp(ctx, cfg.database, tenant, like.ObjectID())
+	if !ok {
+		return nil
+	}
+
+	return cfg.database.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:    chirp.UserID,
+		Type:      notificationLike,
+		Actor:     like.Actor,
+		ChirpID:   uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		RemoteUri: sql.NullString{String: like.ID, Valid: like.ID != ""},
+	})
+}
+
+// localChirp resolves one of our own note URIs back to its chirp.
+func (cfg *apiConfig) localChirp(ctx context.Context, q *database.Queries, tenant database.Tenant, uri string) (database.Chirp, bool) {
+	rawID, ok := strings.CutPrefix(uri, cfg.baseURL(tenant)+"/ap/notes/")
+	if !ok {
+		return database.Chirp{}, false
+	}
+
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return database.Chirp{}, false
+	}
+
+	chirp, err := q.GetChirp(ctx, database.GetChirpParams{ID: id, TenantID: tenant.ID})
+	if err != nil {
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+// federateChirp pushes a new chirp to the author's remote followers. It runs
+// in the background so posting a chirp never waits on other servers.
+func (cfg *apiConfig) federateChirp(tenant database.Tenant, chirp database.Chirp) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	author, err := cfg.database.GetTenantUser(ctx, database.GetTenantUserParams{
+		ID:       chirp.UserID,
+		TenantID: tenant.ID,
+	})
+	if err != nil || !author.Username.Valid {
+		return
+	}
+
+	followers, err := cfg.database.GetFollowers(ctx, author.ID)
+	if err != nil || len(followers) == 0 {
+		return
+	}
+
+	key, err := cfg.actorKey(ctx, author)
+	if err != nil {
+		log.Printf("could not load actor key for %s: %s", author.Username.String, err)
+		return
+	}
+	privateKey, err := activitypub.ParsePrivateKey(key.PrivateKeyPem)
+	if err != nil {
+		log.Printf("invalid actor key for %s: %s", author.Username.String, err)
+		return
+	}
+
+	create := cfg.noteToCreate(cfg.chirpToNote(tenant, author.Username.String, chirp))
+	keyID := cfg.actorURI(tenant, author.Username.String) + "#main-key"
+
+	// Followers on the same server share an inbox; deliver once per inbox.
+	delivered := make(map[string]bool)
+	for _, follower := range followers {
+		if delivered[follower.InboxUri] {
+			continue
+		}
+		delivered[follower.InboxUri] = true
+
+		if err := cfg.apClient.Deliver(ctx, follower.InboxUri, keyID, privateKey, create); err != nil {
+			log.Printf("could not deliver chirp %s to %s: %s", chirp.ID, follower.InboxUri, err)
+		}
+	}
+}
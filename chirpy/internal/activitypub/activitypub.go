@@ -0,0 +1,124 @@
+// Package activitypub implements the parts of ActivityPub, WebFinger and
+// HTTP Signatures chirpy needs to be followed from other servers.
+package activitypub
+
+import "encoding/json"
+
+const (
+	// ContentType is the media type used for ActivityPub documents.
+	ContentType = "application/activity+json"
+	// LDContentType is the alternative media type some servers ask for.
+	LDContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+
+	ActivityStreamsContext = "https://www.w3.org/ns/activitystreams"
+	SecurityContext        = "https://w3id.org/security/v1"
+
+	// Public is the special collection addressing everyone.
+	Public = "https://www.w3.org/ns/activitystreams#Public"
+)
+
+// Activity types chirpy sends or understands.
+const (
+	TypeFollow = "Follow"
+	TypeAccept = "Accept"
+	TypeUndo   = "Undo"
+	TypeCreate = "Create"
+	TypeLike   = "Like"
+	TypeNote   = "Note"
+	TypePerson = "Person"
+)
+
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+type Actor struct {
+	Context           []string  `json:"@context,omitempty"`
+	ID                string    `json:"id"`
+	Type              string    `json:"type"`
+	PreferredUsername string    `json:"preferredUsername"`
+	Name              string    `json:"name,omitempty"`
+	Inbox             string    `json:"inbox"`
+	Outbox            string    `json:"outbox"`
+	Followers         string    `json:"followers"`
+	URL               string    `json:"url,omitempty"`
+	PublicKey         PublicKey `json:"publicKey"`
+}
+
+type Note struct {
+	Context      interface{} `json:"@context,omitempty"`
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo string      `json:"attributedTo"`
+	Content      string      `json:"content"`
+	Published    string      `json:"published"`
+	To           []string    `json:"to,omitempty"`
+	Cc           []string    `json:"cc,omitempty"`
+	URL          string      `json:"url,omitempty"`
+	InReplyTo    string      `json:"inReplyTo,omitempty"`
+}
+
+// Activity is an incoming or outgoing activity. Object is kept raw since it
+// may be an embedded object or just an id, depending on the sender.
+type Activity struct {
+	Context interface{}     `json:"@context,omitempty"`
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Actor   string          `json:"actor"`
+	Object  json.RawMessage `json:"object"`
+	To      []string        `json:"to,omitempty"`
+	Cc      []string        `json:"cc,omitempty"`
+}
+
+type OrderedCollection struct {
+	Context      string        `json:"@context"`
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int           `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems"`
+}
+
+// NewOrderedCollection wraps items in an OrderedCollection served at id.
+func NewOrderedCollection(id string, items []interface{}) OrderedCollection {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return OrderedCollection{
+		Context:      ActivityStreamsContext,
+		ID:           id,
+		Type:         "OrderedCollection",
+		TotalItems:   len(items),
+		OrderedItems: items,
+	}
+}
+
+// ObjectID returns the id of an activity's object, whether it was sent as a
+// bare string or as an embedded object.
+func (a Activity) ObjectID() string {
+	var id string
+	if err := json.Unmarshal(a.Object, &id); err == nil {
+		return id
+	}
+
+	var obj struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(a.Object, &obj); err == nil {
+		return obj.ID
+	}
+	return ""
+}
+
+// ObjectType returns the type of an embedded object, or "" if the object was
+// sent by reference.
+func (a Activity) ObjectType() string {
+	var obj struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(a.Object, &obj); err != nil {
+		return ""
+	}
+	return obj.Type
+}
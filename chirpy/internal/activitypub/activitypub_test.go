@@ -0,0 +1,287 @@
+package activitypub_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/activitypub"
+	"github.com/ckm54/go-projects/chirpy/internal/netguard"
+)
+
+// fakeRemote is a stand-in for another fediverse server hosting one actor.
+type fakeRemote struct {
+	server   *httptest.Server
+	actor    activitypub.Actor
+	key      *rsa.PrivateKey
+	received []activitypub.Activity
+}
+
+func newFakeRemote(t *testing.T) *fakeRemote {
+	t.Helper()
+
+	privPEM, pubPEM, err := activitypub.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("unexpected error generating keys: %v", err)
+	}
+	key, err := activitypub.ParsePrivateKey(privPEM)
+	if err != nil {
+		t.Fatalf("unexpected error parsing key: %v", err)
+	}
+
+	remote := &fakeRemote{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/bob", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", activitypub.ContentType)
+		json.NewEncoder(w).Encode(remote.actor)
+	})
+	mux.HandleFunc("POST /users/bob/inbox", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var activity activitypub.Activity
+		if err := json.Unmarshal(body, &activity); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		remote.received = append(remote.received, activity)
+		w.WriteHeader(http.StatusAccepted)
+	})
+	remote.server = httptest.NewServer(mux)
+	t.Cleanup(remote.server.Close)
+
+	actorURI := remote.server.URL + "/users/bob"
+	remote.actor = activitypub.Actor{
+		ID:                actorURI,
+		Type:              activitypub.TypePerson,
+		PreferredUsername: "bob",
+		Inbox:             actorURI + "/inbox",
+		Outbox:            actorURI + "/outbox",
+		Followers:         actorURI + "/followers",
+		PublicKey: activitypub.PublicKey{
+			ID:           actorURI + "#main-key",
+			Owner:        actorURI,
+			PublicKeyPem: pubPEM,
+		},
+	}
+	return remote
+}
+
+// loopbackClient is a client that can reach the fake remotes, which
+// NewClient refuses to connect to.
+func loopbackClient() *activitypub.Client {
+	return &activitypub.Client{HTTP: &http.Client{Timeout: time.Second}, UserAgent: "chirpy"}
+}
+
+func signedRequest(t *testing.T, remote *fakeRemote, body []byte) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "https://chirpy.example/ap/users/alice/inbox", bytes.NewReader(body))
+	if err := activitypub.Sign(req, remote.actor.PublicKey.ID, remote.key, body); err != nil {
+		t.Fatalf("unexpected error signing request: %v", err)
+	}
+	return req
+}
+
+// Test that a request signed by a remote actor verifies against its published key.
+func TestVerify(t *testing.T) {
+	remote := newFakeRemote(t)
+	body := []byte(`{"type":"Follow"}`)
+	req := signedRequest(t, remote, body)
+
+	owner, err := activitypub.Verify(req, body, loopbackClient())
+	if err != nil {
+		t.Fatalf("unexpected error verifying signature: %v", err)
+	}
+	if owner != remote.actor.ID {
+		t.Fatalf("expected owner %s, got %s", remote.actor.ID, owner)
+	}
+}
+
+// Test that tampering with a signed request is detected.
+func TestVerify_Rejects(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := loopbackClient()
+	body := []byte(`{"type":"Follow"}`)
+
+	tests := []struct {
+		name   string
+		tamper func(r *http.Request) []byte
+	}{
+		{
+			name:   "modified body",
+			tamper: func(r *http.Request) []byte { return []byte(`{"type":"Undo"}`) },
+		},
+		{
+			name: "different path",
+			tamper: func(r *http.Request) []byte {
+				r.URL.Path = "/ap/users/mallory/inbox"
+				return body
+			},
+		},
+		{
+			name: "stale date",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Set("Date", time.Now().Add(-48*time.Hour).UTC().Format(http.TimeFormat))
+				return body
+			},
+		},
+		{
+			name: "missing signature",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Del("Signature")
+				return body
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := signedRequest(t, remote, body)
+			sentBody := tt.tamper(req)
+
+			if _, err := activitypub.Verify(req, sentBody, client); err == nil {
+				t.Fatalf("expected verification to fail")
+			}
+		})
+	}
+}
+
+// Test that a server can't sign with its own key on behalf of an actor it
+// doesn't host.
+func TestVerify_RejectsSpoofedKeys(t *testing.T) {
+	const victim = "https://victim.example/users/alice"
+
+	tests := []struct {
+		name  string
+		spoof func(actor *activitypub.Actor)
+	}{
+		{
+			name:  "actor id of another server",
+			spoof: func(actor *activitypub.Actor) { actor.ID = victim },
+		},
+		{
+			name:  "key owned by another actor",
+			spoof: func(actor *activitypub.Actor) { actor.PublicKey.Owner = victim },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newFakeRemote(t)
+			tt.spoof(&remote.actor)
+			body := []byte(`{"type":"Follow","actor":"` + victim + `"}`)
+			req := signedRequest(t, remote, body)
+
+			if owner, err := activitypub.Verify(req, body, loopbackClient()); err == nil {
+				t.Fatalf("expected verification to fail, got owner %s", owner)
+			}
+		})
+	}
+}
+
+// Test that the default client won't fetch a keyId on a private address.
+func TestNewClient_RefusesPrivateAddresses(t *testing.T) {
+	remote := newFakeRemote(t)
+	body := []byte(`{"type":"Follow"}`)
+	req := signedRequest(t, remote, body)
+
+	_, err := activitypub.Verify(req, body, activitypub.NewClient(time.Second))
+	if !errors.Is(err, netguard.ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+// Test that Deliver posts a signed activity the remote can verify.
+func TestDeliver(t *testing.T) {
+	remote := newFakeRemote(t)
+	client := loopbackClient()
+
+	// Chirpy's own actor, which the fake remote dereferences via a second
+	// fake server to check the signature.
+	local := newFakeRemote(t)
+	var verifyErr error
+	remote.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, verifyErr = activitypub.Verify(r, body, client)
+		var activity activitypub.Activity
+		json.Unmarshal(body, &activity)
+		remote.received = append(remote.received, activity)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	accept := activitypub.Activity{
+		ID:     local.actor.ID + "#accepts/1",
+		Type:   activitypub.TypeAccept,
+		Actor:  local.actor.ID,
+		Object: json.RawMessage(`"https://remote.example/follows/1"`),
+	}
+
+	err := client.Deliver(context.Background(), remote.actor.Inbox, local.actor.PublicKey.ID, local.key, accept)
+	if err != nil {
+		t.Fatalf("unexpected error delivering: %v", err)
+	}
+	if verifyErr != nil {
+		t.Fatalf("remote could not verify delivery: %v", verifyErr)
+	}
+	if len(remote.received) != 1 || remote.received[0].Type != activitypub.TypeAccept {
+		t.Fatalf("expected remote to receive one Accept, got %+v", remote.received)
+	}
+	if got := remote.received[0].ObjectID(); got != "https://remote.example/follows/1" {
+		t.Fatalf("expected object id to round trip, got %s", got)
+	}
+}
+
+// Test parsing WebFinger resources.
+func TestParseAcct(t *testing.T) {
+	tests := []struct {
+		resource     string
+		wantUsername string
+		wantHost     string
+		wantErr      bool
+	}{
+		{resource: "acct:alice@chirpy.example", wantUsername: "alice", wantHost: "chirpy.example"},
+		{resource: "@alice@Chirpy.Example", wantUsername: "alice", wantHost: "chirpy.example"},
+		{resource: "acct:alice", wantErr: true},
+		{resource: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			username, host, err := activitypub.ParseAcct(tt.resource)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if username != tt.wantUsername || host != tt.wantHost {
+				t.Fatalf("expected %s@%s, got %s@%s", tt.wantUsername, tt.wantHost, username, host)
+			}
+		})
+	}
+}
+
+// Test reading the object of an activity sent embedded or by reference.
+func TestActivityObject(t *testing.T) {
+	byRef := activitypub.Activity{Object: json.RawMessage(`"https://remote.example/notes/1"`)}
+	if got := byRef.ObjectID(); got != "https://remote.example/notes/1" {
+		t.Fatalf("expected object id from string, got %q", got)
+	}
+
+	embedded := activitypub.Activity{Object: json.RawMessage(`{"id":"https://remote.example/follows/1","type":"Follow"}`)}
+	if got := embedded.ObjectID(); got != "https://remote.example/follows/1" {
+		t.Fatalf("expected object id from embedded object, got %q", got)
+	}
+	if got := embedded.ObjectType(); got != activitypub.TypeFollow {
+		t.Fatalf("expected embedded object type Follow, got %q", got)
+	}
+}
@@ -0,0 +1,142 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/netguard"
+)
+
+// maxDocumentSize caps how much of a remote document we are willing to read.
+const maxDocumentSize = 1 << 20
+
+// Client talks to remote ActivityPub servers.
+type Client struct {
+	HTTP      *http.Client
+	UserAgent string
+}
+
+// NewClient returns a client that only connects to public addresses. Actor
+// and inbox URLs come from remote servers, and a signature's keyId from
+// whoever sent the request, so they mustn't reach internal services.
+func NewClient(timeout time.Duration) *Client {
+	transport := &http.Transport{
+		DialContext:           netguard.Dialer(timeout, netguard.IsPublic).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	return &Client{
+		HTTP:      &http.Client{Transport: transport, Timeout: timeout},
+		UserAgent: "chirpy",
+	}
+}
+
+// FetchActor retrieves a remote actor document.
+func (c *Client) FetchActor(ctx context.Context, uri string) (Actor, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return Actor{}, err
+	}
+	req.Header.Set("Accept", ContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return Actor{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Actor{}, fmt.Errorf("fetching actor %s: %s", uri, resp.Status)
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocumentSize)).Decode(&actor); err != nil {
+		return Actor{}, fmt.Errorf("decoding actor %s: %w", uri, err)
+	}
+	if actor.ID == "" || actor.Inbox == "" {
+		return Actor{}, fmt.Errorf("actor %s is missing an id or inbox", uri)
+	}
+
+	return actor, nil
+}
+
+// PublicKey implements KeyFetcher by dereferencing the actor that owns keyID.
+// The actor document must be the one keyID points at and must own the key,
+// so a server can't publish its own key under someone else's actor id.
+func (c *Client) PublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, string, error) {
+	actorURI, _, _ := strings.Cut(keyID, "#")
+	actor, err := c.FetchActor(ctx, actorURI)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if actor.ID != actorURI || !sameHost(actor.ID, keyID) {
+		return nil, "", fmt.Errorf("actor document at %s claims to be %s", actorURI, actor.ID)
+	}
+	if actor.PublicKey.ID != keyID {
+		return nil, "", fmt.Errorf("actor %s does not publish key %s", actor.ID, keyID)
+	}
+	if actor.PublicKey.Owner != actor.ID {
+		return nil, "", fmt.Errorf("key %s is owned by %s, not %s", keyID, actor.PublicKey.Owner, actor.ID)
+	}
+
+	key, err := ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, actor.ID, nil
+}
+
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// Deliver POSTs a signed activity to a remote inbox.
+func (c *Client) Deliver(ctx context.Context, inbox, keyID string, key *rsa.PrivateKey, activity interface{}) error {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	if err := Sign(req, keyID, key, body); err != nil {
+		return err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxDocumentSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("delivering to %s: %s", inbox, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package activitypub
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MaxClockSkew is how far a signed request's Date may be from our clock.
+const MaxClockSkew = 12 * time.Hour
+
+// KeyFetcher resolves the public key and owning actor for a signature keyId.
+type KeyFetcher interface {
+	PublicKey(ctx context.Context, keyID string) (*rsa.PublicKey, string, error)
+}
+
+// Sign adds Date, Digest and Signature headers to r following the HTTP
+// Signatures draft as used by Mastodon. body must be the exact request body.
+func Sign(r *http.Request, keyID string, key *rsa.PrivateKey, body []byte) error {
+	if r.Header.Get("Date") == "" {
+		r.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+
+	headers := []string{"(request-target)", "host", "date"}
+	if body != nil {
+		r.Header.Set("Digest", digest(body))
+		headers = append(headers, "digest")
+	}
+
+	signingString, err := buildSigningString(r, headers)
+	if err != nil {
+		return err
+	}
+
+	hashed := sha256.Sum256([]byte(signingString))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return err
+	}
+
+	r.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig)))
+	return nil
+}
+
+// Verify checks the Signature header on r against the sender's public key
+// and returns the actor that owns the signing key.
+func Verify(r *http.Request, body []byte, keys KeyFetcher) (string, error) {
+	params, err := parseSignatureHeader(r.Header.Get("Signature"))
+	if err != nil {
+		return "", err
+	}
+
+	keyID := params["keyId"]
+	if keyID == "" || params["signature"] == "" {
+		return "", errors.New("signature is missing keyId or signature")
+	}
+	if alg := params["algorithm"]; alg != "" && alg != "rsa-sha256" && alg != "hs2019" {
+		return "", fmt.Errorf("unsupported signature algorithm %q", alg)
+	}
+
+	headers := strings.Fields(strings.ToLower(params["headers"]))
+	if len(headers) == 0 {
+		headers = []string{"date"}
+	}
+	required := []string{"(request-target)", "host", "date"}
+	if r.Method == http.MethodPost {
+		required = append(required, "digest")
+	}
+	for _, h := range required {
+		if !slices.Contains(headers, h) {
+			return "", fmt.Errorf("signature must cover %q", h)
+		}
+	}
+
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return "", fmt.Errorf("invalid date header: %w", err)
+	}
+	if skew := time.Since(date); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return "", errors.New("date header is outside the allowed window")
+	}
+
+	if slices.Contains(headers, "digest") && r.Header.Get("Digest") != digest(body) {
+		return "", errors.New("digest does not match body")
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return "", fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	signingString, err := buildSigningString(r, headers)
+	if err != nil {
+		return "", err
+	}
+
+	pub, owner, err := keys.PublicKey(r.Context(), keyID)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch key %s: %w", keyID, err)
+	}
+
+	hashed := sha256.Sum256([]byte(signingString))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], sig); err != nil {
+		return "", errors.New("signature verification failed")
+	}
+
+	return owner, nil
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func buildSigningString(r *http.Request, headers []string) (string, error) {
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		var value string
+		switch h {
+		case "(request-target)":
+			value = strings.ToLower(r.Method) + " " + r.URL.RequestURI()
+		case "host":
+			value = r.Host
+		default:
+			value = r.Header.Get(h)
+			if value == "" {
+				return "", fmt.Errorf("signed header %q is missing", h)
+			}
+		}
+		lines = append(lines, h+": "+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func parseSignatureHeader(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, errors.New("missing signature header")
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed signature parameter %q", part)
+		}
+		params[key] = strings.Trim(value, `"`)
+	}
+	return params, nil
+}
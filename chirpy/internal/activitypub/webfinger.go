@@ -0,0 +1,45 @@
+package activitypub
+
+import (
+	"errors"
+	"strings"
+)
+
+const WebFingerContentType = "application/jrd+json"
+
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// NewWebFinger builds the WebFinger document pointing acct at its actor.
+func NewWebFinger(acct, actorURI, profileURL string) WebFinger {
+	return WebFinger{
+		Subject: "acct:" + acct,
+		Aliases: []string{actorURI},
+		Links: []WebFingerLink{
+			{Rel: "self", Type: ContentType, Href: actorURI},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: profileURL},
+		},
+	}
+}
+
+// ParseAcct splits a WebFinger resource like "acct:alice@example.com" into
+// its username and host.
+func ParseAcct(resource string) (username, host string, err error) {
+	acct := strings.TrimPrefix(resource, "acct:")
+	acct = strings.TrimPrefix(acct, "@")
+
+	username, host, ok := strings.Cut(acct, "@")
+	if !ok || username == "" || host == "" {
+		return "", "", errors.New("resource must look like acct:user@host")
+	}
+	return username, strings.ToLower(host), nil
+}
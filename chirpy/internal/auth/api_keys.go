@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+)
+
+const apiKeyPrefix = "chirpy_"
+
+// Scopes an API key can be granted. A JWT carries all of them.
+const (
+	// ScopeRead covers the caller's private data, such as their
+	// notifications. Public routes like GET /api/chirps need no key at all.
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDelete = "delete"
+)
+
+var AllScopes = []string{ScopeRead, ScopeWrite, ScopeDelete}
+
+// MakeAPIKey generates a new random API key. It returns the key to hand to
+// the user once, a short prefix for identifying it in listings, and the
+// hash that gets stored.
+func MakeAPIKey() (key, prefix, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + hex.EncodeToString(raw)
+	prefix = key[:len(apiKeyPrefix)+8]
+
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the stored form of an API key. Keys are long and
+// random, so a fast hash is enough and lets us look them up directly.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetAPIKey extracts the key from an "Authorization: ApiKey <key>" header.
+func GetAPIKey(headers http.Header) (string, error) {
+	return getAuthorization(headers, "ApiKey")
+}
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !slices.Contains(AllScopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+	}
+
+	return nil
+}
@@ -1,9 +1,13 @@
 package auth_test
 
 import (
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/google/uuid"
 )
 
 // Test that hashing a password returns a non-empty hash and no error.
@@ -69,3 +73,209 @@ func TestCheckPasswordHash_MalformedHash(t *testing.T) {
 		t.Fatalf("expected error when checking malformed hash")
 	}
 }
+
+// Test that a JWT round trips to the same user id.
+func TestMakeAndValidateJWT(t *testing.T) {
+	userID := uuid.New()
+
+	token, err := auth.MakeJWT(userID, "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error making jwt: %v", err)
+	}
+
+	got, err := auth.ValidateJWT(token, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error validating jwt: %v", err)
+	}
+
+	if got != userID {
+		t.Fatalf("expected user id %s, got %s", userID, got)
+	}
+}
+
+// Test that expired tokens and tokens signed with another secret are rejected.
+func TestValidateJWT_Rejects(t *testing.T) {
+	userID := uuid.New()
+
+	expired, err := auth.MakeJWT(userID, "secret", -time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error making jwt: %v", err)
+	}
+	if _, err := auth.ValidateJWT(expired, "secret"); err == nil {
+		t.Fatalf("expected error for expired token")
+	}
+
+	token, err := auth.MakeJWT(userID, "secret", time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error making jwt: %v", err)
+	}
+	if _, err := auth.ValidateJWT(token, "other-secret"); err == nil {
+		t.Fatalf("expected error for wrong secret")
+	}
+}
+
+// Test extracting credentials from the Authorization header.
+func TestGetAuthorization(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		wantBearer string
+		wantAPIKey string
+	}{
+		{name: "bearer", header: "Bearer abc", wantBearer: "abc"},
+		{name: "api key", header: "ApiKey chirpy_123", wantAPIKey: "chirpy_123"},
+		{name: "missing", header: ""},
+		{name: "no value", header: "Bearer "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := http.Header{}
+			if tt.header != "" {
+				headers.Set("Authorization", tt.header)
+			}
+
+			bearer, _ := auth.GetBearerToken(headers)
+			if bearer != tt.wantBearer {
+				t.Fatalf("expected bearer %q, got %q", tt.wantBearer, bearer)
+			}
+
+			key, _ := auth.GetAPIKey(headers)
+			if key != tt.wantAPIKey {
+				t.Fatalf("expected api key %q, got %q", tt.wantAPIKey, key)
+			}
+		})
+	}
+}
+
+// Test that generated API keys are unique and hash to the stored value.
+func TestMakeAPIKey(t *testing.T) {
+	key, prefix, hash, err := auth.MakeAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error making api key: %v", err)
+	}
+
+	if !strings.HasPrefix(key, prefix) {
+		t.Fatalf("expected key %q to start with prefix %q", key, prefix)
+	}
+	if hash != auth.HashAPIKey(key) {
+		t.Fatalf("expected hash to match HashAPIKey")
+	}
+	if strings.Contains(hash, key) {
+		t.Fatalf("expected hash not to contain the key")
+	}
+
+	other, _, _, err := auth.MakeAPIKey()
+	if err != nil {
+		t.Fatalf("unexpected error making api key: %v", err)
+	}
+	if other == key {
+		t.Fatalf("expected api keys to be unique")
+	}
+}
+
+// Test scope validation.
+func TestValidateScopes(t *testing.T) {
+	if err := auth.ValidateScopes([]string{auth.ScopeRead, auth.ScopeWrite}); err != nil {
+		t.Fatalf("unexpected error for valid scopes: %v", err)
+	}
+	if err := auth.ValidateScopes(nil); err == nil {
+		t.Fatalf("expected error for no scopes")
+	}
+	if err := auth.ValidateScopes([]string{"admin"}); err == nil {
+		t.Fatalf("expected error for unknown scope")
+	}
+}
+
+// Test that an MFA challenge token can't be used as an access token.
+func TestMFATokenIsNotAccessToken(t *testing.T) {
+	userID := uuid.New()
+
+	token, err := auth.MakeMFAToken(userID, "secret", time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error making mfa token: %v", err)
+	}
+
+	if _, err := auth.ValidateJWT(token, "secret"); err == nil {
+		t.Fatalf("expected mfa token to be rejected as an access token")
+	}
+
+	got, err := auth.ValidateMFAToken(token, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error validating mfa token: %v", err)
+	}
+	if got != userID {
+		t.Fatalf("expected user id %v, got %v", userID, got)
+	}
+}
+
+// Test TOTP codes against the SHA1 vectors from RFC 6238, truncated to six
+// digits.
+func TestTOTPCode(t *testing.T) {
+	// base32 of "12345678901234567890"
+	secret := "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := auth.TOTPCode(secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("at %d expected %s, got %s", tt.unix, tt.want, got)
+		}
+	}
+}
+
+// Test that codes from neighbouring periods are accepted and others are not.
+func TestValidateTOTP(t *testing.T) {
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error making secret: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	for _, offset := range []time.Duration{-30 * time.Second, 0, 30 * time.Second} {
+		code, _ := auth.TOTPCode(secret, now.Add(offset))
+		if _, ok := auth.ValidateTOTP(secret, code, now); !ok {
+			t.Fatalf("expected code from %v to be valid", offset)
+		}
+	}
+
+	old, _ := auth.TOTPCode(secret, now.Add(-2*time.Minute))
+	if _, ok := auth.ValidateTOTP(secret, old, now); ok {
+		t.Fatalf("expected stale code to be rejected")
+	}
+	if _, ok := auth.ValidateTOTP(secret, "abc", now); ok {
+		t.Fatalf("expected malformed code to be rejected")
+	}
+}
+
+// Test that recovery codes hash the same however they are retyped.
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := auth.MakeRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("unexpected error making recovery codes: %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("expected 10 codes, got %d", len(codes))
+	}
+
+	code := codes[0]
+	retyped := strings.ToUpper(strings.ReplaceAll(code, "-", " "))
+	if auth.HashRecoveryCode(code) != auth.HashRecoveryCode(retyped) {
+		t.Fatalf("expected %q and %q to hash the same", code, retyped)
+	}
+	if auth.HashRecoveryCode(code) == auth.HashRecoveryCode(codes[1]) {
+		t.Fatalf("expected different codes to hash differently")
+	}
+}
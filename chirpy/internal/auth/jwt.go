@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	tokenIssuer = "chirpy"
+	// mfaTokenIssuer marks tokens that only prove the password step of a
+	// two-factor login. ValidateJWT rejects them.
+	mfaTokenIssuer = "chirpy-mfa"
+)
+
+var ErrNoAuthHeader = errors.New("no authorization header included in request")
+
+func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return makeToken(tokenIssuer, userID, tokenSecret, expiresIn)
+}
+
+func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(tokenIssuer, tokenString, tokenSecret)
+}
+
+// MakeMFAToken issues a challenge token for a user who has passed the first
+// login step and still has to present a second factor.
+func MakeMFAToken(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	return makeToken(mfaTokenIssuer, userID, tokenSecret, expiresIn)
+}
+
+func ValidateMFAToken(tokenString, tokenSecret string) (uuid.UUID, error) {
+	return validateToken(mfaTokenIssuer, tokenString, tokenSecret)
+}
+
+func makeToken(issuer string, userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+	now := time.Now().UTC()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Issuer:    issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+		Subject:   userID.String(),
+	})
+
+	return token.SignedString([]byte(tokenSecret))
+}
+
+func validateToken(issuer, tokenString, tokenSecret string) (uuid.UUID, error) {
+	claims := jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(tokenSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithIssuer(issuer))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid user id in token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// GetBearerToken extracts the token from an "Authorization: Bearer <token>" header.
+func GetBearerToken(headers http.Header) (string, error) {
+	return getAuthorization(headers, "Bearer")
+}
+
+func getAuthorization(headers http.Header, scheme string) (string, error) {
+	header := headers.Get("Authorization")
+	if header == "" {
+		return "", ErrNoAuthHeader
+	}
+
+	got, value, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(got, scheme) || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("malformed authorization header, expected %q scheme", scheme)
+	}
+
+	return strings.TrimSpace(value), nil
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// recoveryCodeAlphabet has 32 symbols so bytes map onto it evenly, and leaves
+// out characters that are easily confused (0, 1, l, o).
+const recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+// MakeRecoveryCodes generates n single-use recovery codes of the form
+// "xxxxx-xxxxx". They are shown to the user once; only HashRecoveryCode
+// of each should be stored.
+func MakeRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for range n {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+
+		code := make([]byte, 0, 11)
+		for i, b := range raw {
+			if i == 5 {
+				code = append(code, '-')
+			}
+			code = append(code, recoveryCodeAlphabet[int(b)%len(recoveryCodeAlphabet)])
+		}
+		codes = append(codes, string(code))
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Case,
+// spaces and dashes are ignored so codes survive being retyped.
+func HashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,251 @@
+package compress
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// MinSize is the smallest body worth compressing. Anything shorter is sent
+// as-is since the encoding overhead would outweigh the savings.
+const MinSize = 512
+
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+// Middleware compresses responses with brotli or gzip depending on what the
+// client advertises in Accept-Encoding.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// ETags are suffixed with the encoding on the way out, so strip it
+		// again before handlers compare them against their own.
+		inm := r.Header.Get("If-None-Match")
+		if inm != "" {
+			r.Header.Set("If-None-Match", stripETagSuffixes(inm))
+		}
+
+		cw := &responseWriter{ResponseWriter: w, encoding: encoding, status: http.StatusOK, ifNoneMatch: inm}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiate picks the preferred supported encoding from an Accept-Encoding
+// header, or "" if the client accepts neither.
+func negotiate(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, q := parseCoding(part)
+		if name == "*" {
+			name = encodingBrotli
+		}
+		if name != encodingBrotli && name != encodingGzip {
+			continue
+		}
+		// Prefer brotli when both have the same weight.
+		if q > bestQ || (q == bestQ && q > 0 && name == encodingBrotli) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+func parseCoding(part string) (string, float64) {
+	name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+	q := 1.0
+	if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", 0
+		}
+		q = parsed
+	}
+	return strings.ToLower(strings.TrimSpace(name)), q
+}
+
+func isCompressible(contentType string) bool {
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func suffixETag(etag, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+func stripETagSuffixes(header string) string {
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		header = strings.ReplaceAll(header, "-"+encoding+`"`, `"`)
+	}
+	return header
+}
+
+// responseWriter buffers the start of a response until it knows whether the
+// body is large enough and of a type worth compressing.
+type responseWriter struct {
+	http.ResponseWriter
+	encoding    string
+	status      int
+	ifNoneMatch string
+	buf         []byte
+	encoder     io.WriteCloser
+	decided     bool
+	wroteHeader bool
+}
+
+func (cw *responseWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.status = status
+
+	// Bodiless and partial responses are never compressed.
+	if status == http.StatusNoContent || status == http.StatusNotModified ||
+		status == http.StatusPartialContent || status < http.StatusOK {
+		cw.decided = true
+		// A 304 must repeat the ETag the full response carried. Small
+		// bodies went out uncompressed with the bare ETag, so only add the
+		// suffix when the client's validator had it.
+		if etag := cw.Header().Get("ETag"); status == http.StatusNotModified && etag != "" {
+			if suffixed := suffixETag(etag, cw.encoding); strings.Contains(cw.ifNoneMatch, suffixed) {
+				cw.Header().Set("ETag", suffixed)
+			}
+		}
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *responseWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= MinSize {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide commits to either compressing or passing the body through and
+// flushes anything buffered so far.
+func (cw *responseWriter) decide() error {
+	cw.decided = true
+	header := cw.Header()
+
+	if header.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	compress := len(cw.buf) >= MinSize &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == "" &&
+		isCompressible(header.Get("Content-Type"))
+
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", cw.encoding)
+		if etag := header.Get("ETag"); etag != "" {
+			header.Set("ETag", suffixETag(etag, cw.encoding))
+		}
+
+		switch cw.encoding {
+		case encodingBrotli:
+			cw.encoder = brotli.NewWriterLevel(cw.ResponseWriter, brotli.DefaultCompression)
+		default:
+			cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+	return err
+}
+
+// Flush sends any buffered data to the client, committing to a decision
+// early if needed.
+func (cw *responseWriter) Flush() {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			cw.WriteHeader(http.StatusOK)
+		}
+		cw.decide()
+	}
+
+	if flusher, ok := cw.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *responseWriter) Close() error {
+	if !cw.decided {
+		if !cw.wroteHeader {
+			// The handler never wrote anything; let net/http send its default.
+			return nil
+		}
+		if err := cw.decide(); err != nil {
+			return err
+		}
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+func (cw *responseWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
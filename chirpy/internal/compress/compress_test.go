@@ -0,0 +1,134 @@
+package compress_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/ckm54/go-projects/chirpy/internal/compress"
+	"github.com/ckm54/go-projects/chirpy/internal/httpcache"
+)
+
+func jsonHandler(body string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		httpcache.WriteJSON(w, r, http.StatusOK, []byte(body))
+	})
+}
+
+// Test content negotiation and size thresholds.
+func TestMiddleware(t *testing.T) {
+	large := `{"body":"` + strings.Repeat("chirp ", 200) + `"}`
+	small := `{"body":"hi"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		wantEncoding   string
+	}{
+		{name: "no accept-encoding", body: large, wantEncoding: ""},
+		{name: "gzip", acceptEncoding: "gzip", body: large, wantEncoding: "gzip"},
+		{name: "prefers brotli", acceptEncoding: "gzip, br", body: large, wantEncoding: "br"},
+		{name: "respects q values", acceptEncoding: "br;q=0.1, gzip;q=0.9", body: large, wantEncoding: "gzip"},
+		{name: "small body untouched", acceptEncoding: "gzip", body: small, wantEncoding: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			rec := httptest.NewRecorder()
+			compress.Middleware(jsonHandler(tt.body)).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("expected encoding %q, got %q", tt.wantEncoding, got)
+			}
+
+			var reader io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("unexpected error reading gzip: %v", err)
+				}
+				reader = gz
+			case "br":
+				reader = brotli.NewReader(rec.Body)
+			}
+
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("unexpected error decoding body: %v", err)
+			}
+			if string(data) != tt.body {
+				t.Fatalf("body did not round trip, got %q", string(data))
+			}
+		})
+	}
+}
+
+// Test that a compressed etag still produces a 304 when sent back.
+func TestMiddleware_ConditionalGet(t *testing.T) {
+	body := `{"body":"` + strings.Repeat("chirp ", 200) + `"}`
+	handler := compress.Middleware(jsonHandler(body))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if !strings.HasSuffix(etag, `-gzip"`) {
+		t.Fatalf("expected etag to carry the encoding, got %s", etag)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("expected no content-encoding on 304")
+	}
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Fatalf("expected 304 to repeat etag %s, got %s", etag, got)
+	}
+}
+
+// Test that a body too small to compress is revalidated with its bare etag.
+func TestMiddleware_ConditionalGetSmallBody(t *testing.T) {
+	handler := compress.Middleware(jsonHandler(`{"body":"hi"}`))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" || strings.HasSuffix(etag, `-gzip"`) {
+		t.Fatalf("expected a bare etag on an uncompressed body, got %q", etag)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Fatalf("expected 304 to repeat etag %s, got %s", etag, got)
+	}
+}
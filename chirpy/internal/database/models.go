@@ -5,23 +5,139 @@
 package database
 
 import (
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type ActorKey struct {
+	UserID        uuid.UUID `json:"user_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	PublicKeyPem  string    `json:"public_key_pem"`
+	PrivateKeyPem string    `json:"private_key_pem"`
+}
+
+type ApiKey struct {
+	ID         uuid.UUID    `json:"id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	UpdatedAt  time.Time    `json:"updated_at"`
+	UserID     uuid.UUID    `json:"user_id"`
+	Name       string       `json:"name"`
+	Prefix     string       `json:"prefix"`
+	KeyHash    string       `json:"key_hash"`
+	Scopes     []string     `json:"scopes"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+}
+
+type BannedWord struct {
+	TenantID uuid.UUID `json:"tenant_id"`
+	Word     string    `json:"word"`
+}
+
 type Chirp struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
 	UserID    uuid.UUID `json:"user_id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+}
+
+type ChirpLink struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	Url      string    `json:"url"`
+	Position int32     `json:"position"`
+}
+
+type Follower struct {
+	ID               uuid.UUID `json:"id"`
+	CreatedAt        time.Time `json:"created_at"`
+	UserID           uuid.UUID `json:"user_id"`
+	ActorUri         string    `json:"actor_uri"`
+	InboxUri         string    `json:"inbox_uri"`
+	FollowActivityID string    `json:"follow_activity_id"`
+}
+
+type LinkPreview struct {
+	Url         string    `json:"url"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Ok          bool      `json:"ok"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageUrl    string    `json:"image_url"`
+}
+
+type Notification struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UserID    uuid.UUID      `json:"user_id"`
+	Type      string         `json:"type"`
+	Actor     string         `json:"actor"`
+	ChirpID   uuid.NullUUID  `json:"chirp_id"`
+	RemoteUri sql.NullString `json:"remote_uri"`
+	ReadAt    sql.NullTime   `json:"read_at"`
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Type    string    `json:"type"`
+	Enabled bool      `json:"enabled"`
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	CreatedAt time.Time    `json:"created_at"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+}
+
+type RemoteNote struct {
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UserID    uuid.UUID      `json:"user_id"`
+	NoteUri   string         `json:"note_uri"`
+	ActorUri  string         `json:"actor_uri"`
+	Content   string         `json:"content"`
+	InReplyTo sql.NullString `json:"in_reply_to"`
+}
+
+type Tenant struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Host      string    `json:"host"`
+	Name      string    `json:"name"`
+	Platform  string    `json:"platform"`
 }
 
 type User struct {
-	ID             uuid.UUID `json:"id"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-	Email          string    `json:"email"`
-	HashedPassword string    `json:"hashed_password"`
+	ID             uuid.UUID      `json:"id"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Email          string         `json:"email"`
+	HashedPassword string         `json:"hashed_password"`
+	TenantID       uuid.UUID      `json:"tenant_id"`
+	Username       sql.NullString `json:"username"`
+	IsAdmin        bool           `json:"is_admin"`
+}
+
+type UserIdentity struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uuid.UUID `json:"user_id"`
+	TenantID  uuid.UUID `json:"tenant_id"`
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID    `json:"user_id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	Secret       string       `json:"secret"`
+	EnabledAt    sql.NullTime `json:"enabled_at"`
+	LastUsedStep int64        `json:"last_used_step"`
 }
@@ -7,21 +7,31 @@ package database
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (email, hashed_password)
-VALUES ($1, $2)
-RETURNING id, created_at, updated_at, email, hashed_password
+INSERT INTO users (email, hashed_password, tenant_id, username)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, updated_at, email, hashed_password, tenant_id, username, is_admin
 `
 
 type CreateUserParams struct {
-	Email          string `json:"email"`
-	HashedPassword string `json:"hashed_password"`
+	Email          string         `json:"email"`
+	HashedPassword string         `json:"hashed_password"`
+	TenantID       uuid.UUID      `json:"tenant_id"`
+	Username       sql.NullString `json:"username"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.TenantID,
+		arg.Username,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -29,15 +39,117 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.TenantID,
+		&i.Username,
+		&i.IsAdmin,
 	)
 	return i, err
 }
 
 const deleteUsers = `-- name: DeleteUsers :exec
 DELETE FROM users
+WHERE tenant_id = $1
 `
 
-func (q *Queries) DeleteUsers(ctx context.Context) error {
-	_, err := q.db.ExecContext(ctx, deleteUsers)
+func (q *Queries) DeleteUsers(ctx context.Context, tenantID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteUsers, tenantID)
 	return err
 }
+
+const getTenantUser = `-- name: GetTenantUser :one
+SELECT id, created_at, updated_at, email, hashed_password, tenant_id, username, is_admin FROM users
+WHERE id = $1 AND tenant_id = $2
+`
+
+type GetTenantUserParams struct {
+	ID       uuid.UUID `json:"id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetTenantUser(ctx context.Context, arg GetTenantUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getTenantUser, arg.ID, arg.TenantID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.TenantID,
+		&i.Username,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, created_at, updated_at, email, hashed_password, tenant_id, username, is_admin FROM users
+WHERE email = $1 AND tenant_id = $2
+`
+
+type GetUserByEmailParams struct {
+	Email    string    `json:"email"`
+	TenantID uuid.UUID `json:"tenant_id"`
+}
+
+func (q *Queries) GetUserByEmail(ctx context.Context, arg GetUserByEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, arg.Email, arg.TenantID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.TenantID,
+		&i.Username,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const getUserByUsername = `-- name: GetUserByUsername :one
+SELECT id, created_at, updated_at, email, hashed_password, tenant_id, username, is_admin FROM users
+WHERE username = $1 AND tenant_id = $2
+`
+
+type GetUserByUsernameParams struct {
+	Username sql.NullString `json:"username"`
+	TenantID uuid.UUID      `json:"tenant_id"`
+}
+
+func (q *Queries) GetUserByUsername(ctx context.Context, arg GetUserByUsernameParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByUsername, arg.Username, arg.TenantID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.TenantID,
+		&i.Username,
+		&i.IsAdmin,
+	)
+	return i, err
+}
+
+const setUserAdmin = `-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $3, updated_at = NOW()
+WHERE email = $1 AND tenant_id = $2
+`
+
+type SetUserAdminParams struct {
+	Email    string    `json:"email"`
+	TenantID uuid.UUID `json:"tenant_id"`
+	IsAdmin  bool      `json:"is_admin"`
+}
+
+func (q *Queries) SetUserAdmin(ctx context.Context, arg SetUserAdminParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setUserAdmin, arg.Email, arg.TenantID, arg.IsAdmin)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
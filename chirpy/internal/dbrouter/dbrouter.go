@@ -0,0 +1,164 @@
+package dbrouter
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// pruneThreshold is how many remembered writers we allow before sweeping
+// out the expired ones.
+const pruneThreshold = 1024
+
+type userKey struct{}
+
+// WithUser tags ctx with the user a request is made for, so their reads can
+// stick to the primary after they write.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+func userFrom(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// Router sends a fixed set of read-only sqlc queries to a replica and
+// everything else to the primary. It satisfies database.DBTX.
+//
+// A user who wrote within the last stickFor reads from the primary, so
+// they see their own writes despite replication lag.
+type Router struct {
+	primary  *sql.DB
+	replica  *sql.DB
+	reads    map[string]bool
+	stickFor time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	lastWrite map[string]time.Time
+}
+
+// New returns a Router. replica may be nil, in which case every query goes
+// to the primary. readQueries are sqlc query names, such as "GetChirps".
+func New(primary, replica *sql.DB, stickFor time.Duration, readQueries ...string) *Router {
+	reads := make(map[string]bool, len(readQueries))
+	for _, name := range readQueries {
+		reads[name] = true
+	}
+
+	return &Router{
+		primary:   primary,
+		replica:   replica,
+		reads:     reads,
+		stickFor:  stickFor,
+		now:       time.Now,
+		lastWrite: make(map[string]time.Time),
+	}
+}
+
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := r.primary.ExecContext(ctx, query, args...)
+	if err == nil {
+		r.MarkWrite(ctx)
+	}
+	return result, err
+}
+
+func (r *Router) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.pick(ctx, query).PrepareContext(ctx, query)
+}
+
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := r.pick(ctx, query).QueryContext(ctx, query, args...)
+	if err == nil && isWrite(query) {
+		r.MarkWrite(ctx)
+	}
+	return rows, err
+}
+
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := r.pick(ctx, query).QueryRowContext(ctx, query, args...)
+	if row.Err() == nil && isWrite(query) {
+		r.MarkWrite(ctx)
+	}
+	return row
+}
+
+// MarkWrite records that the user on ctx just wrote. Writes made in a
+// transaction bypass the Router, so callers should mark them once committed.
+func (r *Router) MarkWrite(ctx context.Context) {
+	user := userFrom(ctx)
+	if user == "" || r.replica == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	r.lastWrite[user] = now
+
+	if len(r.lastWrite) > pruneThreshold {
+		for key, at := range r.lastWrite {
+			if now.Sub(at) >= r.stickFor {
+				delete(r.lastWrite, key)
+			}
+		}
+	}
+}
+
+func (r *Router) pick(ctx context.Context, query string) *sql.DB {
+	if r.replica == nil || !r.reads[queryName(query)] || r.sticky(ctx) {
+		return r.primary
+	}
+	return r.replica
+}
+
+func (r *Router) sticky(ctx context.Context) bool {
+	user := userFrom(ctx)
+	if user == "" {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	at, ok := r.lastWrite[user]
+	if !ok {
+		return false
+	}
+	if r.now().Sub(at) >= r.stickFor {
+		delete(r.lastWrite, user)
+		return false
+	}
+	return true
+}
+
+// queryName returns the name from the "-- name: GetChirps :many" comment
+// sqlc puts at the top of every query.
+func queryName(query string) string {
+	rest, ok := strings.CutPrefix(query, "-- name: ")
+	if !ok {
+		return ""
+	}
+	name, _, _ := strings.Cut(rest, " ")
+	return name
+}
+
+// isWrite reports whether query modifies data, for queries that return rows
+// such as INSERT ... RETURNING.
+func isWrite(query string) bool {
+	if _, body, ok := strings.Cut(query, "\n"); ok && strings.HasPrefix(query, "--") {
+		query = body
+	}
+
+	verb, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	switch strings.ToUpper(verb) {
+	case "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package dbrouter
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingDriver is a database/sql driver that remembers which DSN each
+// query was sent to.
+type recordingDriver struct {
+	mu   sync.Mutex
+	hits map[string][]string
+}
+
+func (d *recordingDriver) Open(dsn string) (driver.Conn, error) {
+	return &recordingConn{driver: d, dsn: dsn}, nil
+}
+
+func (d *recordingDriver) record(dsn, query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hits[dsn] = append(d.hits[dsn], queryName(query))
+}
+
+func (d *recordingDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hits = make(map[string][]string)
+}
+
+func (d *recordingDriver) count(dsn string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.hits[dsn])
+}
+
+type recordingConn struct {
+	driver *recordingDriver
+	dsn    string
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{conn: c, query: query}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type recordingStmt struct {
+	conn  *recordingConn
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.conn.driver.record(s.conn.dsn, s.query)
+	return driver.RowsAffected(1), nil
+}
+func (s *recordingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.conn.driver.record(s.conn.dsn, s.query)
+	return emptyRows{}, nil
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return []string{"id"} }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+var (
+	registerOnce sync.Once
+	testDriver   = &recordingDriver{hits: make(map[string][]string)}
+)
+
+func openTestDBs(t *testing.T) (*sql.DB, *sql.DB) {
+	t.Helper()
+	registerOnce.Do(func() { sql.Register("recording", testDriver) })
+	testDriver.reset()
+
+	primary, err := sql.Open("recording", "primary")
+	if err != nil {
+		t.Fatalf("unexpected error opening primary: %v", err)
+	}
+	replica, err := sql.Open("recording", "replica")
+	if err != nil {
+		t.Fatalf("unexpected error opening replica: %v", err)
+	}
+	t.Cleanup(func() {
+		primary.Close()
+		replica.Close()
+	})
+
+	return primary, replica
+}
+
+const (
+	getChirps   = "-- name: GetChirps :many\nSELECT id FROM chirps"
+	getUser     = "-- name: GetTenantUser :one\nSELECT id FROM users"
+	createChirp = "-- name: CreateChirp :one\nINSERT INTO chirps (body) VALUES ($1)\nRETURNING id"
+	deleteChirp = "-- name: DeleteChirp :exec\nDELETE FROM chirps WHERE id = $1"
+)
+
+// Test that only the listed queries go to the replica.
+func TestRouterRoutesReads(t *testing.T) {
+	primary, replica := openTestDBs(t)
+	r := New(primary, replica, time.Minute, "GetChirps")
+	ctx := context.Background()
+
+	rows, err := r.QueryContext(ctx, getChirps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows.Close()
+	r.QueryRowContext(ctx, getUser).Scan(new(string))
+	r.QueryRowContext(ctx, createChirp, "hi").Scan(new(string))
+	if _, err := r.ExecContext(ctx, deleteChirp, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := testDriver.count("replica"); got != 1 {
+		t.Fatalf("expected 1 query on the replica, got %d", got)
+	}
+	if got := testDriver.count("primary"); got != 3 {
+		t.Fatalf("expected 3 queries on the primary, got %d", got)
+	}
+}
+
+// Test that a user reads from the primary for a while after writing, and
+// other users are unaffected.
+func TestRouterReadYourWrites(t *testing.T) {
+	primary, replica := openTestDBs(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := New(primary, replica, 10*time.Second, "GetChirps")
+	r.now = func() time.Time { return now }
+
+	alice := WithUser(context.Background(), "alice")
+	bob := WithUser(context.Background(), "bob")
+
+	r.QueryRowContext(alice, createChirp, "hi").Scan(new(string))
+	testDriver.reset()
+
+	query := func(ctx context.Context) {
+		rows, err := r.QueryContext(ctx, getChirps)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		rows.Close()
+	}
+
+	query(alice)
+	query(bob)
+	if got := testDriver.count("primary"); got != 1 {
+		t.Fatalf("expected the writer's read on the primary, got %d primary reads", got)
+	}
+	if got := testDriver.count("replica"); got != 1 {
+		t.Fatalf("expected the other user's read on the replica, got %d replica reads", got)
+	}
+
+	now = now.Add(10 * time.Second)
+	testDriver.reset()
+	query(alice)
+	if got := testDriver.count("replica"); got != 1 {
+		t.Fatalf("expected reads to return to the replica once the window passes")
+	}
+}
+
+// Test that without a replica everything goes to the primary.
+func TestRouterWithoutReplica(t *testing.T) {
+	primary, _ := openTestDBs(t)
+	r := New(primary, nil, time.Minute, "GetChirps")
+
+	rows, err := r.QueryContext(context.Background(), getChirps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rows.Close()
+
+	if got := testDriver.count("primary"); got != 1 {
+		t.Fatalf("expected the read on the primary, got %d", got)
+	}
+}
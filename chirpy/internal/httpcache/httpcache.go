@@ -0,0 +1,77 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Cache-Control policies used by the chirpy routes.
+const (
+	// NoStore is for responses that must never be cached (admin, health).
+	NoStore = "no-store"
+	// Revalidate lets clients keep a copy but forces them to check the ETag
+	// on every request. Used for collections that change often.
+	Revalidate = "no-cache"
+	// ShortLived lets clients reuse a single resource for a minute before
+	// revalidating it.
+	ShortLived = "public, max-age=60, must-revalidate"
+	// Static is for files served from the assets directory.
+	Static = "public, max-age=3600"
+)
+
+// ETag returns a strong entity tag for the given response body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// NotModified reports whether the request's If-None-Match header matches
+// etag, meaning the client's cached copy is still fresh.
+func NotModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+
+	// If-None-Match uses the weak comparison function, so a W/ prefix on
+	// either side is ignored.
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == want {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WriteJSON writes body as a JSON response tagged with its ETag, or a 304
+// with no body when the client already has the current version.
+func WriteJSON(w http.ResponseWriter, r *http.Request, status int, body []byte) {
+	etag := ETag(body)
+	w.Header().Set("ETag", etag)
+
+	if NotModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// Control sets the Cache-Control header to policy on every response from next.
+func Control(policy string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", policy)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,86 @@
+package httpcache_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ckm54/go-projects/chirpy/internal/httpcache"
+)
+
+// Test that the ETag is strong, stable and changes with the body.
+func TestETag(t *testing.T) {
+	a := httpcache.ETag([]byte(`{"body":"hello"}`))
+	b := httpcache.ETag([]byte(`{"body":"hello"}`))
+	c := httpcache.ETag([]byte(`{"body":"bye"}`))
+
+	if a != b {
+		t.Fatalf("expected identical bodies to share an etag, got %s and %s", a, b)
+	}
+	if a == c {
+		t.Fatalf("expected different bodies to have different etags")
+	}
+	if a[0] != '"' || a[len(a)-1] != '"' {
+		t.Fatalf("expected a quoted strong etag, got %s", a)
+	}
+}
+
+// Test matching of If-None-Match against an etag.
+func TestNotModified(t *testing.T) {
+	etag := `"abc123"`
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		want        bool
+	}{
+		{name: "no header", ifNoneMatch: "", want: false},
+		{name: "exact match", ifNoneMatch: `"abc123"`, want: true},
+		{name: "weak match", ifNoneMatch: `W/"abc123"`, want: true},
+		{name: "in list", ifNoneMatch: `"nope", "abc123"`, want: true},
+		{name: "wildcard", ifNoneMatch: "*", want: true},
+		{name: "mismatch", ifNoneMatch: `"other"`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+
+			if got := httpcache.NotModified(req, etag); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// Test that WriteJSON sends a 304 without a body for a fresh client copy.
+func TestWriteJSON(t *testing.T) {
+	body := []byte(`[{"id":"1"}]`)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	rec := httptest.NewRecorder()
+	httpcache.WriteJSON(rec, req, http.StatusOK, body)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected an etag header")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/chirps", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	httpcache.WriteJSON(rec, req, http.StatusOK, body)
+
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected empty body on 304, got %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,227 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/netguard"
+)
+
+// Limits on what gets stored, so a page can't stuff a chirp with text.
+const (
+	maxTitleLen       = 300
+	maxDescriptionLen = 1000
+	maxRedirects      = 3
+)
+
+// ErrForbiddenAddress is returned when a URL resolves to an address we won't
+// connect to, such as loopback or a private network.
+var ErrForbiddenAddress = netguard.ErrForbiddenAddress
+
+// Preview is the OpenGraph metadata for a page.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+}
+
+// Fetcher retrieves a Preview for a URL.
+type Fetcher interface {
+	Fetch(ctx context.Context, rawURL string) (Preview, error)
+}
+
+// HTTPFetcher fetches pages over HTTP. It only connects to public
+// addresses, follows at most a few redirects, gives up after a timeout and
+// reads no more than maxBytes of each page.
+type HTTPFetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewHTTPFetcher returns a fetcher with the given per-fetch timeout and body
+// size limit.
+func NewHTTPFetcher(timeout time.Duration, maxBytes int64) *HTTPFetcher {
+	return newHTTPFetcher(timeout, maxBytes, netguard.IsPublic)
+}
+
+func newHTTPFetcher(timeout time.Duration, maxBytes int64, allow func(netip.Addr) bool) *HTTPFetcher {
+	dialer := netguard.Dialer(timeout, allow)
+
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errors.New("too many redirects")
+				}
+				if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+					return fmt.Errorf("refusing to follow redirect to %s", req.URL.Scheme)
+				}
+				return nil
+			},
+		},
+		maxBytes: maxBytes,
+	}
+}
+
+func (f *HTTPFetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, err
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return Preview{}, fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return Preview{}, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", "Chirpy link preview")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %s", res.Status)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Preview{}, fmt.Errorf("not an HTML page: %q", mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, f.maxBytes))
+	if err != nil {
+		return Preview{}, err
+	}
+
+	preview := Parse(string(body), res.Request.URL)
+	preview.URL = rawURL
+	if preview.Title == "" && preview.Description == "" {
+		return Preview{}, errors.New("page has no title or description")
+	}
+
+	return preview, nil
+}
+
+var (
+	metaTagPattern  = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	attrPattern     = regexp.MustCompile(`(?is)([a-z:-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+	titleTagPattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+)
+
+// Parse extracts OpenGraph metadata from an HTML document, falling back to
+// the <title> and description meta tag. Relative image URLs are resolved
+// against base.
+func Parse(doc string, base *url.URL) Preview {
+	// Metadata lives in the head; ignore anything after it.
+	if end := strings.Index(strings.ToLower(doc), "</head>"); end >= 0 {
+		doc = doc[:end]
+	}
+
+	meta := map[string]string{}
+	for _, tag := range metaTagPattern.FindAllString(doc, -1) {
+		attrs := map[string]string{}
+		for _, match := range attrPattern.FindAllStringSubmatch(tag, -1) {
+			attrs[strings.ToLower(match[1])] = match[2] + match[3] + match[4]
+		}
+
+		key := attrs["property"]
+		if key == "" {
+			key = attrs["name"]
+		}
+		key = strings.ToLower(key)
+		if _, seen := meta[key]; key != "" && !seen {
+			meta[key] = clean(attrs["content"])
+		}
+	}
+
+	preview := Preview{
+		Title:       meta["og:title"],
+		Description: meta["og:description"],
+	}
+	if preview.Title == "" {
+		if match := titleTagPattern.FindStringSubmatch(doc); match != nil {
+			preview.Title = clean(match[1])
+		}
+	}
+	if preview.Description == "" {
+		preview.Description = meta["description"]
+	}
+
+	if image := meta["og:image"]; image != "" {
+		if ref, err := url.Parse(image); err == nil {
+			resolved := ref
+			if base != nil {
+				resolved = base.ResolveReference(ref)
+			}
+			if resolved.Scheme == "http" || resolved.Scheme == "https" {
+				preview.ImageURL = resolved.String()
+			}
+		}
+	}
+
+	preview.Title = truncate(preview.Title, maxTitleLen)
+	preview.Description = truncate(preview.Description, maxDescriptionLen)
+
+	return preview
+}
+
+func clean(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// ExtractURLs returns up to max distinct http(s) URLs found in text.
+func ExtractURLs(text string, max int) []string {
+	var urls []string
+	for _, match := range urlPattern.FindAllString(text, -1) {
+		// Punctuation right after a link is almost always part of the
+		// sentence, not the URL.
+		match = strings.TrimRight(match, ".,;:!?)'")
+		if _, err := url.ParseRequestURI(match); err != nil || slices.Contains(urls, match) {
+			continue
+		}
+
+		urls = append(urls, match)
+		if len(urls) == max {
+			break
+		}
+	}
+	return urls
+}
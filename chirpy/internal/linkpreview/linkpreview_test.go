@@ -0,0 +1,135 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPage = `<!doctype html>
+<html>
+<head>
+  <title>Fallback title</title>
+  <meta property="og:title" content="Gophers &amp; friends">
+  <meta name="description" content="A plain description">
+  <meta content='An OpenGraph
+    description' property='og:description'>
+  <meta property="og:image" content="/img/gopher.png">
+</head>
+<body><meta property="og:title" content="Not in the head"></body>
+</html>`
+
+// allowAll lets tests reach the httptest server on loopback.
+func allowAll(netip.Addr) bool { return true }
+
+// Test that OpenGraph tags win over fallbacks and relative images resolve.
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	got := Parse(testPage, base)
+
+	want := Preview{
+		Title:       "Gophers & friends",
+		Description: "An OpenGraph description",
+		ImageURL:    "https://example.com/img/gopher.png",
+	}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+// Test that pages without OpenGraph fall back to <title> and description.
+func TestParseFallbacks(t *testing.T) {
+	doc := `<head><title> Just a
+	title </title><meta name="Description" content="Plain"><meta property="og:image" content="javascript:alert(1)"></head>`
+	got := Parse(doc, nil)
+
+	if got.Title != "Just a title" || got.Description != "Plain" {
+		t.Fatalf("unexpected fallback preview: %+v", got)
+	}
+	if got.ImageURL != "" {
+		t.Fatalf("expected non-http image to be dropped, got %q", got.ImageURL)
+	}
+}
+
+// Test fetching a preview from a local server, following a redirect.
+func TestHTTPFetcherFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := newHTTPFetcher(time.Second, 64<<10, allowAll)
+	got, err := fetcher.Fetch(context.Background(), srv.URL+"/old")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got.URL != srv.URL+"/old" {
+		t.Fatalf("expected preview for the requested URL, got %q", got.URL)
+	}
+	if got.Title != "Gophers & friends" {
+		t.Fatalf("unexpected title %q", got.Title)
+	}
+	if got.ImageURL != srv.URL+"/img/gopher.png" {
+		t.Fatalf("expected image resolved against the final URL, got %q", got.ImageURL)
+	}
+}
+
+// Test that non-HTML responses and oversized heads are not used.
+func TestHTTPFetcherLimits(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("not really a png"))
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<head>" + strings.Repeat(" ", 1<<20) + "<title>Too far down</title></head>"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := newHTTPFetcher(200*time.Millisecond, 64<<10, allowAll)
+	for _, path := range []string{"/image", "/huge", "/slow"} {
+		if _, err := fetcher.Fetch(context.Background(), srv.URL+path); err == nil {
+			t.Fatalf("expected an error fetching %s", path)
+		}
+	}
+}
+
+// Test that the default fetcher refuses to connect to loopback.
+func TestHTTPFetcherBlocksPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("request should never reach the server")
+	}))
+	defer srv.Close()
+
+	_, err := NewHTTPFetcher(time.Second, 64<<10).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+func TestExtractURLs(t *testing.T) {
+	got := ExtractURLs("see https://example.com/a, and (http://go.dev/doc). Also https://example.com/a again and ftp://x https://three.example https://four.example", 3)
+	want := []string{"https://example.com/a", "http://go.dev/doc", "https://three.example"}
+
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
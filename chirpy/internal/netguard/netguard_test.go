@@ -0,0 +1,29 @@
+package netguard
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1":    true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+	}
+
+	for addr, want := range tests {
+		if got := IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Fatalf("IsPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
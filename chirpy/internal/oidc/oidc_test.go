@@ -0,0 +1,205 @@
+package oidc_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is a minimal local OIDC provider.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// codes maps an authorization code to the PKCE challenge and nonce it
+	// was issued for.
+	codes map[string]pendingCode
+	// claims are put in every ID token the issuer signs.
+	email         string
+	emailVerified bool
+}
+
+type pendingCode struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unexpected error generating key: %v", err)
+	}
+
+	m := &mockIssuer{key: key, codes: map[string]pendingCode{}, email: "alice@example.com", emailVerified: true}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "test-key",
+				"kty": "RSA",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		pending, ok := m.codes[r.Form.Get("code")]
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != pending.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(m.codes, r.Form.Get("code"))
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.idToken(t, pending.nonce)})
+	})
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockIssuer) idToken(t *testing.T, nonce string) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, oidc.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.server.URL,
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{"chirpy"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+		Email:         m.email,
+		EmailVerified: m.emailVerified,
+		Nonce:         nonce,
+	})
+	token.Header["kid"] = "test-key"
+
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		t.Fatalf("unexpected error signing id token: %v", err)
+	}
+	return signed
+}
+
+// authorize stands in for the user signing in at the provider: it reads
+// the PKCE challenge and nonce from the auth URL and issues a code.
+func (m *mockIssuer) authorize(t *testing.T, authURL string) string {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("unexpected error parsing auth url: %v", err)
+	}
+	q := parsed.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected S256 PKCE, got %q", q.Get("code_challenge_method"))
+	}
+
+	m.codes["code-1"] = pendingCode{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return "code-1"
+}
+
+func discover(t *testing.T, m *mockIssuer) *oidc.Provider {
+	t.Helper()
+
+	provider, err := oidc.Discover(context.Background(), oidc.Config{Issuer: m.server.URL, ClientID: "chirpy"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error discovering issuer: %v", err)
+	}
+	return provider
+}
+
+// Test the full authorization code + PKCE flow against the mock issuer.
+func TestAuthorizationCodeFlow(t *testing.T) {
+	m := newMockIssuer(t)
+	provider := discover(t, m)
+
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		t.Fatalf("unexpected error making pkce: %v", err)
+	}
+
+	redirect := "https://chirpy.example/api/auth/oidc/callback"
+	code := m.authorize(t, provider.AuthCodeURL(redirect, "state", "nonce-1", challenge))
+
+	claims, err := provider.Exchange(context.Background(), redirect, code, verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("unexpected error exchanging code: %v", err)
+	}
+
+	if claims.Subject != "user-123" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("unexpected claims: %+v", claims)
+	}
+}
+
+// Test that the exchange fails when the PKCE verifier or nonce is wrong.
+func TestExchange_Rejects(t *testing.T) {
+	redirect := "https://chirpy.example/api/auth/oidc/callback"
+
+	tests := []struct {
+		name          string
+		wrongVerifier bool
+		wrongNonce    bool
+	}{
+		{name: "wrong verifier", wrongVerifier: true},
+		{name: "wrong nonce", wrongNonce: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newMockIssuer(t)
+			provider := discover(t, m)
+
+			verifier, challenge, _ := oidc.NewPKCE()
+			code := m.authorize(t, provider.AuthCodeURL(redirect, "state", "nonce-1", challenge))
+
+			if tt.wrongVerifier {
+				verifier, _, _ = oidc.NewPKCE()
+			}
+			nonce := "nonce-1"
+			if tt.wrongNonce {
+				nonce = "other"
+			}
+
+			if _, err := provider.Exchange(context.Background(), redirect, code, verifier, nonce); err == nil {
+				t.Fatalf("expected exchange to fail")
+			}
+		})
+	}
+}
+
+// Test that tokens signed by another key are rejected.
+func TestVerify_WrongKey(t *testing.T) {
+	m := newMockIssuer(t)
+	provider := discover(t, m)
+
+	other := newMockIssuer(t)
+	other.server.URL = m.server.URL // same issuer claim, different key
+
+	if _, err := provider.Verify(context.Background(), other.idToken(t, "n"), "n"); err == nil {
+		t.Fatalf("expected verification with the wrong key to fail")
+	}
+}
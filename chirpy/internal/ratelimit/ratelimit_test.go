@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// Test that a key is limited to its burst and refills over time.
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(60, 2)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Allow("key"); !ok {
+			t.Fatalf("expected request %d to be allowed", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("key")
+	if ok {
+		t.Fatalf("expected request over burst to be limited")
+	}
+	if wait <= 0 || wait > time.Second {
+		t.Fatalf("expected a wait of up to one second, got %s", wait)
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := l.Allow("key"); !ok {
+		t.Fatalf("expected bucket to refill after a second")
+	}
+}
+
+// Test that each key has its own bucket.
+func TestLimiterPerKey(t *testing.T) {
+	l := New(60, 1)
+
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatalf("expected first request for a to be allowed")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Fatalf("expected second request for a to be limited")
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Fatalf("expected b to have its own bucket")
+	}
+}
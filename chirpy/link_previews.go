@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/linkpreview"
+	"github.com/google/uuid"
+)
+
+const (
+	maxPreviewsPerChirp = 3
+	previewTimeout      = 5 * time.Second
+	previewMaxBytes     = 512 << 10
+	// A cached preview is reused for this long before refetching. Failures
+	// are retried sooner in case the site was only briefly down.
+	previewCacheTTL = 24 * time.Hour
+	previewRetryTTL = time.Hour
+	previewFetchers = 8
+)
+
+type linkPreviewRes struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+}
+
+// chirpRes is a chirp with the previews of the links in it, once they
+// have been fetched.
+type chirpRes struct {
+	database.Chirp
+	Previews []linkPreviewRes `json:"previews,omitempty"`
+}
+
+// withPreviews attaches the stored link previews to chirps.
+func (cfg *apiConfig) withPreviews(ctx context.Context, chirps []database.Chirp) ([]chirpRes, error) {
+	if chirps == nil {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		ids[i] = chirp.ID
+	}
+
+	rows, err := cfg.database.GetChirpPreviews(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make(map[uuid.UUID][]linkPreviewRes)
+	for _, row := range rows {
+		previews[row.ChirpID] = append(previews[row.ChirpID], linkPreviewRes{
+			URL:         row.Url,
+			Title:       row.Title,
+			Description: row.Description,
+			ImageURL:    row.ImageUrl,
+		})
+	}
+
+	res := make([]chirpRes, len(chirps))
+	for i, chirp := range chirps {
+		res[i] = chirpRes{Chirp: chirp, Previews: previews[chirp.ID]}
+	}
+	return res, nil
+}
+
+// fetchPreviews records the links in a new chirp and fetches previews for
+// any that aren't cached. It runs after the chirp has been returned to the
+// author, so the previews show up on later reads.
+func (cfg *apiConfig) fetchPreviews(chirp database.Chirp) {
+	urls := linkpreview.ExtractURLs(chirp.Body, maxPreviewsPerChirp)
+	if len(urls) == 0 {
+		return
+	}
+
+	cfg.previewSlots <- struct{}{}
+	defer func() { <-cfg.previewSlots }()
+
+	ctx := context.Background()
+	for i, url := range urls {
+		if err := cfg.database.AddChirpLink(ctx, database.AddChirpLinkParams{
+			ChirpID:  chirp.ID,
+			Url:      url,
+			Position: int32(i),
+		}); err != nil {
+			log.Printf("Failed to record link %s in chirp %s: %s", url, chirp.ID, err)
+			return
+		}
+
+		cached, err := cfg.database.GetLinkPreview(ctx, url)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to look up preview for %s: %s", url, err)
+			continue
+		}
+		if err == nil && previewFresh(cached) {
+			continue
+		}
+
+		fetchCtx, cancel := context.WithTimeout(ctx, previewTimeout)
+		preview, err := cfg.previewFetcher.Fetch(fetchCtx, url)
+		cancel()
+		if err != nil {
+			log.Printf("No preview for %s: %s", url, err)
+		}
+
+		if err := cfg.database.SaveLinkPreview(ctx, database.SaveLinkPreviewParams{
+			Url:         url,
+			Ok:          err == nil,
+			Title:       preview.Title,
+			Description: preview.Description,
+			ImageUrl:    preview.ImageURL,
+		}); err != nil {
+			log.Printf("Failed to save preview for %s: %s", url, err)
+		}
+	}
+}
+
+func previewFresh(cached database.LinkPreview) bool {
+	ttl := previewCacheTTL
+	if !cached.Ok {
+		ttl = previewRetryTTL
+	}
+	return time.Since(cached.FetchedAt) < ttl
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	accessTokenTTL = time.Hour
+	// mfaTokenTTL is how long a user has to enter their second factor after
+	// getting their password right.
+	mfaTokenTTL = 5 * time.Minute
+)
+
+type loginRes struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Email     string    `json:"email"`
+	Token     string    `json:"token"`
+}
+
+// mfaChallengeRes is returned instead of loginRes when the user still has
+// to present a second factor. EnrollmentRequired is set for admins who have
+// not set up 2FA yet: the token then only lets them enroll.
+type mfaChallengeRes struct {
+	MFARequired        bool   `json:"mfa_required"`
+	EnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
+	MFAToken           string `json:"mfa_token"`
+}
+
+func (cfg *apiConfig) handleLogin(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	type parameters struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	user, err := cfg.database.GetUserByEmail(r.Context(), database.GetUserByEmailParams{
+		Email:    params.Email,
+		TenantID: tenant.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
+	match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+	if err != nil || !match {
+		respondWithError(w, http.StatusUnauthorized, "Incorrect email or password")
+		return
+	}
+
+	cfg.respondWithLogin(w, r, user)
+}
+
+// respondWithLogin finishes the first login step. Users with 2FA, and
+// admins who must set it up, get an MFA challenge; everyone else gets an
+// access token straight away.
+func (cfg *apiConfig) respondWithLogin(w http.ResponseWriter, r *http.Request, user database.User) {
+	enabled, err := cfg.twoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if !enabled && !user.IsAdmin {
+		cfg.respondWithAccessToken(w, user)
+		return
+	}
+
+	token, err := auth.MakeMFAToken(user.ID, cfg.jwtSecret, mfaTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not create access token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, mfaChallengeRes{
+		MFARequired:        true,
+		EnrollmentRequired: !enabled,
+		MFAToken:           token,
+	})
+}
+
+// respondWithAccessToken issues an access token for a user who has proven
+// who they are.
+func (cfg *apiConfig) respondWithAccessToken(w http.ResponseWriter, user database.User) {
+	token, err := auth.MakeJWT(user.ID, cfg.jwtSecret, accessTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not create access token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, loginRes{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Email:     user.Email,
+		Token:     token,
+	})
+}
@@ -5,24 +5,53 @@ import (
 	"database/sql"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"time"
 
+	"github.com/ckm54/go-projects/chirpy/internal/activitypub"
 	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/ckm54/go-projects/chirpy/internal/compress"
 	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/dbrouter"
+	"github.com/ckm54/go-projects/chirpy/internal/httpcache"
+	"github.com/ckm54/go-projects/chirpy/internal/linkpreview"
+	"github.com/ckm54/go-projects/chirpy/internal/oidc"
+	"github.com/ckm54/go-projects/chirpy/internal/ratelimit"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
-	fileserverHits atomic.Int32
+	fileserverHits tenantHits
+	db             *sql.DB
+	dbRouter       *dbrouter.Router
 	database       *database.Queries
-	platform       string
+	jwtSecret      string
+	apiKeyLimiter  *ratelimit.Limiter
+	mfaLimiter     *ratelimit.Limiter
+	publicScheme   string
+	apClient       *activitypub.Client
+	oidcProvider   *oidc.Provider
+	previewFetcher linkpreview.Fetcher
+	// previewSlots bounds how many link previews are fetched at once.
+	previewSlots chan struct{}
+}
+
+type userRes struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username,omitempty"`
 }
 
 type ErrorRes struct {
@@ -31,46 +60,167 @@ type ErrorRes struct {
 
 var marshalError = "{\"error\": \"Error marshaling json\"}"
 
+// usernamePattern limits usernames to what works in a WebFinger acct: URI.
+var usernamePattern = regexp.MustCompile(`^[a-z0-9_]{1,30}$`)
+
 func main() {
 	mux := *http.NewServeMux()
 	godotenv.Load()
 	dbURL := os.Getenv("DB_URL")
-	platform := os.Getenv("PLATFORM")
 
 	const assetsDir = "./assets"
 	const port = "8080"
 
-	db, err := sql.Open("postgres", dbURL)
+	pool, err := loadPoolConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := openDB(dbURL, pool)
 	if err != nil {
 		log.Fatalf("Failed to connect to DB: %s", err)
 	}
 
-	dbQueries := database.New(db)
+	migrations, err := newMigrationProvider(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %s", err)
+	}
+
+	// Subcommands only need the database, not the server's settings.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(context.Background(), migrations, os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("Migration failed: %s", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "tenant" {
+		if err := checkSchemaVersion(context.Background(), migrations); err != nil {
+			log.Fatalf("Refusing to run: %s", err)
+		}
+		if err := runTenantCommand(context.Background(), database.New(db), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		log.Fatal("JWT_SECRET must be set")
+	}
+
+	apiKeyRate := 60
+	if v := os.Getenv("API_KEY_RATE_PER_MINUTE"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 {
+			log.Fatalf("Invalid API_KEY_RATE_PER_MINUTE: %q", v)
+		}
+		apiKeyRate = parsed
+	}
+
+	// Scheme used in ActivityPub ids; only worth changing for local testing.
+	publicScheme := os.Getenv("PUBLIC_SCHEME")
+	if publicScheme == "" {
+		publicScheme = "https"
+	}
+
+	migrateOnStart := flag.Bool("migrate", false, "apply pending database migrations before serving")
+	flag.Parse()
+
+	if err := prepareSchema(context.Background(), migrations, *migrateOnStart); err != nil {
+		log.Fatalf("Refusing to start: %s", err)
+	}
+
+	// Reads that can tolerate replication lag go to DB_REPLICA_URL if set,
+	// except for a user who has just written.
+	var replica *sql.DB
+	if replicaURL := os.Getenv("DB_REPLICA_URL"); replicaURL != "" {
+		replica, err = openDB(replicaURL, pool)
+		if err != nil {
+			log.Fatalf("Failed to connect to replica DB: %s", err)
+		}
+	}
+	stickiness := 5 * time.Second
+	if v := os.Getenv("DB_REPLICA_STICKINESS"); v != "" {
+		stickiness, err = time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("Invalid DB_REPLICA_STICKINESS: %q", v)
+		}
+	}
+	dbRouter := dbrouter.New(db, replica, stickiness, replicaReads...)
+
+	dbQueries := database.New(dbRouter)
+
 	apiCfg := apiConfig{
-		database: dbQueries,
-		platform: platform,
+		db:             db,
+		dbRouter:       dbRouter,
+		database:       dbQueries,
+		jwtSecret:      jwtSecret,
+		apiKeyLimiter:  ratelimit.New(apiKeyRate, apiKeyRate),
+		mfaLimiter:     ratelimit.New(mfaAttemptsPerMinute, mfaAttemptsPerMinute),
+		publicScheme:   publicScheme,
+		apClient:       activitypub.NewClient(deliveryTimeout),
+		previewFetcher: linkpreview.NewHTTPFetcher(previewTimeout, previewMaxBytes),
+		previewSlots:   make(chan struct{}, previewFetchers),
 	}
 
-	mux.HandleFunc("GET /admin/metrics", apiCfg.handlerMetrics)
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		provider, err := oidc.Discover(context.Background(), oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		}, nil)
+		if err != nil {
+			log.Fatalf("Failed to set up OIDC login: %s", err)
+		}
+		apiCfg.oidcProvider = provider
 
-	mux.HandleFunc("POST /admin/reset", apiCfg.handlerReset)
+		mux.HandleFunc("GET /api/auth/oidc/login", apiCfg.middlewareTenant(apiCfg.handleOIDCLogin))
+		mux.HandleFunc("GET /api/auth/oidc/callback", apiCfg.middlewareTenant(apiCfg.handleOIDCCallback))
+	}
 
-	// mux.HandleFunc("POST /api/validate_chirp", handleValidateChirp)
-	mux.HandleFunc("POST /api/users", apiCfg.handleRegister)
-	mux.HandleFunc("POST /api/chirps", apiCfg.handleCreateChirp)
-	mux.HandleFunc("GET /api/chirps", apiCfg.handleGetChirps)
-	mux.HandleFunc("GET /api/chirps/{id}", apiCfg.handleGetChirp)
+	mux.Handle("GET /admin/metrics", httpcache.Control(httpcache.NoStore, apiCfg.middlewareTenant(apiCfg.handlerMetrics)))
 
-	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("POST /admin/reset", apiCfg.middlewareTenant(apiCfg.handlerReset))
+
+	// mux.HandleFunc("POST /api/validate_chirp", handleValidateChirp)
+	mux.HandleFunc("POST /api/users", apiCfg.middlewareTenant(apiCfg.handleRegister))
+	mux.HandleFunc("POST /api/login", apiCfg.middlewareTenant(apiCfg.handleLogin))
+	mux.HandleFunc("POST /api/login/2fa", apiCfg.middlewareMFAToken(apiCfg.handleLoginSecondFactor))
+	mux.HandleFunc("POST /api/2fa/totp", apiCfg.middlewareEnrollment(apiCfg.handleEnrollTOTP))
+	mux.HandleFunc("POST /api/2fa/totp/confirm", apiCfg.middlewareEnrollment(apiCfg.handleConfirmTOTP))
+	mux.HandleFunc("POST /api/2fa/disable", apiCfg.middlewareJWT(apiCfg.handleDisableTwoFactor))
+
+	mux.HandleFunc("POST /api/keys", apiCfg.middlewareJWT(apiCfg.handleCreateAPIKey))
+	mux.HandleFunc("GET /api/keys", apiCfg.middlewareJWT(apiCfg.handleGetAPIKeys))
+	mux.HandleFunc("DELETE /api/keys/{id}", apiCfg.middlewareJWT(apiCfg.handleRevokeAPIKey))
+
+	mux.HandleFunc("POST /api/chirps", apiCfg.middlewareAuth(auth.ScopeWrite, apiCfg.handleCreateChirp))
+	mux.HandleFunc("DELETE /api/chirps/{id}", apiCfg.middlewareAuth(auth.ScopeDelete, apiCfg.handleDeleteChirp))
+	mux.Handle("GET /api/chirps", httpcache.Control(httpcache.Revalidate, apiCfg.middlewareTenant(apiCfg.handleGetChirps)))
+	mux.Handle("GET /api/chirps/{id}", httpcache.Control(httpcache.ShortLived, apiCfg.middlewareTenant(apiCfg.handleGetChirp)))
+
+	mux.HandleFunc("GET /api/notifications", apiCfg.middlewareAuth(auth.ScopeRead, apiCfg.handleGetNotifications))
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.middlewareAuth(auth.ScopeWrite, apiCfg.handleMarkNotificationsRead))
+	mux.HandleFunc("GET /api/notifications/preferences", apiCfg.middlewareAuth(auth.ScopeRead, apiCfg.handleGetNotificationPreferences))
+	mux.HandleFunc("PUT /api/notifications/preferences", apiCfg.middlewareAuth(auth.ScopeWrite, apiCfg.handleUpdateNotificationPreferences))
+
+	mux.HandleFunc("GET /.well-known/webfinger", apiCfg.middlewareTenant(apiCfg.handleWebFinger))
+	mux.HandleFunc("GET /ap/users/{username}", apiCfg.middlewareTenant(apiCfg.handleActor))
+	mux.HandleFunc("GET /ap/users/{username}/outbox", apiCfg.middlewareTenant(apiCfg.handleOutbox))
+	mux.HandleFunc("GET /ap/users/{username}/followers", apiCfg.middlewareTenant(apiCfg.handleFollowers))
+	mux.HandleFunc("POST /ap/users/{username}/inbox", apiCfg.middlewareTenant(apiCfg.handleInbox))
+	mux.HandleFunc("GET /ap/notes/{id}", apiCfg.middlewareTenant(apiCfg.handleNote))
+
+	mux.Handle("GET /api/healthz", httpcache.Control(httpcache.NoStore, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(200)
 		w.Write([]byte("OK"))
-	})
+	})))
 
 	rootFs := http.FileServer(http.Dir("."))
 
 	rootWithMetrics := apiCfg.middlewareMetricsInc(rootFs)
-	fs := apiCfg.middlewareMetricsInc(http.FileServer(http.Dir(assetsDir)))
+	fs := httpcache.Control(httpcache.Static, apiCfg.middlewareMetricsInc(http.FileServer(http.Dir(assetsDir))))
 
 	mux.Handle("/app", http.StripPrefix("/app", rootWithMetrics))
 	mux.Handle("/app/", http.StripPrefix("/app", rootWithMetrics))
@@ -78,50 +228,83 @@ func main() {
 	mux.Handle("/assets/", http.StripPrefix("/assets", fs))
 
 	server := &http.Server{
-		Handler: &mux,
+		Handler: compress.Middleware(apiCfg.middlewareReadYourWrites(&mux)),
 		Addr:    ":" + port,
 	}
 
 	server.ListenAndServe()
 }
 
+// tenantHits counts fileserver hits per tenant. The zero value is ready to
+// use.
+type tenantHits struct {
+	counts sync.Map // tenant ID -> *atomic.Int32
+}
+
+func (h *tenantHits) add(tenantID uuid.UUID) {
+	counter, _ := h.counts.LoadOrStore(tenantID, new(atomic.Int32))
+	counter.(*atomic.Int32).Add(1)
+}
+
+func (h *tenantHits) load(tenantID uuid.UUID) int32 {
+	counter, ok := h.counts.Load(tenantID)
+	if !ok {
+		return 0
+	}
+	return counter.(*atomic.Int32).Load()
+}
+
+func (h *tenantHits) reset(tenantID uuid.UUID) {
+	h.counts.Delete(tenantID)
+}
+
+// middlewareMetricsInc counts a hit for the request's tenant. Files are
+// served either way; hits on hosts without a tenant aren't counted.
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		cfg.fileserverHits.Add(1)
+		if tenant, err := cfg.database.GetTenantByHost(r.Context(), requestHost(r)); err == nil {
+			cfg.fileserverHits.add(tenant.ID)
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handlerMetrics(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
 	w.Header().Set("Content-Type", "text/html")
-	hits := cfg.fileserverHits.Load()
+	hits := cfg.fileserverHits.load(tenant.ID)
 	html := fmt.Sprintf("<html><body><h1>Welcome, Chirpy Admin</h1><p>Chirpy has been visited %d times!</p></body></html>", hits)
 	w.Write([]byte(html))
 }
 
-func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request) {
-	if cfg.platform != "dev" {
+func (cfg *apiConfig) handlerReset(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	if tenant.Platform != "dev" {
 		w.WriteHeader(403)
 		w.Write([]byte("Forbidden"))
 		return
 	}
 
-	if err := cfg.database.DeleteUsers(context.Background()); err != nil {
+	if err := cfg.database.DeleteUsers(context.Background(), tenant.ID); err != nil {
 		w.WriteHeader(500)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	cfg.fileserverHits.Store(0)
+	cfg.fileserverHits.reset(tenant.ID)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte("{\"message\": \"users deleted\"}"))
 }
 
-func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request, caller principal) {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
+	type parameters struct {
+		Body string `json:"body"`
+		// UserID is accepted from older clients and ignored; the author is
+		// always the caller.
+		UserID json.RawMessage `json:"user_id"`
+	}
 
-	chirpInfo := database.CreateChirpParams{}
+	chirpInfo := parameters{}
 	if err := decoder.Decode(&chirpInfo); err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		response := ErrorRes{
@@ -138,16 +321,40 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	cleanedBody, err := validateChirp(chirpInfo.Body)
+	bannedWords, err := cfg.database.GetBannedWords(r.Context(), caller.Tenant.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	cleanedBody, err := validateChirp(chirpInfo.Body, bannedWords)
 	if err != nil {
 		w.Header().Set("Content-Type", "text/plain")
 		w.WriteHeader(400)
 		w.Write([]byte(err.Error()))
+		return
 	}
 
-	chirp, err := cfg.database.CreateChirp(context.Background(), database.CreateChirpParams{
-		Body:   cleanedBody,
-		UserID: chirpInfo.UserID,
+	var chirp database.Chirp
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		author, err := q.GetTenantUser(r.Context(), database.GetTenantUserParams{
+			ID:       caller.UserID,
+			TenantID: caller.Tenant.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		chirp, err = q.CreateChirp(r.Context(), database.CreateChirpParams{
+			Body:     cleanedBody,
+			UserID:   caller.UserID,
+			TenantID: caller.Tenant.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return notifyMentions(r.Context(), q, caller.Tenant, author, chirp)
 	})
 
 	if err != nil {
@@ -164,91 +371,140 @@ func (cfg *apiConfig) handleCreateChirp(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	go cfg.federateChirp(caller.Tenant, chirp)
+	go cfg.fetchPreviews(chirp)
+
 	w.WriteHeader(201)
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(data)
 }
 
-func (cfg *apiConfig) handleGetChirps(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.database.GetChirps(r.Context())
+func (cfg *apiConfig) handleGetChirps(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	chirps, err := cfg.database.GetChirps(r.Context(), tenant.ID)
 	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	data, err := json.Marshal(&chirps)
+	res, err := cfg.withPreviews(r.Context(), chirps)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	data, err := json.Marshal(&res)
 	if err != nil {
-		w.WriteHeader(500)
 		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(500)
 		w.Write([]byte(err.Error()))
 		return
 	}
 
-	w.WriteHeader(200)
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(data)
+	httpcache.WriteJSON(w, r, http.StatusOK, data)
 }
 
-func (cfg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handleGetChirp(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	chirp, ok := cfg.getChirpForRequest(w, r, tenant)
+	if !ok {
+		return
+	}
+
+	res, err := cfg.withPreviews(r.Context(), []database.Chirp{chirp})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	data, err := json.Marshal(&res[0])
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(400)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	httpcache.WriteJSON(w, r, http.StatusOK, data)
+}
+
+// getChirpForRequest loads the chirp named by the {id} path value, writing an
+// error response and returning false if it can't.
+func (cfg *apiConfig) getChirpForRequest(w http.ResponseWriter, r *http.Request, tenant database.Tenant) (database.Chirp, bool) {
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(400)
 		w.Write([]byte("{\"error\": \"Bad Request\"}"))
+		return database.Chirp{}, false
 	}
 
-	chirp, err := cfg.database.GetChirp(r.Context(), id)
+	chirp, err := cfg.database.GetChirp(r.Context(), database.GetChirpParams{
+		ID:       id,
+		TenantID: tenant.ID,
+	})
 	if err != nil {
 		// Check if the error is "no rows found"
 		if errors.Is(err, sql.ErrNoRows) {
 			w.WriteHeader(http.StatusNotFound)
 			w.Write([]byte(`{"error": "Chirp not found"}`))
-			return
+			return database.Chirp{}, false
 		}
 
 		// Other DB error
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error": "Internal Server Error"}`))
-		return
+		return database.Chirp{}, false
 	}
 
-	data, err := json.Marshal(&chirp)
+	return chirp, true
+}
+
+func (cfg *apiConfig) handleDeleteChirp(w http.ResponseWriter, r *http.Request, caller principal) {
+	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(400)
-		w.Write([]byte(err.Error()))
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
 		return
 	}
 
-	w.WriteHeader(200)
-	w.Header().Set("Comtent-Type", "application/json")
-	w.Write(data)
+	deleted, err := cfg.database.DeleteChirp(r.Context(), database.DeleteChirpParams{
+		ID:     id,
+		UserID: caller.UserID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, http.StatusNotFound, "Chirp not found")
+		return
+	}
 
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func validateChirp(s string) (string, error) {
+func validateChirp(s string, bannedWords []string) (string, error) {
 	maxLen := 140
 
 	if len(s) > maxLen {
 		return "", fmt.Errorf("chirp is too long. got %d characters max is %d characters", len(s), maxLen)
 	}
 
-	replacements := map[string]string{
-		"kerfuffle": "****",
-		"sharbert":  "****",
-		"fornax":    "****",
+	replacements := make(map[string]string, len(bannedWords))
+	for _, word := range bannedWords {
+		replacements[word] = "****"
 	}
 
 	return replaceCaseInsensitive(s, replacements), nil
 
 }
 
-func (cfg *apiConfig) handleRegister(w http.ResponseWriter, r *http.Request) {
+func (cfg *apiConfig) handleRegister(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Username string `json:"username"`
 	}
 
 	userInfo := parameters{}
@@ -268,6 +524,11 @@ func (cfg *apiConfig) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if userInfo.Username != "" && !usernamePattern.MatchString(userInfo.Username) {
+		respondWithError(w, http.StatusBadRequest, "username must be 1-30 lowercase letters, digits or underscores")
+		return
+	}
+
 	hashedPass, err := auth.HashPassword(userInfo.Password)
 	if err != nil {
 		w.WriteHeader(400)
@@ -278,6 +539,8 @@ func (cfg *apiConfig) handleRegister(w http.ResponseWriter, r *http.Request) {
 	user, err := cfg.database.CreateUser(context.Background(), database.CreateUserParams{
 		Email:          userInfo.Email,
 		HashedPassword: hashedPass,
+		TenantID:       tenant.ID,
+		Username:       sql.NullString{String: userInfo.Username, Valid: userInfo.Username != ""},
 	})
 	if err != nil {
 		w.WriteHeader(400)
@@ -285,11 +548,12 @@ func (cfg *apiConfig) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res := database.User{
+	res := userRes{
 		ID:        user.ID,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		Email:     user.Email,
+		Username:  user.Username.String,
 	}
 	userData, err := json.Marshal(&res)
 	if err != nil {
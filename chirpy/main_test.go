@@ -0,0 +1,75 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/dbrouter"
+	"github.com/google/uuid"
+)
+
+// Test that one tenant's reset leaves the other tenants' hits alone.
+func TestTenantHits(t *testing.T) {
+	var hits tenantHits
+	a, b := uuid.New(), uuid.New()
+
+	hits.add(a)
+	hits.add(a)
+	hits.add(b)
+	if hits.load(a) != 2 || hits.load(b) != 1 {
+		t.Fatalf("expected 2 and 1 hits, got %d and %d", hits.load(a), hits.load(b))
+	}
+
+	hits.reset(a)
+	if hits.load(a) != 0 || hits.load(b) != 1 {
+		t.Fatalf("expected only a's hits to be reset, got %d and %d", hits.load(a), hits.load(b))
+	}
+}
+
+// Test that the user_id older clients send is ignored while other unknown
+// fields are still refused.
+func TestHandleCreateChirp_LegacyUserID(t *testing.T) {
+	db := (&stubDB{}).open(t)
+	cfg := &apiConfig{db: db, dbRouter: dbrouter.New(db, nil, time.Second), database: database.New(db)}
+
+	tests := []struct {
+		body    string
+		refused bool
+	}{
+		{`{"body":"hello","user_id":"` + uuid.NewString() + `"}`, false},
+		{`{"body":"hello","author":"someone"}`, true},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/chirps", strings.NewReader(tt.body))
+		cfg.handleCreateChirp(rec, req, principal{UserID: uuid.New()})
+		// The stub database has no users, so an accepted body fails later.
+		if got := strings.Contains(rec.Body.String(), "Bad Request"); got != tt.refused {
+			t.Fatalf("%s: expected the body to be refused: %v, got %d %s", tt.body, tt.refused, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestRespondAuthError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{lookupError(sql.ErrNoRows), http.StatusUnauthorized},
+		{errors.New("token is expired"), http.StatusUnauthorized},
+		{lookupError(errors.New("connection refused")), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		respondAuthError(rec, tt.err)
+		if rec.Code != tt.want {
+			t.Fatalf("%v: expected %d, got %d", tt.err, tt.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/dbrouter"
+	"github.com/google/uuid"
+)
+
+// principal is the caller behind an authenticated request.
+type principal struct {
+	UserID uuid.UUID
+	Tenant database.Tenant
+	// APIKeyID is set when the request was authenticated with an API key
+	// rather than a JWT.
+	APIKeyID uuid.NullUUID
+	Scopes   []string
+}
+
+func (p principal) hasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+type authedHandler func(http.ResponseWriter, *http.Request, principal)
+
+// middlewareAuth accepts either a JWT ("Authorization: Bearer <token>") or an
+// API key ("Authorization: ApiKey <key>") and requires the given scope.
+func (cfg *apiConfig) middlewareAuth(scope string, handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := cfg.resolveTenant(w, r)
+		if !ok {
+			return
+		}
+
+		caller, err := cfg.authenticate(r, tenant)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if caller.APIKeyID.Valid {
+			if ok, wait := cfg.apiKeyLimiter.Allow(caller.APIKeyID.UUID.String()); !ok {
+				w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+				respondWithError(w, http.StatusTooManyRequests, "Too Many Requests")
+				return
+			}
+		}
+
+		if !caller.hasScope(scope) {
+			respondWithError(w, http.StatusForbidden, fmt.Sprintf("missing %q scope", scope))
+			return
+		}
+
+		if caller.APIKeyID.Valid {
+			// Only requests that get through are recorded, so a rejected
+			// or throttled key doesn't cost a write each time.
+			if err := cfg.database.MarkAPIKeyUsed(r.Context(), caller.APIKeyID.UUID); err != nil {
+				respondWithError(w, http.StatusInternalServerError, "Could not record API key use")
+				return
+			}
+		}
+
+		// JWT callers are already tagged by middlewareReadYourWrites, API
+		// key callers are only known from here on.
+		handler(w, r.WithContext(dbrouter.WithUser(r.Context(), caller.UserID.String())), caller)
+	}
+}
+
+// middlewareJWT only accepts a JWT. It guards endpoints an API key must not
+// reach, such as managing API keys themselves.
+func (cfg *apiConfig) middlewareJWT(handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := cfg.resolveTenant(w, r)
+		if !ok {
+			return
+		}
+
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		caller, err := cfg.authenticateJWT(r, tenant, token)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		handler(w, r, caller)
+	}
+}
+
+// middlewareReadYourWrites tags requests carrying a valid JWT with the
+// caller's user id, so the database router keeps their reads on the primary
+// right after they write, even on public routes. It doesn't reject anything;
+// the auth middlewares still decide who may do what.
+func (cfg *apiConfig) middlewareReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, err := auth.GetBearerToken(r.Header); err == nil {
+			if userID, err := auth.ValidateJWT(token, cfg.jwtSecret); err == nil {
+				r = r.WithContext(dbrouter.WithUser(r.Context(), userID.String()))
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate identifies the caller and checks they belong to tenant, so a
+// token from one instance can't be replayed against another.
+func (cfg *apiConfig) authenticate(r *http.Request, tenant database.Tenant) (principal, error) {
+	if token, err := auth.GetBearerToken(r.Header); err == nil {
+		return cfg.authenticateJWT(r, tenant, token)
+	}
+
+	key, err := auth.GetAPIKey(r.Header)
+	if err != nil {
+		return principal{}, errors.New("no valid credentials")
+	}
+
+	apiKey, err := cfg.database.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(key))
+	if err != nil {
+		return principal{}, lookupError(err)
+	}
+
+	if _, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       apiKey.UserID,
+		TenantID: tenant.ID,
+	}); err != nil {
+		return principal{}, lookupError(err)
+	}
+
+	return principal{
+		UserID:   apiKey.UserID,
+		Tenant:   tenant,
+		APIKeyID: uuid.NullUUID{UUID: apiKey.ID, Valid: true},
+		Scopes:   apiKey.Scopes,
+	}, nil
+}
+
+func (cfg *apiConfig) authenticateJWT(r *http.Request, tenant database.Tenant, token string) (principal, error) {
+	userID, err := auth.ValidateJWT(token, cfg.jwtSecret)
+	if err != nil {
+		return principal{}, err
+	}
+
+	if _, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       userID,
+		TenantID: tenant.ID,
+	}); err != nil {
+		return principal{}, lookupError(err)
+	}
+
+	return principal{UserID: userID, Tenant: tenant, Scopes: auth.AllScopes}, nil
+}
+
+// errAuthUnavailable marks an authentication that failed because the
+// database couldn't be asked, rather than because of the credentials.
+var errAuthUnavailable = errors.New("could not check credentials")
+
+// lookupError wraps a failed credential lookup. A missing row means the
+// credentials are bad; anything else means they couldn't be checked.
+func lookupError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errAuthUnavailable, err)
+}
+
+// respondAuthError answers a failed authentication with a 401, or a 500 when
+// the credentials couldn't be checked.
+func respondAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errAuthUnavailable) {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/sql/schema"
+	"github.com/pressly/goose/v3"
+)
+
+const migrateUsage = "usage: chirpy migrate up|down|status"
+
+func newMigrationProvider(db *sql.DB) (*goose.Provider, error) {
+	return goose.NewProvider(goose.DialectPostgres, db, schema.FS)
+}
+
+// runMigrateCommand handles the "chirpy migrate <action>" subcommand.
+func runMigrateCommand(ctx context.Context, provider *goose.Provider, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			fmt.Fprintln(out, "no pending migrations")
+		}
+		for _, result := range results {
+			fmt.Fprintf(out, "applied %s (%s)\n", result.Source.Path, result.Duration.Round(time.Millisecond))
+		}
+
+	case "down":
+		result, err := provider.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "rolled back %s\n", result.Source.Path)
+
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%-25s %s\n", appliedAt, status.Source.Path)
+		}
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
+
+// prepareSchema runs at startup: it applies pending migrations when the
+// server was started with -migrate, then refuses to go on if the schema is
+// still behind this binary.
+func prepareSchema(ctx context.Context, provider *goose.Provider, migrate bool) error {
+	if migrate {
+		if _, err := provider.Up(ctx); err != nil {
+			return fmt.Errorf("could not apply migrations: %w", err)
+		}
+	}
+	return checkSchemaVersion(ctx, provider)
+}
+
+// checkSchemaVersion returns an error if the database has not had every
+// migration embedded in this binary applied.
+func checkSchemaVersion(ctx context.Context, provider *goose.Provider) error {
+	current, target, err := provider.GetVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("could not read schema version: %w", err)
+	}
+	return compareSchemaVersions(current, target)
+}
+
+// compareSchemaVersions only objects to a schema that is behind. A newer
+// schema is fine, so a binary can still serve while the next release
+// rolls out.
+func compareSchemaVersions(current, target int64) error {
+	if current < target {
+		return fmt.Errorf("database schema is at version %d but this binary expects %d; run \"chirpy migrate up\" or start with -migrate", current, target)
+	}
+	return nil
+}
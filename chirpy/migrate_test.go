@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/sql/schema"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/database"
+)
+
+// Test that every migration on disk is embedded, in order, with versions
+// 1..n.
+func TestSchemaFS(t *testing.T) {
+	onDisk, err := filepath.Glob(filepath.Join("sql", "schema", "*.sql"))
+	if err != nil {
+		t.Fatalf("unexpected error listing migrations: %v", err)
+	}
+	for i, path := range onDisk {
+		onDisk[i] = filepath.Base(path)
+	}
+
+	embedded, err := fs.Glob(schema.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("unexpected error listing embedded migrations: %v", err)
+	}
+	if len(onDisk) == 0 || !slices.Equal(onDisk, embedded) {
+		t.Fatalf("embedded migrations %v don't match %v", embedded, onDisk)
+	}
+
+	provider, _ := newTestMigrationProvider(t)
+	sources := provider.ListSources()
+	if len(sources) != len(embedded) {
+		t.Fatalf("expected %d migrations, got %d", len(embedded), len(sources))
+	}
+	for i, source := range sources {
+		if source.Version != int64(i+1) || filepath.Base(source.Path) != embedded[i] {
+			t.Fatalf("expected %s as version %d, got %s as %d", embedded[i], i+1, source.Path, source.Version)
+		}
+	}
+}
+
+// Test that every migration parses in both directions by running them all up
+// and back down against a driver that accepts any statement.
+func TestMigrationsParse(t *testing.T) {
+	provider, stub := newTestMigrationProvider(t)
+	ctx := context.Background()
+
+	results, err := provider.Up(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error migrating up: %v", err)
+	}
+	if len(results) != len(provider.ListSources()) {
+		t.Fatalf("expected every migration to apply, got %d", len(results))
+	}
+	if stub.execs.Load() == 0 {
+		t.Fatalf("expected migrations to run statements")
+	}
+
+	if _, err := provider.DownTo(ctx, 0); err != nil {
+		t.Fatalf("unexpected error migrating down: %v", err)
+	}
+}
+
+func TestRunMigrateCommand(t *testing.T) {
+	provider, _ := newTestMigrationProvider(t)
+	ctx := context.Background()
+	last := provider.ListSources()[len(provider.ListSources())-1]
+
+	var out bytes.Buffer
+	if err := runMigrateCommand(ctx, provider, []string{"status"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "pending") {
+		t.Fatalf("expected pending migrations, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runMigrateCommand(ctx, provider, []string{"up"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "applied "+last.Path) {
+		t.Fatalf("expected %s to be applied, got:\n%s", last.Path, out.String())
+	}
+
+	out.Reset()
+	if err := runMigrateCommand(ctx, provider, []string{"up"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "no pending migrations\n" {
+		t.Fatalf("expected nothing to apply, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runMigrateCommand(ctx, provider, []string{"down"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "rolled back "+last.Path+"\n" {
+		t.Fatalf("expected %s to be rolled back, got:\n%s", last.Path, out.String())
+	}
+
+	for _, args := range [][]string{nil, {"sideways"}, {"up", "now"}} {
+		if err := runMigrateCommand(ctx, provider, args, &out); err == nil || err.Error() != migrateUsage {
+			t.Fatalf("expected usage for %v, got %v", args, err)
+		}
+	}
+}
+
+// Test the -migrate flag and that the server refuses to start on an old
+// schema.
+func TestPrepareSchema(t *testing.T) {
+	ctx := context.Background()
+
+	provider, _ := newTestMigrationProvider(t)
+	if err := prepareSchema(ctx, provider, false); err == nil {
+		t.Fatalf("expected an unmigrated database to be refused")
+	}
+	if err := prepareSchema(ctx, provider, true); err != nil {
+		t.Fatalf("expected -migrate to bring the schema up to date, got %v", err)
+	}
+	if err := prepareSchema(ctx, provider, false); err != nil {
+		t.Fatalf("expected a migrated database to be accepted, got %v", err)
+	}
+
+	if _, err := provider.Down(ctx); err != nil {
+		t.Fatalf("unexpected error migrating down: %v", err)
+	}
+	if err := prepareSchema(ctx, provider, false); err == nil {
+		t.Fatalf("expected a schema one version behind to be refused")
+	}
+}
+
+func TestCompareSchemaVersions(t *testing.T) {
+	tests := []struct {
+		name            string
+		current, target int64
+		wantErr         bool
+	}{
+		{"up to date", 10, 10, false},
+		{"behind", 9, 10, true},
+		{"empty database", 0, 10, true},
+		{"ahead during a rollout", 11, 10, false},
+	}
+
+	for _, tt := range tests {
+		err := compareSchemaVersions(tt.current, tt.target)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+	}
+}
+
+// newTestMigrationProvider loads the embedded migrations with the versions
+// kept in memory and a database that accepts every statement.
+func newTestMigrationProvider(t *testing.T) (*goose.Provider, *stubDB) {
+	t.Helper()
+
+	stub := &stubDB{}
+	provider, err := goose.NewProvider("", stub.open(t), schema.FS, goose.WithStore(&memoryStore{applied: map[int64]time.Time{}}))
+	if err != nil {
+		t.Fatalf("unexpected error loading migrations: %v", err)
+	}
+	return provider, stub
+}
+
+// memoryStore is a goose version table kept in memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	applied map[int64]time.Time
+}
+
+func (s *memoryStore) Tablename() string { return goose.DefaultTablename }
+
+func (s *memoryStore) CreateVersionTable(ctx context.Context, db database.DBTxConn) error {
+	return nil
+}
+
+func (s *memoryStore) Insert(ctx context.Context, db database.DBTxConn, req database.InsertRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applied[req.Version] = time.Now()
+	return nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, db database.DBTxConn, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.applied, version)
+	return nil
+}
+
+func (s *memoryStore) GetMigration(ctx context.Context, db database.DBTxConn, version int64) (*database.GetMigrationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	appliedAt, ok := s.applied[version]
+	if !ok {
+		return nil, database.ErrVersionNotFound
+	}
+	return &database.GetMigrationResult{Timestamp: appliedAt, IsApplied: true}, nil
+}
+
+func (s *memoryStore) GetLatestVersion(ctx context.Context, db database.DBTxConn) (int64, error) {
+	migrations, _ := s.ListMigrations(ctx, db)
+	if len(migrations) == 0 {
+		return 0, database.ErrVersionNotFound
+	}
+	return migrations[0].Version, nil
+}
+
+func (s *memoryStore) ListMigrations(ctx context.Context, db database.DBTxConn) ([]*database.ListMigrationsResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var migrations []*database.ListMigrationsResult
+	for version := range s.applied {
+		migrations = append(migrations, &database.ListMigrationsResult{Version: version, IsApplied: true})
+	}
+	slices.SortFunc(migrations, func(a, b *database.ListMigrationsResult) int {
+		return int(b.Version - a.Version)
+	})
+	return migrations, nil
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Notification types. Users can turn each one off in their preferences.
+const (
+	notificationMention = "mention"
+	notificationReply   = "reply"
+	notificationLike    = "like"
+	notificationFollow  = "follow"
+)
+
+var notificationTypes = []string{notificationMention, notificationReply, notificationLike, notificationFollow}
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^a-z0-9_])@([a-z0-9_]{1,30})\b`)
+
+type notificationRes struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Type      string     `json:"type"`
+	Actor     string     `json:"actor"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	RemoteURI string     `json:"remote_uri,omitempty"`
+	Read      bool       `json:"read"`
+}
+
+type notificationsRes struct {
+	UnreadCount   int64             `json:"unread_count"`
+	Notifications []notificationRes `json:"notifications"`
+}
+
+// withTx runs fn inside a transaction, so that a write and the
+// notifications it triggers are committed together.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(cfg.database.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Transactions run on the primary directly, so the router can't see
+	// the writes in them.
+	cfg.dbRouter.MarkWrite(ctx)
+	return nil
+}
+
+// mentionedUsernames returns the distinct @usernames in a chirp body.
+func mentionedUsernames(body string) []string {
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if !slices.Contains(usernames, match[1]) {
+			usernames = append(usernames, match[1])
+		}
+	}
+	return usernames
+}
+
+// notifyMentions records a mention notification for every local user named
+// in chirp. It must be called with the Queries of the transaction that
+// created the chirp.
+func notifyMentions(ctx context.Context, q *database.Queries, tenant database.Tenant, author database.User, chirp database.Chirp) error {
+	for _, username := range mentionedUsernames(chirp.Body) {
+		mentioned, err := q.GetUserByUsername(ctx, database.GetUserByUsernameParams{
+			Username: sql.NullString{String: username, Valid: true},
+			TenantID: tenant.ID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if mentioned.ID == author.ID {
+			continue
+		}
+
+		if err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:  mentioned.ID,
+			Type:    notificationMention,
+			Actor:   displayName(author),
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func displayName(user database.User) string {
+	if user.Username.Valid {
+		return "@" + user.Username.String
+	}
+	return user.Email
+}
+
+func (cfg *apiConfig) handleGetNotifications(w http.ResponseWriter, r *http.Request, caller principal) {
+	limit := defaultNotificationLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > maxNotificationLimit {
+			respondWithError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = parsed
+	}
+
+	notifications, err := cfg.database.GetNotifications(r.Context(), database.GetNotificationsParams{
+		UserID:     caller.UserID,
+		UnreadOnly: r.URL.Query().Get("unread") == "true",
+		RowLimit:   int32(limit),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	unread, err := cfg.database.CountUnreadNotifications(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	res := notificationsRes{
+		UnreadCount:   unread,
+		Notifications: make([]notificationRes, 0, len(notifications)),
+	}
+	for _, n := range notifications {
+		item := notificationRes{
+			ID:        n.ID,
+			CreatedAt: n.CreatedAt,
+			Type:      n.Type,
+			Actor:     n.Actor,
+			RemoteURI: n.RemoteUri.String,
+			Read:      n.ReadAt.Valid,
+		}
+		if n.ChirpID.Valid {
+			item.ChirpID = &n.ChirpID.UUID
+		}
+		res.Notifications = append(res.Notifications, item)
+	}
+
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handleMarkNotificationsRead marks the given notifications as read, or all
+// of them when no ids are sent.
+func (cfg *apiConfig) handleMarkNotificationsRead(w http.ResponseWriter, r *http.Request, caller principal) {
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+
+	params := parameters{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Bad Request")
+			return
+		}
+	}
+
+	var err error
+	if len(params.IDs) == 0 {
+		_, err = cfg.database.MarkAllNotificationsRead(r.Context(), caller.UserID)
+	} else {
+		_, err = cfg.database.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{
+			UserID: caller.UserID,
+			Ids:    params.IDs,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	unread, err := cfg.database.CountUnreadNotifications(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]int64{"unread_count": unread})
+}
+
+func (cfg *apiConfig) handleGetNotificationPreferences(w http.ResponseWriter, r *http.Request, caller principal) {
+	prefs, err := cfg.notificationPreferences(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// handleUpdateNotificationPreferences takes a map of notification type to
+// whether it is enabled. Types not included are left unchanged.
+func (cfg *apiConfig) handleUpdateNotificationPreferences(w http.ResponseWriter, r *http.Request, caller principal) {
+	params := map[string]bool{}
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	for notificationType := range params {
+		if !slices.Contains(notificationTypes, notificationType) {
+			respondWithError(w, http.StatusBadRequest, "unknown notification type "+strconv.Quote(notificationType))
+			return
+		}
+	}
+
+	err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+		for notificationType, enabled := range params {
+			if err := q.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{
+				UserID:  caller.UserID,
+				Type:    notificationType,
+				Enabled: enabled,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	prefs, err := cfg.notificationPreferences(r.Context(), caller.UserID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, prefs)
+}
+
+// notificationPreferences returns every notification type with whether it
+// is enabled. Types the user never changed default to enabled.
+func (cfg *apiConfig) notificationPreferences(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	stored, err := cfg.database.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := make(map[string]bool, len(notificationTypes))
+	for _, notificationType := range notificationTypes {
+		prefs[notificationType] = true
+	}
+	for _, pref := range stored {
+		prefs[pref.Type] = pref.Enabled
+	}
+	return prefs, nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/activitypub"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/dbrouter"
+	"github.com/google/uuid"
+)
+
+func TestMentionedUsernames(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{"hi @alice and @bob_2", []string{"alice", "bob_2"}},
+		{"@alice at the start, @alice again", []string{"alice"}},
+		{"mail me at carol@example.com", nil},
+		{"(@dave) and @erin.", []string{"dave", "erin"}},
+		{"no mentions here", nil},
+	}
+
+	for _, tt := range tests {
+		if got := mentionedUsernames(tt.body); !slices.Equal(got, tt.want) {
+			t.Fatalf("mentionedUsernames(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+// Test that withTx commits only when fn succeeds.
+func TestWithTx(t *testing.T) {
+	stub := &stubDB{}
+	db := stub.open(t)
+	cfg := &apiConfig{db: db, dbRouter: dbrouter.New(db, nil, time.Second)}
+	ctx := context.Background()
+
+	failed := errors.New("failed")
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		if err := q.CreateNotification(ctx, database.CreateNotificationParams{UserID: uuid.New(), Type: notificationLike}); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("expected fn's error, got %v", err)
+	}
+	if stub.commits.Load() != 0 || stub.rollbacks.Load() != 1 {
+		t.Fatalf("expected a rollback, got %d commits and %d rollbacks", stub.commits.Load(), stub.rollbacks.Load())
+	}
+
+	if err := cfg.withTx(ctx, func(q *database.Queries) error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stub.commits.Load() != 1 || stub.rollbacks.Load() != 1 {
+		t.Fatalf("expected a commit, got %d commits and %d rollbacks", stub.commits.Load(), stub.rollbacks.Load())
+	}
+}
+
+func TestNotifyMentions(t *testing.T) {
+	_, q := testTx(t)
+	ctx := context.Background()
+	tenant := createTestTenant(t, q)
+	author := createTestUser(t, q, tenant, "author")
+	alice := createTestUser(t, q, tenant, "alice")
+	// bob lives on another tenant, so @bob doesn't reach him.
+	bob := createTestUser(t, q, createTestTenant(t, q), "bob")
+
+	chirp, err := q.CreateChirp(ctx, database.CreateChirpParams{
+		Body:     "hi @alice, @bob, @nobody and me @author",
+		UserID:   author.ID,
+		TenantID: tenant.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating chirp: %v", err)
+	}
+	if err := notifyMentions(ctx, q, tenant, author, chirp); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := notificationsFor(t, q, alice)
+	if len(got) != 1 || got[0].Type != notificationMention || got[0].Actor != "@author" || got[0].ChirpID.UUID != chirp.ID {
+		t.Fatalf("expected alice to get one mention, got %+v", got)
+	}
+	for _, user := range []database.User{author, bob} {
+		if got := notificationsFor(t, q, user); len(got) != 0 {
+			t.Fatalf("expected no notifications for %s, got %+v", user.Username.String, got)
+		}
+	}
+}
+
+// Test that a disabled notification type is never stored, and that the
+// other types still are.
+func TestCreateNotificationPreferences(t *testing.T) {
+	_, q := testTx(t)
+	ctx := context.Background()
+	user := createTestUser(t, q, createTestTenant(t, q), "alice")
+
+	if err := q.SetNotificationPreference(ctx, database.SetNotificationPreferenceParams{
+		UserID:  user.ID,
+		Type:    notificationLike,
+		Enabled: false,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, notificationType := range []string{notificationLike, notificationFollow} {
+		if err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID: user.ID,
+			Type:   notificationType,
+			Actor:  "https://remote.example/users/bob",
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	got := notificationsFor(t, q, user)
+	if len(got) != 1 || got[0].Type != notificationFollow {
+		t.Fatalf("expected only the follow to be stored, got %+v", got)
+	}
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	_, q := testTx(t)
+	ctx := context.Background()
+	tenant := createTestTenant(t, q)
+	user := createTestUser(t, q, tenant, "alice")
+	other := createTestUser(t, q, tenant, "bob")
+
+	for _, owner := range []database.User{user, user, user, other} {
+		if err := q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID: owner.ID,
+			Type:   notificationFollow,
+			Actor:  "https://remote.example/users/carol",
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	notifications := notificationsFor(t, q, user)
+	otherID := notificationsFor(t, q, other)[0].ID
+
+	// Someone else's notification in the list is left alone.
+	marked, err := q.MarkNotificationsRead(ctx, database.MarkNotificationsReadParams{
+		UserID: user.ID,
+		Ids:    []uuid.UUID{notifications[0].ID, otherID},
+	})
+	if err != nil || marked != 1 {
+		t.Fatalf("expected one notification marked read, got %d (%v)", marked, err)
+	}
+	assertUnread(t, q, user, 2)
+	assertUnread(t, q, other, 1)
+
+	marked, err = q.MarkAllNotificationsRead(ctx, user.ID)
+	if err != nil || marked != 2 {
+		t.Fatalf("expected the other two marked read, got %d (%v)", marked, err)
+	}
+	assertUnread(t, q, user, 0)
+	assertUnread(t, q, other, 1)
+
+	unread, err := q.GetNotifications(ctx, database.GetNotificationsParams{UserID: user.ID, UnreadOnly: true, RowLimit: 10})
+	if err != nil || len(unread) != 0 {
+		t.Fatalf("expected no unread notifications, got %+v (%v)", unread, err)
+	}
+}
+
+// Test that an Undo only removes a follower when it undoes their follow.
+func TestUndoActivity(t *testing.T) {
+	_, q := testTx(t)
+	ctx := context.Background()
+	user := createTestUser(t, q, createTestTenant(t, q), "alice")
+	cfg := &apiConfig{database: q}
+
+	const (
+		actor    = "https://remote.example/users/bob"
+		followID = "https://remote.example/follows/1"
+		likeID   = "https://remote.example/likes/1"
+	)
+
+	tests := []struct {
+		name       string
+		object     string
+		wantRemain bool
+	}{
+		{"like by reference", `"` + likeID + `"`, true},
+		{"embedded like", `{"id":"` + likeID + `","type":"Like"}`, true},
+		{"follow by reference", `"` + followID + `"`, false},
+		{"embedded follow with another id", `{"id":"https://remote.example/follows/2","type":"Follow"}`, true},
+		{"embedded follow without an id", `{"type":"Follow"}`, true},
+		{"embedded follow", `{"id":"` + followID + `","type":"Follow"}`, false},
+	}
+
+	for _, tt := range tests {
+		if err := q.CreateFollower(ctx, database.CreateFollowerParams{
+			UserID:           user.ID,
+			ActorUri:         actor,
+			InboxUri:         actor + "/inbox",
+			FollowActivityID: followID,
+		}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		undo := activitypub.Activity{Type: activitypub.TypeUndo, Actor: actor, Object: json.RawMessage(tt.object)}
+		if err := cfg.undoActivity(ctx, user, undo); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		followers, err := q.GetFollowers(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if remain := len(followers) == 1; remain != tt.wantRemain {
+			t.Fatalf("%s: expected follower to remain: %v, got %+v", tt.name, tt.wantRemain, followers)
+		}
+	}
+}
+
+func notificationsFor(t *testing.T, q *database.Queries, user database.User) []database.Notification {
+	t.Helper()
+
+	notifications, err := q.GetNotifications(context.Background(), database.GetNotificationsParams{UserID: user.ID, RowLimit: 50})
+	if err != nil {
+		t.Fatalf("unexpected error getting notifications: %v", err)
+	}
+	return notifications
+}
+
+func assertUnread(t *testing.T, q *database.Queries, user database.User, want int64) {
+	t.Helper()
+
+	got, err := q.CountUnreadNotifications(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("unexpected error counting notifications: %v", err)
+	}
+	if got != want {
+		t.Fatalf("expected %d unread for %s, got %d", want, user.Username.String, got)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	oidcCookieName = "chirpy_oidc"
+	oidcCookiePath = "/api/auth/oidc"
+	oidcFlowTTL    = 10 * time.Minute
+)
+
+// oidcFlow is what we need to remember between sending the user to the
+// provider and them coming back. It is kept in a signed cookie.
+type oidcFlow struct {
+	jwt.RegisteredClaims
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+func (cfg *apiConfig) oidcRedirectURL(tenant database.Tenant) string {
+	return cfg.baseURL(tenant) + oidcCookiePath + "/callback"
+}
+
+func (cfg *apiConfig) handleOIDCLogin(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	state, err := oidc.RandomString(16)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	nonce, err := oidc.RandomString(16)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	expires := time.Now().Add(oidcFlowTTL)
+	cookie, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcFlow{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{tenant.Host},
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}).SignedString([]byte(cfg.jwtSecret))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    cookie,
+		Path:     oidcCookiePath,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   cfg.publicScheme == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, cfg.oidcProvider.AuthCodeURL(cfg.oidcRedirectURL(tenant), state, nonce, challenge), http.StatusFound)
+}
+
+func (cfg *apiConfig) handleOIDCCallback(w http.ResponseWriter, r *http.Request, tenant database.Tenant) {
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		respondWithError(w, http.StatusUnauthorized, "Sign in was cancelled: "+errParam)
+		return
+	}
+
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Sign in session expired, please try again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcCookieName, Path: oidcCookiePath, MaxAge: -1})
+
+	flow := oidcFlow{}
+	_, err = jwt.ParseWithClaims(cookie.Value, &flow, func(token *jwt.Token) (interface{}, error) {
+		return []byte(cfg.jwtSecret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(tenant.Host))
+	if err != nil || flow.State == "" || flow.State != r.URL.Query().Get("state") {
+		respondWithError(w, http.StatusBadRequest, "Invalid sign in state")
+		return
+	}
+
+	claims, err := cfg.oidcProvider.Exchange(r.Context(), cfg.oidcRedirectURL(tenant), r.URL.Query().Get("code"), flow.Verifier, flow.Nonce)
+	if err != nil {
+		respondWithError(w, http.StatusUnauthorized, "Could not verify sign in")
+		return
+	}
+
+	user, err := cfg.userForIdentity(r, tenant, claims)
+	if err != nil {
+		if errors.Is(err, errUnverifiedEmail) {
+			respondWithError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	cfg.respondWithLogin(w, r, user)
+}
+
+var errUnverifiedEmail = errors.New("the provider has not verified your email address; verify it with them and try again")
+
+// userForIdentity finds the user linked to an OIDC identity. Unknown
+// identities are only accepted with an email the provider verified: they
+// are linked to the existing user with that email, or get a new
+// passwordless account. Otherwise anyone could claim an address at a lax
+// provider before its owner signs up.
+func (cfg *apiConfig) userForIdentity(r *http.Request, tenant database.Tenant, claims oidc.Claims) (database.User, error) {
+	ctx := r.Context()
+	issuer := cfg.oidcProvider.Issuer()
+
+	user, err := cfg.database.GetUserByIdentity(ctx, database.GetUserByIdentityParams{
+		TenantID: tenant.ID,
+		Issuer:   issuer,
+		Subject:  claims.Subject,
+	})
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+
+	if claims.Email == "" {
+		return database.User{}, errors.New("provider did not share an email address")
+	}
+	if !claims.EmailVerified {
+		return database.User{}, errUnverifiedEmail
+	}
+
+	err = cfg.withTx(ctx, func(q *database.Queries) error {
+		existing, err := q.GetUserByEmail(ctx, database.GetUserByEmailParams{
+			Email:    claims.Email,
+			TenantID: tenant.ID,
+		})
+		switch {
+		case err == nil:
+			user = existing
+		case errors.Is(err, sql.ErrNoRows):
+			// The password column keeps its "unset" default, which never
+			// matches, so this account can only sign in through the provider.
+			user, err = q.CreateUser(ctx, database.CreateUserParams{
+				Email:          claims.Email,
+				HashedPassword: "unset",
+				TenantID:       tenant.ID,
+			})
+			if err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		return q.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+			UserID:   user.ID,
+			TenantID: tenant.ID,
+			Issuer:   issuer,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		})
+	})
+
+	return user, err
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/dbrouter"
+	"github.com/ckm54/go-projects/chirpy/internal/oidc"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Test that an unverified email never gets an account of its own.
+func TestUserForIdentity_UnverifiedEmail(t *testing.T) {
+	stub := &stubDB{}
+	db := stub.open(t)
+	cfg := &apiConfig{
+		db:           db,
+		dbRouter:     dbrouter.New(db, nil, time.Second),
+		database:     database.New(db),
+		oidcProvider: testOIDCProvider(t),
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback", nil)
+	_, err := cfg.userForIdentity(req, database.Tenant{}, identityClaims("sub-1", "alice@example.com", false))
+	if !errors.Is(err, errUnverifiedEmail) {
+		t.Fatalf("expected errUnverifiedEmail, got %v", err)
+	}
+	if stub.execs.Load() != 0 || stub.commits.Load() != 0 {
+		t.Fatalf("expected nothing to be written")
+	}
+}
+
+func TestUserForIdentity(t *testing.T) {
+	db := testDB(t)
+	q := database.New(db)
+	ctx := context.Background()
+	tenant := createTestTenant(t, q)
+	t.Cleanup(func() { db.Exec("DELETE FROM tenants WHERE id = $1", tenant.ID) })
+	bob := createTestUser(t, q, tenant, "bob")
+
+	cfg := &apiConfig{
+		db:           db,
+		dbRouter:     dbrouter.New(db, nil, time.Second),
+		database:     q,
+		oidcProvider: testOIDCProvider(t),
+	}
+	login := func(claims oidc.Claims) (database.User, error) {
+		return cfg.userForIdentity(httptest.NewRequest(http.MethodGet, "/api/auth/oidc/callback", nil), tenant, claims)
+	}
+
+	if _, err := login(identityClaims("sub-1", "alice@example.com", false)); !errors.Is(err, errUnverifiedEmail) {
+		t.Fatalf("expected an unverified new email to be refused, got %v", err)
+	}
+	_, err := q.GetUserByEmail(ctx, database.GetUserByEmailParams{Email: "alice@example.com", TenantID: tenant.ID})
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected no account for the unverified email, got %v", err)
+	}
+
+	alice, err := login(identityClaims("sub-1", "alice@example.com", true))
+	if err != nil || alice.Email != "alice@example.com" {
+		t.Fatalf("expected a new account for a verified email, got %+v (%v)", alice, err)
+	}
+
+	// Once linked, the identity is found by its subject.
+	again, err := login(identityClaims("sub-1", "alice@example.com", false))
+	if err != nil || again.ID != alice.ID {
+		t.Fatalf("expected the linked account, got %+v (%v)", again, err)
+	}
+
+	if _, err := login(identityClaims("sub-2", bob.Email, false)); !errors.Is(err, errUnverifiedEmail) {
+		t.Fatalf("expected an unverified existing email to be refused, got %v", err)
+	}
+	linked, err := login(identityClaims("sub-2", bob.Email, true))
+	if err != nil || linked.ID != bob.ID {
+		t.Fatalf("expected a verified email to link to bob, got %+v (%v)", linked, err)
+	}
+}
+
+func identityClaims(subject, email string, verified bool) oidc.Claims {
+	return oidc.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+		Email:            email,
+		EmailVerified:    verified,
+	}
+}
+
+// testOIDCProvider discovers a stand-in issuer. Only its discovery
+// document is served, which is all userForIdentity needs.
+func testOIDCProvider(t *testing.T) *oidc.Provider {
+	t.Helper()
+
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/authorize",
+			"token_endpoint":         issuer + "/token",
+			"jwks_uri":               issuer + "/jwks",
+		})
+	}))
+	t.Cleanup(srv.Close)
+	issuer = srv.URL
+
+	provider, err := oidc.Discover(context.Background(), oidc.Config{Issuer: issuer, ClientID: "chirpy"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error discovering issuer: %v", err)
+	}
+	return provider
+}
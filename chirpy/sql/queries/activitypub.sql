@@ -0,0 +1,29 @@
+-- name: EnsureActorKey :one
+INSERT INTO actor_keys (user_id, public_key_pem, private_key_pem)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET user_id = actor_keys.user_id
+RETURNING *;
+
+-- name: GetActorKey :one
+SELECT * FROM actor_keys
+WHERE user_id = $1;
+
+-- name: CreateFollower :exec
+INSERT INTO followers (user_id, actor_uri, inbox_uri, follow_activity_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, actor_uri) DO UPDATE
+SET inbox_uri = EXCLUDED.inbox_uri, follow_activity_id = EXCLUDED.follow_activity_id;
+
+-- name: DeleteFollowerByActivity :execrows
+DELETE FROM followers
+WHERE user_id = $1 AND actor_uri = $2 AND follow_activity_id = $3;
+
+-- name: GetFollowers :many
+SELECT * FROM followers
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: CreateRemoteNote :exec
+INSERT INTO remote_notes (user_id, note_uri, actor_uri, content, in_reply_to)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (user_id, note_uri) DO NOTHING;
@@ -1,12 +1,22 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (body, user_id)
-VALUES ($1, $2)
+INSERT INTO chirps (body, user_id, tenant_id)
+VALUES ($1, $2, $3)
 RETURNING *;
 
 -- name: GetChirps :many
 SELECT * FROM chirps
+WHERE tenant_id = $1
 ORDER BY created_at ASC;
 
 -- name: GetChirp :one
 SELECT * FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: DeleteChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetChirpsForUser :many
+SELECT * FROM chirps
+WHERE user_id = $1
+ORDER BY created_at DESC;
@@ -0,0 +1,23 @@
+-- name: AddChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: GetLinkPreview :one
+SELECT * FROM link_previews
+WHERE url = $1;
+
+-- name: SaveLinkPreview :exec
+INSERT INTO link_previews (url, ok, title, description, image_url)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (url) DO UPDATE
+SET fetched_at = NOW(), ok = EXCLUDED.ok, title = EXCLUDED.title,
+    description = EXCLUDED.description, image_url = EXCLUDED.image_url;
+
+-- name: GetChirpPreviews :many
+SELECT chirp_links.chirp_id, link_previews.url, link_previews.title,
+       link_previews.description, link_previews.image_url
+FROM chirp_links
+JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY(@chirp_ids::uuid[]) AND link_previews.ok
+ORDER BY chirp_links.chirp_id, chirp_links.position;
@@ -0,0 +1,36 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (user_id, type, actor, chirp_id, remote_uri)
+SELECT @user_id::uuid, @type::text, @actor::text, sqlc.narg('chirp_id')::uuid, sqlc.narg('remote_uri')::text
+WHERE NOT EXISTS (
+  SELECT 1 FROM notification_preferences
+  WHERE user_id = @user_id::uuid AND type = @type::text AND enabled = FALSE
+);
+
+-- name: GetNotifications :many
+SELECT * FROM notifications
+WHERE user_id = @user_id AND (NOT @unread_only::bool OR read_at IS NULL)
+ORDER BY created_at DESC
+LIMIT @row_limit;
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = @user_id AND read_at IS NULL AND id = ANY(@ids::uuid[]);
+
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE user_id = $1;
+
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled;
@@ -0,0 +1,36 @@
+-- name: CreateTenant :one
+INSERT INTO tenants (host, name, platform)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetTenantByHost :one
+SELECT * FROM tenants
+WHERE host = $1;
+
+-- name: GetTenants :many
+SELECT * FROM tenants
+ORDER BY host ASC;
+
+-- name: SetTenantPlatform :execrows
+UPDATE tenants
+SET platform = $2, updated_at = NOW()
+WHERE host = $1;
+
+-- name: SetTenantHost :execrows
+UPDATE tenants
+SET host = @new_host, updated_at = NOW()
+WHERE host = @host;
+
+-- name: GetBannedWords :many
+SELECT word FROM banned_words
+WHERE tenant_id = $1
+ORDER BY word ASC;
+
+-- name: AddBannedWord :exec
+INSERT INTO banned_words (tenant_id, word)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveBannedWord :execrows
+DELETE FROM banned_words
+WHERE tenant_id = $1 AND word = $2;
@@ -0,0 +1,10 @@
+-- name: CreateUserIdentity :exec
+INSERT INTO user_identities (user_id, tenant_id, issuer, subject, email)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: GetUserByIdentity :one
+SELECT users.* FROM users
+JOIN user_identities ON user_identities.user_id = users.id
+WHERE user_identities.tenant_id = $1
+  AND user_identities.issuer = $2
+  AND user_identities.subject = $3;
@@ -1,7 +1,25 @@
 -- name: CreateUser :one
-INSERT INTO users (email, hashed_password)
-VALUES ($1, $2)
+INSERT INTO users (email, hashed_password, tenant_id, username)
+VALUES ($1, $2, $3, $4)
 RETURNING *;
 
 -- name: DeleteUsers :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users
+WHERE tenant_id = $1;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 AND tenant_id = $2;
+
+-- name: GetTenantUser :one
+SELECT * FROM users
+WHERE id = $1 AND tenant_id = $2;
+
+-- name: GetUserByUsername :one
+SELECT * FROM users
+WHERE username = $1 AND tenant_id = $2;
+
+-- name: SetUserAdmin :execrows
+UPDATE users
+SET is_admin = $3, updated_at = NOW()
+WHERE email = $1 AND tenant_id = $2;
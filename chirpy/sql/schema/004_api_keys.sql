@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE api_keys (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  key_hash TEXT UNIQUE NOT NULL,
+  scopes TEXT[] NOT NULL,
+  last_used_at TIMESTAMP WITHOUT TIME ZONE,
+  revoked_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+-- +goose Down
+DROP TABLE api_keys;
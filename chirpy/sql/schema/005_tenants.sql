@@ -0,0 +1,50 @@
+-- +goose Up
+CREATE TABLE tenants (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  updated_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  host TEXT UNIQUE NOT NULL,
+  name TEXT NOT NULL,
+  platform TEXT NOT NULL DEFAULT 'prod'
+);
+
+CREATE TABLE banned_words (
+  tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+  word TEXT NOT NULL,
+  PRIMARY KEY (tenant_id, word)
+);
+
+-- Existing users and chirps move into a default tenant served on the
+-- deployment's hostname, PUBLIC_HOST (without a port), or localhost when it
+-- isn't set. It keeps the platform the server ran as before, from the
+-- PLATFORM setting that tenants replace, so a dev setup keeps /admin/reset.
+-- A deployment migrated without PUBLIC_HOST answers 404 on its real
+-- hostname until it runs: chirpy tenant host localhost <hostname>
+-- +goose ENVSUB ON
+INSERT INTO tenants (id, host, name, platform)
+VALUES ('00000000-0000-0000-0000-000000000001', lower('${PUBLIC_HOST:-localhost}'), 'default', '${PLATFORM:-prod}');
+-- +goose ENVSUB OFF
+
+INSERT INTO banned_words (tenant_id, word)
+VALUES
+  ('00000000-0000-0000-0000-000000000001', 'kerfuffle'),
+  ('00000000-0000-0000-0000-000000000001', 'sharbert'),
+  ('00000000-0000-0000-0000-000000000001', 'fornax');
+
+ALTER TABLE users ADD COLUMN tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE;
+UPDATE users SET tenant_id = '00000000-0000-0000-0000-000000000001';
+ALTER TABLE users ALTER COLUMN tenant_id SET NOT NULL;
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE chirps ADD COLUMN tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE;
+UPDATE chirps SET tenant_id = users.tenant_id FROM users WHERE chirps.user_id = users.id;
+ALTER TABLE chirps ALTER COLUMN tenant_id SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN tenant_id;
+ALTER TABLE users DROP CONSTRAINT users_tenant_email_key;
+ALTER TABLE users DROP COLUMN tenant_id;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+DROP TABLE banned_words;
+DROP TABLE tenants;
@@ -0,0 +1,38 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN username TEXT;
+ALTER TABLE users ADD CONSTRAINT users_tenant_username_key UNIQUE (tenant_id, username);
+
+CREATE TABLE actor_keys (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  public_key_pem TEXT NOT NULL,
+  private_key_pem TEXT NOT NULL
+);
+
+CREATE TABLE followers (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  actor_uri TEXT NOT NULL,
+  inbox_uri TEXT NOT NULL,
+  follow_activity_id TEXT NOT NULL,
+  UNIQUE (user_id, actor_uri)
+);
+
+CREATE TABLE remote_notes (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  note_uri TEXT NOT NULL,
+  actor_uri TEXT NOT NULL,
+  content TEXT NOT NULL,
+  in_reply_to TEXT,
+  UNIQUE (user_id, note_uri)
+);
+
+-- +goose Down
+DROP TABLE remote_notes;
+DROP TABLE followers;
+DROP TABLE actor_keys;
+ALTER TABLE users DROP CONSTRAINT users_tenant_username_key;
+ALTER TABLE users DROP COLUMN username;
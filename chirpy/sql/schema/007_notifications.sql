@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE notifications (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  actor TEXT NOT NULL,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+  remote_uri TEXT,
+  read_at TIMESTAMP WITHOUT TIME ZONE
+);
+
+CREATE INDEX notifications_user_created_idx ON notifications (user_id, created_at DESC);
+
+CREATE TABLE notification_preferences (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  enabled BOOLEAN NOT NULL,
+  PRIMARY KEY (user_id, type)
+);
+
+-- +goose Down
+DROP TABLE notification_preferences;
+DROP TABLE notifications;
@@ -0,0 +1,24 @@
+-- +goose Up
+-- Cache of fetched pages, shared by every chirp that links to them.
+CREATE TABLE link_previews (
+  url TEXT PRIMARY KEY,
+  fetched_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  -- False when the fetch failed, so we don't retry on every chirp.
+  ok BOOLEAN NOT NULL,
+  title TEXT NOT NULL DEFAULT '',
+  description TEXT NOT NULL DEFAULT '',
+  image_url TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE chirp_links (
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  url TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  PRIMARY KEY (chirp_id, url)
+);
+
+CREATE INDEX chirp_links_url_idx ON chirp_links (url);
+
+-- +goose Down
+DROP TABLE chirp_links;
+DROP TABLE link_previews;
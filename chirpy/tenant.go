@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+)
+
+const tenantUsage = `usage:
+  chirpy tenant list
+  chirpy tenant add <host> <name> <platform>
+  chirpy tenant platform <host> <platform>
+  chirpy tenant host <host> <new-host>
+  chirpy tenant ban <host> <word>
+  chirpy tenant unban <host> <word>
+  chirpy tenant admin <host> <email>
+  chirpy tenant unadmin <host> <email>`
+
+type tenantHandler func(http.ResponseWriter, *http.Request, database.Tenant)
+
+// middlewareTenant resolves the tenant (Chirpy instance) the request is for
+// from its Host header.
+func (cfg *apiConfig) middlewareTenant(handler tenantHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := cfg.resolveTenant(w, r)
+		if !ok {
+			return
+		}
+
+		handler(w, r, tenant)
+	}
+}
+
+// resolveTenant looks up the tenant for r, writing an error response and
+// returning false if there isn't one.
+func (cfg *apiConfig) resolveTenant(w http.ResponseWriter, r *http.Request) (database.Tenant, bool) {
+	tenant, err := cfg.database.GetTenantByHost(r.Context(), requestHost(r))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusNotFound, "Unknown Chirpy instance")
+			return database.Tenant{}, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return database.Tenant{}, false
+	}
+
+	return tenant, true
+}
+
+// requestHost returns the lowercased Host header without its port.
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+// runTenantCommand handles the "chirpy tenant <action>" subcommand used to
+// manage the instances served by this deployment.
+func runTenantCommand(ctx context.Context, db *database.Queries, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(tenantUsage)
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		tenants, err := db.GetTenants(ctx)
+		if err != nil {
+			return err
+		}
+		for _, tenant := range tenants {
+			fmt.Fprintf(out, "%-30s %-20s %s\n", tenant.Host, tenant.Name, tenant.Platform)
+		}
+
+	case args[0] == "add" && len(args) == 4:
+		tenant, err := db.CreateTenant(ctx, database.CreateTenantParams{
+			Host:     strings.ToLower(args[1]),
+			Name:     args[2],
+			Platform: args[3],
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "created tenant %s for %s\n", tenant.Name, tenant.Host)
+
+	case args[0] == "platform" && len(args) == 3:
+		updated, err := db.SetTenantPlatform(ctx, database.SetTenantPlatformParams{
+			Host:     strings.ToLower(args[1]),
+			Platform: args[2],
+		})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return fmt.Errorf("no tenant for host %s", args[1])
+		}
+		fmt.Fprintf(out, "%s is now on platform %s\n", args[1], args[2])
+
+	case args[0] == "host" && len(args) == 3:
+		updated, err := db.SetTenantHost(ctx, database.SetTenantHostParams{
+			Host:    strings.ToLower(args[1]),
+			NewHost: strings.ToLower(args[2]),
+		})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return fmt.Errorf("no tenant for host %s", args[1])
+		}
+		fmt.Fprintf(out, "%s is now served on %s\n", args[1], strings.ToLower(args[2]))
+
+	case args[0] == "ban" && len(args) == 3:
+		tenant, err := db.GetTenantByHost(ctx, strings.ToLower(args[1]))
+		if err != nil {
+			return fmt.Errorf("no tenant for host %s: %w", args[1], err)
+		}
+		err = db.AddBannedWord(ctx, database.AddBannedWordParams{
+			TenantID: tenant.ID,
+			Word:     strings.ToLower(args[2]),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "banned %q on %s\n", args[2], tenant.Host)
+
+	case args[0] == "unban" && len(args) == 3:
+		tenant, err := db.GetTenantByHost(ctx, strings.ToLower(args[1]))
+		if err != nil {
+			return fmt.Errorf("no tenant for host %s: %w", args[1], err)
+		}
+		removed, err := db.RemoveBannedWord(ctx, database.RemoveBannedWordParams{
+			TenantID: tenant.ID,
+			Word:     strings.ToLower(args[2]),
+		})
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			return fmt.Errorf("%q is not banned on %s", args[2], tenant.Host)
+		}
+		fmt.Fprintf(out, "unbanned %q on %s\n", args[2], tenant.Host)
+
+	case (args[0] == "admin" || args[0] == "unadmin") && len(args) == 3:
+		tenant, err := db.GetTenantByHost(ctx, strings.ToLower(args[1]))
+		if err != nil {
+			return fmt.Errorf("no tenant for host %s: %w", args[1], err)
+		}
+		updated, err := db.SetUserAdmin(ctx, database.SetUserAdminParams{
+			Email:    args[2],
+			TenantID: tenant.ID,
+			IsAdmin:  args[0] == "admin",
+		})
+		if err != nil {
+			return err
+		}
+		if updated == 0 {
+			return fmt.Errorf("no user %s on %s", args[2], tenant.Host)
+		}
+		if args[0] == "admin" {
+			fmt.Fprintf(out, "%s is now an admin on %s and must use two-factor authentication\n", args[2], tenant.Host)
+		} else {
+			fmt.Fprintf(out, "%s is no longer an admin on %s\n", args[2], tenant.Host)
+		}
+
+	default:
+		return errors.New(tenantUsage)
+	}
+
+	return nil
+}
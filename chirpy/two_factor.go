@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// mfaAttemptsPerMinute limits second factor guesses per user. Six digit
+	// codes are only safe against guessing if attempts are scarce.
+	mfaAttemptsPerMinute = 5
+)
+
+type totpEnrollmentRes struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type totpConfirmedRes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+	Token         string   `json:"token"`
+}
+
+// twoFactorEnabled reports whether userID has a confirmed authenticator.
+func (cfg *apiConfig) twoFactorEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := cfg.database.GetUserTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return totp.EnabledAt.Valid, nil
+}
+
+// middlewareMFAToken only accepts the challenge token handed out by the
+// first login step.
+func (cfg *apiConfig) middlewareMFAToken(handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := cfg.resolveTenant(w, r)
+		if !ok {
+			return
+		}
+
+		caller, err := cfg.authenticateMFAToken(r, tenant)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		handler(w, r, caller)
+	}
+}
+
+// middlewareEnrollment accepts an access token, or an MFA token so admins
+// who are made to enroll at login can do so before getting an access token.
+// Enrolling is refused once 2FA is on, so an MFA token can't be used to
+// swap in a new authenticator.
+func (cfg *apiConfig) middlewareEnrollment(handler authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenant, ok := cfg.resolveTenant(w, r)
+		if !ok {
+			return
+		}
+
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		caller, err := cfg.authenticateJWT(r, tenant, token)
+		if err != nil && !errors.Is(err, errAuthUnavailable) {
+			caller, err = cfg.authenticateMFAToken(r, tenant)
+		}
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		handler(w, r, caller)
+	}
+}
+
+func (cfg *apiConfig) authenticateMFAToken(r *http.Request, tenant database.Tenant) (principal, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return principal{}, err
+	}
+
+	userID, err := auth.ValidateMFAToken(token, cfg.jwtSecret)
+	if err != nil {
+		return principal{}, err
+	}
+
+	if _, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       userID,
+		TenantID: tenant.ID,
+	}); err != nil {
+		return principal{}, lookupError(err)
+	}
+
+	return principal{UserID: userID, Tenant: tenant}, nil
+}
+
+// allowMFAAttempt applies the per-user limit on second factor guesses,
+// writing a 429 if it is exceeded.
+func (cfg *apiConfig) allowMFAAttempt(w http.ResponseWriter, userID uuid.UUID) bool {
+	ok, wait := cfg.mfaLimiter.Allow(userID.String())
+	if !ok {
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		respondWithError(w, http.StatusTooManyRequests, "Too many attempts, try again later")
+	}
+
+	return ok
+}
+
+func (cfg *apiConfig) handleEnrollTOTP(w http.ResponseWriter, r *http.Request, caller principal) {
+	user, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       caller.UserID,
+		TenantID: caller.Tenant.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	enabled, err := cfg.twoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if enabled {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if err := cfg.database.SetPendingTOTP(r.Context(), database.SetPendingTOTPParams{
+		UserID: user.ID,
+		Secret: secret,
+	}); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, totpEnrollmentRes{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(caller.Tenant.Name, user.Email, secret),
+	})
+}
+
+// handleConfirmTOTP turns 2FA on once the user shows their authenticator
+// produces the right codes, and hands out their recovery codes.
+func (cfg *apiConfig) handleConfirmTOTP(w http.ResponseWriter, r *http.Request, caller principal) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	type parameters struct {
+		Code string `json:"code"`
+	}
+
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	totp, err := cfg.database.GetUserTOTP(r.Context(), caller.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusBadRequest, "Start enrollment first")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if totp.EnabledAt.Valid {
+		respondWithError(w, http.StatusConflict, "Two-factor authentication is already enabled")
+		return
+	}
+
+	if !cfg.allowMFAAttempt(w, caller.UserID) {
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, params.Code, time.Now())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	codes, err := auth.MakeRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := q.EnableUserTOTP(r.Context(), database.EnableUserTOTPParams{
+			UserID:       caller.UserID,
+			LastUsedStep: step,
+		}); err != nil {
+			return err
+		}
+
+		if err := q.DeleteRecoveryCodes(r.Context(), caller.UserID); err != nil {
+			return err
+		}
+
+		for _, code := range codes {
+			if err := q.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+				UserID:   caller.UserID,
+				CodeHash: auth.HashRecoveryCode(code),
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	// The user has just shown both factors, so they can have a full access
+	// token; admins enrolling at login need one to continue.
+	token, err := auth.MakeJWT(caller.UserID, cfg.jwtSecret, accessTokenTTL)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not create access token")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, totpConfirmedRes{
+		RecoveryCodes: codes,
+		Token:         token,
+	})
+}
+
+// handleLoginSecondFactor completes a login with either a TOTP code or one
+// of the user's recovery codes.
+func (cfg *apiConfig) handleLoginSecondFactor(w http.ResponseWriter, r *http.Request, caller principal) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	type parameters struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if (params.Code == "") == (params.RecoveryCode == "") {
+		respondWithError(w, http.StatusBadRequest, "Provide either code or recovery_code")
+		return
+	}
+
+	user, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       caller.UserID,
+		TenantID: caller.Tenant.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	totp, err := cfg.database.GetUserTOTP(r.Context(), user.ID)
+	if err != nil || !totp.EnabledAt.Valid {
+		respondWithError(w, http.StatusForbidden, "Two-factor authentication is not set up, enroll first")
+		return
+	}
+
+	if !cfg.allowMFAAttempt(w, user.ID) {
+		return
+	}
+
+	ok, err := cfg.useSecondFactor(r.Context(), totp, params.Code, params.RecoveryCode)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid code")
+		return
+	}
+
+	cfg.respondWithAccessToken(w, user)
+}
+
+// useSecondFactor spends a TOTP code, or a recovery code if code is empty,
+// reporting whether it was valid.
+func (cfg *apiConfig) useSecondFactor(ctx context.Context, totp database.UserTotp, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// Only succeeds for a step newer than the last one used, so a code
+		// seen over someone's shoulder can't be replayed.
+		used, err := cfg.database.UseTOTPStep(ctx, database.UseTOTPStepParams{
+			UserID:       totp.UserID,
+			LastUsedStep: step,
+		})
+		return used > 0, err
+	}
+
+	used, err := cfg.database.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   totp.UserID,
+		CodeHash: auth.HashRecoveryCode(recoveryCode),
+	})
+	return used > 0, err
+}
+
+// handleDisableTwoFactor turns 2FA off after the user confirms their
+// password, or a current TOTP or recovery code for accounts that sign in
+// through OIDC and have no password. Admins have to keep it.
+func (cfg *apiConfig) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request, caller principal) {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	type parameters struct {
+		Password     string `json:"password"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}
+
+	params := parameters{}
+	if err := decoder.Decode(&params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+	if params.Password == "" && params.Code == "" && params.RecoveryCode == "" {
+		respondWithError(w, http.StatusBadRequest, "Provide password, code or recovery_code")
+		return
+	}
+
+	user, err := cfg.database.GetTenantUser(r.Context(), database.GetTenantUserParams{
+		ID:       caller.UserID,
+		TenantID: caller.Tenant.ID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	if user.IsAdmin {
+		respondWithError(w, http.StatusForbidden, "Admins must keep two-factor authentication enabled")
+		return
+	}
+
+	if params.Password != "" {
+		match, err := auth.CheckPasswordHash(params.Password, user.HashedPassword)
+		if err != nil || !match {
+			respondWithError(w, http.StatusUnauthorized, "Incorrect password")
+			return
+		}
+	} else {
+		totp, err := cfg.database.GetUserTOTP(r.Context(), user.ID)
+		if err != nil || !totp.EnabledAt.Valid {
+			respondWithError(w, http.StatusForbidden, "Two-factor authentication is not enabled")
+			return
+		}
+		if !cfg.allowMFAAttempt(w, user.ID) {
+			return
+		}
+		ok, err := cfg.useSecondFactor(r.Context(), totp, params.Code, params.RecoveryCode)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+			return
+		}
+		if !ok {
+			respondWithError(w, http.StatusUnauthorized, "Invalid code")
+			return
+		}
+	}
+
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		if err := q.DeleteUserTOTP(r.Context(), user.ID); err != nil {
+			return err
+		}
+		return q.DeleteRecoveryCodes(r.Context(), user.ID)
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
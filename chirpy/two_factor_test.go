@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/chirpy/internal/auth"
+	"github.com/ckm54/go-projects/chirpy/internal/database"
+	"github.com/ckm54/go-projects/chirpy/internal/dbrouter"
+	"github.com/ckm54/go-projects/chirpy/internal/ratelimit"
+)
+
+// Test that a user without a usable password, as OIDC sign-ins create, can
+// turn 2FA off with a current code.
+func TestHandleDisableTwoFactor_Code(t *testing.T) {
+	db := testDB(t)
+	q := database.New(db)
+	ctx := context.Background()
+	tenant := createTestTenant(t, q)
+	t.Cleanup(func() { db.Exec("DELETE FROM tenants WHERE id = $1", tenant.ID) })
+	user := createTestUser(t, q, tenant, "alice")
+
+	secret, err := auth.MakeTOTPSecret()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.SetPendingTOTP(ctx, database.SetPendingTOTPParams{UserID: user.ID, Secret: secret}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.EnableUserTOTP(ctx, database.EnableUserTOTPParams{UserID: user.ID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &apiConfig{
+		db:         db,
+		dbRouter:   dbrouter.New(db, nil, time.Second),
+		database:   q,
+		mfaLimiter: ratelimit.New(mfaAttemptsPerMinute, mfaAttemptsPerMinute),
+	}
+	disable := func(body string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/2fa/disable", strings.NewReader(body))
+		cfg.handleDisableTwoFactor(rec, req, principal{UserID: user.ID, Tenant: tenant})
+		return rec.Code
+	}
+
+	if got := disable(`{"password":"guess"}`); got != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong password to be refused, got %d", got)
+	}
+	if got := disable(`{"code":"000000x"}`); got != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong code to be refused, got %d", got)
+	}
+
+	code, err := auth.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := disable(`{"code":"` + code + `"}`); got != http.StatusNoContent {
+		t.Fatalf("expected a current code to disable 2FA, got %d", got)
+	}
+	if _, err := q.GetUserTOTP(ctx, user.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected the authenticator to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package article
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/netguard"
+)
+
+func TestExtract(t *testing.T) {
+	file, err := os.Open("testdata/article.html")
+	if err != nil {
+		t.Fatalf("could not open fixture: %v", err)
+	}
+	defer file.Close()
+
+	pageURL, _ := url.Parse("https://blog.example.com/posts/channels")
+	article, err := Extract(file, pageURL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if article.Title != "Understanding Go channels" {
+		t.Fatalf("expected the og:title, got %q", article.Title)
+	}
+
+	for _, want := range []string{"Channels are the pipes", "Buffered channels", "ch := make(chan int, 3)"} {
+		if !strings.Contains(article.Text, want) {
+			t.Fatalf("expected text to contain %q, got:\n%s", want, article.Text)
+		}
+	}
+	for _, unwanted := range []string{"Archive", "cookies", "Popular posts", "Great article", "Copyright", "analytics"} {
+		if strings.Contains(article.Text, unwanted) || strings.Contains(article.HTML, unwanted) {
+			t.Fatalf("expected %q to be left out, got:\n%s", unwanted, article.HTML)
+		}
+	}
+
+	for _, want := range []string{
+		`<img src="https://blog.example.com/img/pipes.png" alt="Two goroutines joined by a channel">`,
+		`<a href="https://blog.example.com/posts/select">`,
+		`<h2>Understanding Go channels</h2>`,
+	} {
+		if !strings.Contains(article.HTML, want) {
+			t.Fatalf("expected html to contain %s, got:\n%s", want, article.HTML)
+		}
+	}
+	if strings.Contains(article.HTML, "class=") {
+		t.Fatalf("expected attributes to be stripped, got:\n%s", article.HTML)
+	}
+}
+
+func TestExtractWithoutContent(t *testing.T) {
+	page := `<html><body><nav><a href="/">Home</a></nav><p>Short.</p></body></html>`
+	if _, err := Extract(strings.NewReader(page), nil); !errors.Is(err, ErrNoContent) {
+		t.Fatalf("expected ErrNoContent, got %v", err)
+	}
+}
+
+func TestRobotsRules(t *testing.T) {
+	robots := `
+# comments are ignored
+User-agent: *
+Disallow: /private/
+Allow: /private/open
+Disallow: /*.pdf$
+
+User-agent: otherbot
+Disallow: /
+`
+	tests := map[string]bool{
+		"/":                   true,
+		"/posts/channels":     true,
+		"/private/notes":      false,
+		"/private/open/page":  true,
+		"/files/report.pdf":   false,
+		"/files/report.pdf?x": true,
+	}
+
+	rules := parseRobots(strings.NewReader(robots), "gator")
+	for path, want := range tests {
+		if got := rules.allowed(path); got != want {
+			t.Fatalf("%s: expected allowed=%v, got %v", path, want, got)
+		}
+	}
+
+	named := parseRobots(strings.NewReader("User-agent: *\nDisallow:\n\nUser-agent: Gator\nDisallow: /\n"), "gator")
+	if named.allowed("/anything") {
+		t.Fatalf("expected the group naming gator to win over *")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/article.html")
+	if err != nil {
+		t.Fatalf("could not read fixture: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	})
+	mux.HandleFunc("/posts/channels", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(fixture)
+	})
+	mux.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/channels", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/private/draft", func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("fetched a page disallowed by robots.txt")
+	})
+	mux.HandleFunc("/report.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte("%PDF-1.7"))
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Repeat("<p>padding</p>", maxArticleBytes/14+1)))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newFetcher(200*time.Millisecond, func(netip.Addr) bool { return true })
+	ctx := context.Background()
+
+	for _, path := range []string{"/posts/channels", "/moved"} {
+		article, err := f.Fetch(ctx, srv.URL+path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", path, err)
+		}
+		if !strings.Contains(article.Text, "Channels are the pipes") {
+			t.Fatalf("%s: expected the article text, got:\n%s", path, article.Text)
+		}
+		if !strings.Contains(article.HTML, srv.URL+"/img/pipes.png") {
+			t.Fatalf("%s: expected image urls resolved against the page, got:\n%s", path, article.HTML)
+		}
+	}
+
+	if _, err := f.Fetch(ctx, srv.URL+"/private/draft"); !errors.Is(err, ErrDisallowed) {
+		t.Fatalf("expected ErrDisallowed, got %v", err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/report.pdf"); !errors.Is(err, ErrNotHTML) {
+		t.Fatalf("expected ErrNotHTML, got %v", err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/huge"); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/slow"); err == nil {
+		t.Fatalf("expected a timeout error")
+	}
+}
+
+// Test that the robots.txt cache drops expired entries, then the oldest,
+// rather than growing without bound.
+func TestStoreRobots(t *testing.T) {
+	f := newFetcher(time.Second, netguard.IsPublic)
+	now := time.Now()
+	for i := range maxRobotsSites {
+		f.storeRobots(fmt.Sprintf("https://%d.example", i), robotsEntry{expires: now.Add(time.Duration(i+1) * time.Minute)})
+	}
+	f.robots["https://0.example"] = robotsEntry{expires: now.Add(-time.Minute)}
+
+	f.storeRobots("https://new.example", robotsEntry{expires: now.Add(robotsTTL)})
+	if _, ok := f.robots["https://0.example"]; ok || len(f.robots) != maxRobotsSites {
+		t.Fatalf("expected the expired entry to make room, got %d entries", len(f.robots))
+	}
+
+	f.storeRobots("https://newer.example", robotsEntry{expires: now.Add(robotsTTL)})
+	if _, ok := f.robots["https://1.example"]; ok || len(f.robots) != maxRobotsSites {
+		t.Fatalf("expected the entry expiring soonest to make room, got %d entries", len(f.robots))
+	}
+}
+
+// Test that links to loopback or private addresses are never fetched.
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>internal</p>"))
+	}))
+	defer srv.Close()
+
+	_, err := NewFetcher(time.Second).Fetch(context.Background(), srv.URL+"/admin")
+	if !errors.Is(err, netguard.ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
@@ -0,0 +1,389 @@
+// Package article pulls the main content out of a web page, so posts whose
+// feed only carries a teaser can be read in full offline.
+package article
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/ckm54/go-projects/gator/internal/plaintext"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNoContent is returned for pages without anything that looks like an
+// article, such as index pages or pages rendered by JavaScript.
+var ErrNoContent = errors.New("no article content found")
+
+// Article is the readable part of a page. HTML is cleaned: only simple
+// formatting tags are kept, without attributes other than link and image
+// targets, which are made absolute.
+type Article struct {
+	Title string
+	HTML  string
+	Text  string
+}
+
+// minArticleText is how much text the chosen content must have. Anything
+// shorter is more likely a cookie banner than an article.
+const minArticleText = 200
+
+var (
+	// unlikelyCandidates and likelyCandidates are matched against an
+	// element's class and id.
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|comment|community|cookie|disqus|footer|header|menu|modal|nav|newsletter|pagination|popup|promo|related|share|shoutbox|sidebar|social|sponsor|subscribe|tags|widget`)
+	likelyCandidates   = regexp.MustCompile(`(?i)article|body|column|content|entry|main|post|story|text`)
+
+	positiveWeight = regexp.MustCompile(`(?i)article|body|content|entry|h-entry|main|page|post|story|text`)
+	negativeWeight = regexp.MustCompile(`(?i)comment|footer|footnote|masthead|meta|outbrain|promo|related|share|shoutbox|sidebar|skyscraper|sponsor|widget`)
+)
+
+// removedElements never hold article text.
+var removedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Svg: true, atom.Nav: true, atom.Aside: true,
+	atom.Footer: true, atom.Header: true, atom.Object: true, atom.Embed: true,
+}
+
+// keptElements are written to the cleaned HTML; other elements are
+// replaced by their children.
+var keptElements = map[atom.Atom]bool{
+	atom.P: true, atom.Br: true, atom.Hr: true,
+	atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Blockquote: true, atom.Pre: true, atom.Code: true,
+	atom.Em: true, atom.Strong: true, atom.B: true, atom.I: true, atom.Sup: true, atom.Sub: true,
+	atom.A: true, atom.Img: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true, atom.Th: true, atom.Td: true,
+}
+
+// Extract finds the main content of the HTML page read from r. pageURL is
+// used to resolve relative links and images.
+func Extract(r io.Reader, pageURL *url.URL) (Article, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Article{}, err
+	}
+
+	title := pageTitle(doc)
+	removeClutter(doc)
+
+	content := bestCandidate(doc)
+	if content == nil {
+		return Article{}, ErrNoContent
+	}
+
+	var b strings.Builder
+	for _, n := range content {
+		writeClean(&b, n, pageURL)
+	}
+
+	article := Article{Title: title, HTML: strings.TrimSpace(b.String())}
+	article.Text = plaintext.FromHTML(article.HTML)
+	if len(article.Text) < minArticleText {
+		return Article{}, ErrNoContent
+	}
+	return article, nil
+}
+
+// pageTitle prefers og:title, which usually leaves out the site name.
+func pageTitle(doc *html.Node) string {
+	var title, ogTitle string
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = strings.TrimSpace(textContent(n))
+			}
+		case atom.Meta:
+			if attr(n, "property") == "og:title" && ogTitle == "" {
+				ogTitle = strings.TrimSpace(attr(n, "content"))
+			}
+		}
+		return true
+	})
+
+	if ogTitle != "" {
+		return ogTitle
+	}
+	return title
+}
+
+// removeClutter drops elements that never hold the article, and elements
+// whose class or id marks them as page furniture.
+func removeClutter(doc *html.Node) {
+	var doomed []*html.Node
+	walk(doc, func(n *html.Node) bool {
+		if n.Type == html.CommentNode {
+			doomed = append(doomed, n)
+			return false
+		}
+		if n.Type != html.ElementNode {
+			return true
+		}
+		if removedElements[n.DataAtom] || isUnlikely(n) {
+			doomed = append(doomed, n)
+			return false
+		}
+		return true
+	})
+
+	for _, n := range doomed {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func isUnlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Html, atom.Body, atom.Article, atom.Main, atom.A:
+		return false
+	}
+	if attr(n, "role") == "complementary" || attr(n, "role") == "navigation" {
+		return true
+	}
+
+	match := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(match) && !likelyCandidates.MatchString(match)
+}
+
+// bestCandidate scores each paragraph's ancestors by the text below them,
+// as readability does, and returns the best scoring element along with any
+// siblings that look like part of the same article.
+func bestCandidate(doc *html.Node) []*html.Node {
+	scores := make(map[*html.Node]float64)
+	var order []*html.Node
+
+	initialize := func(n *html.Node) {
+		if _, ok := scores[n]; ok {
+			return
+		}
+		scores[n] = classWeight(n) + tagWeight(n)
+		order = append(order, n)
+	}
+
+	walk(doc, func(n *html.Node) bool {
+		switch n.DataAtom {
+		case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		default:
+			return true
+		}
+
+		text := strings.TrimSpace(textContent(n))
+		if len(text) < 25 || n.Parent == nil {
+			return false
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+
+		parent := n.Parent
+		initialize(parent)
+		scores[parent] += score
+		if grandparent := parent.Parent; grandparent != nil && grandparent.Type == html.ElementNode {
+			initialize(grandparent)
+			scores[grandparent] += score / 2
+		}
+		return false
+	})
+
+	var best *html.Node
+	for _, n := range order {
+		scores[n] *= 1 - linkDensity(n)
+		if best == nil || scores[n] > scores[best] {
+			best = n
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	if best.Parent == nil {
+		return []*html.Node{best}
+	}
+
+	threshold := max(10, scores[best]*0.2)
+	var content []*html.Node
+	for sibling := best.Parent.FirstChild; sibling != nil; sibling = sibling.NextSibling {
+		if sibling == best {
+			content = append(content, sibling)
+			continue
+		}
+		if sibling.Type != html.ElementNode {
+			continue
+		}
+		if score, ok := scores[sibling]; ok && score >= threshold {
+			content = append(content, sibling)
+			continue
+		}
+		if sibling.DataAtom == atom.P {
+			text := strings.TrimSpace(textContent(sibling))
+			if len(text) > 80 && linkDensity(sibling) < 0.25 {
+				content = append(content, sibling)
+			}
+		}
+	}
+	return content
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, v := range []string{attr(n, "class"), attr(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negativeWeight.MatchString(v) {
+			weight -= 25
+		}
+		if positiveWeight.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func tagWeight(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article:
+		return 10
+	case atom.Div, atom.Main, atom.Section:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+// linkDensity is the share of n's text that sits inside links.
+func linkDensity(n *html.Node) float64 {
+	total := len(textContent(n))
+	if total == 0 {
+		return 0
+	}
+
+	linked := 0
+	walk(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			linked += len(textContent(c))
+			return false
+		}
+		return true
+	})
+	return float64(linked) / float64(total)
+}
+
+// writeClean writes n as HTML made only of keptElements.
+func writeClean(b *strings.Builder, n *html.Node, pageURL *url.URL) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(html.EscapeString(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	tag := n.DataAtom
+	if tag == atom.H1 {
+		// The post title is shown separately; keep the heading level below it.
+		tag = atom.H2
+	}
+	if !keptElements[tag] {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			writeClean(b, c, pageURL)
+		}
+		return
+	}
+
+	if tag == atom.Img {
+		// Lazy loaded images often keep the real source in data-src.
+		src := resolve(pageURL, attr(n, "src"))
+		if src == "" {
+			src = resolve(pageURL, attr(n, "data-src"))
+		}
+		if src != "" {
+			b.WriteString(`<img src="` + html.EscapeString(src) + `"`)
+			if alt := attr(n, "alt"); alt != "" {
+				b.WriteString(` alt="` + html.EscapeString(alt) + `"`)
+			}
+			b.WriteString(">")
+		}
+		return
+	}
+
+	b.WriteString("<" + tag.String())
+	if tag == atom.A {
+		if href := resolve(pageURL, attr(n, "href")); href != "" {
+			b.WriteString(` href="` + html.EscapeString(href) + `"`)
+		}
+	}
+	b.WriteString(">")
+
+	if tag == atom.Br || tag == atom.Hr {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeClean(b, c, pageURL)
+	}
+	b.WriteString("</" + tag.String() + ">")
+}
+
+// resolve makes ref absolute, dropping anything that isn't http or https
+// (javascript: links in particular).
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// walk calls visit for n and its descendants, skipping the children of
+// nodes for which visit returns false.
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		// visit may remove c, so find the next sibling first.
+		next := c.NextSibling
+		walk(c, visit)
+		c = next
+	}
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
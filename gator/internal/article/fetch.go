@@ -0,0 +1,230 @@
+package article
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/netguard"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	userAgent = "gator"
+
+	// maxArticleBytes caps how much of a page we download. Articles are
+	// far smaller; the cap keeps a bad link from filling memory.
+	maxArticleBytes = 5 << 20
+	maxRobotsBytes  = 500 << 10
+	maxRedirects    = 5
+
+	// robotsTTL is how long a site's robots.txt is trusted before it is
+	// fetched again.
+	robotsTTL = 24 * time.Hour
+	// maxRobotsSites is how many sites' robots.txt are cached at once.
+	maxRobotsSites = 1000
+)
+
+var (
+	ErrDisallowed = errors.New("robots.txt does not allow fetching this page")
+	ErrNotHTML    = errors.New("page is not HTML")
+	ErrTooLarge   = fmt.Errorf("page is larger than %d MiB", maxArticleBytes>>20)
+)
+
+// Fetcher downloads pages and extracts their article. It honours each
+// site's robots.txt, which it caches, and is safe for concurrent use.
+type Fetcher struct {
+	client       *http.Client
+	robotsClient *http.Client
+
+	mu     sync.Mutex
+	robots map[string]robotsEntry
+}
+
+type robotsEntry struct {
+	rules   robotsRules
+	expires time.Time
+}
+
+// NewFetcher returns a Fetcher whose requests, robots.txt included, each
+// take at most timeout. Links come from feeds, so it never connects to
+// loopback or private addresses.
+func NewFetcher(timeout time.Duration) *Fetcher {
+	return newFetcher(timeout, netguard.IsPublic)
+}
+
+func newFetcher(timeout time.Duration, allow func(netip.Addr) bool) *Fetcher {
+	transport := &http.Transport{
+		DialContext:           netguard.Dialer(timeout, allow).DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	f := &Fetcher{
+		robotsClient: &http.Client{Transport: transport, Timeout: timeout},
+		robots:       make(map[string]robotsEntry),
+	}
+	f.client = &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		// A redirect may lead to another site, with its own robots.txt.
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("more than %d redirects", maxRedirects)
+			}
+			allowed, err := f.allowed(req.Context(), req.URL)
+			if err != nil {
+				return err
+			}
+			if !allowed {
+				return ErrDisallowed
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// Fetch downloads pageURL and extracts its article.
+func (f *Fetcher) Fetch(ctx context.Context, pageURL string) (Article, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return Article{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Article{}, fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+
+	allowed, err := f.allowed(ctx, u)
+	if err != nil {
+		return Article{}, err
+	}
+	if !allowed {
+		return Article{}, ErrDisallowed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Article{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Article{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Article{}, fmt.Errorf("unexpected status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+			return Article{}, ErrNotHTML
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArticleBytes+1))
+	if err != nil {
+		return Article{}, err
+	}
+	if len(data) > maxArticleBytes {
+		return Article{}, ErrTooLarge
+	}
+
+	body, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return Article{}, err
+	}
+
+	// Links in the page are relative to where we ended up, not where we
+	// started.
+	return Extract(body, resp.Request.URL)
+}
+
+// allowed checks u against its site's robots.txt.
+func (f *Fetcher) allowed(ctx context.Context, u *url.URL) (bool, error) {
+	site := u.Scheme + "://" + u.Host
+
+	f.mu.Lock()
+	entry, ok := f.robots[site]
+	f.mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		rules, err := f.fetchRobots(ctx, site)
+		if err != nil {
+			return false, err
+		}
+		entry = robotsEntry{rules: rules, expires: time.Now().Add(robotsTTL)}
+		f.storeRobots(site, entry)
+	}
+
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.rules.allowed(path), nil
+}
+
+// storeRobots caches a site's robots.txt. Once the cache is full, expired
+// entries are dropped, then the one expiring soonest.
+func (f *Fetcher) storeRobots(site string, entry robotsEntry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.robots[site]; !ok && len(f.robots) >= maxRobotsSites {
+		now := time.Now()
+		soonest := ""
+		for cached, e := range f.robots {
+			if now.After(e.expires) {
+				delete(f.robots, cached)
+			} else if soonest == "" || e.expires.Before(f.robots[soonest].expires) {
+				soonest = cached
+			}
+		}
+		if len(f.robots) >= maxRobotsSites {
+			delete(f.robots, soonest)
+		}
+	}
+	f.robots[site] = entry
+}
+
+// fetchRobots downloads a site's robots.txt. A missing file allows
+// everything; a server error is treated as disallowing everything, as
+// RFC 9309 asks.
+func (f *Fetcher) fetchRobots(ctx context.Context, site string) (robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, site+"/robots.txt", nil)
+	if err != nil {
+		return robotsRules{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := f.robotsClient.Do(req)
+	if err != nil {
+		return robotsRules{}, fmt.Errorf("could not fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return parseRobots(io.LimitReader(resp.Body, maxRobotsBytes), userAgent), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		return robotsRules{}, nil
+	default:
+		return robotsRules{}, fmt.Errorf("could not fetch robots.txt: status %d", resp.StatusCode)
+	}
+}
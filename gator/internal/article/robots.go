@@ -0,0 +1,121 @@
+package article
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// robotsRules are the Allow and Disallow lines of the robots.txt group that
+// applies to us.
+type robotsRules struct {
+	allow    []string
+	disallow []string
+}
+
+// parseRobots reads a robots.txt file and keeps the rules for agent, or
+// for "*" if no group names agent.
+func parseRobots(r io.Reader, agent string) robotsRules {
+	agent = strings.ToLower(agent)
+
+	var named, wildcard robotsRules
+	var hasNamed bool
+
+	// A group is one or more User-agent lines followed by rules.
+	var current []*robotsRules
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				current = nil
+				inRules = false
+			}
+			switch name := strings.ToLower(value); {
+			case name == "*":
+				current = append(current, &wildcard)
+			case name == agent:
+				hasNamed = true
+				current = append(current, &named)
+			}
+
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// "Disallow:" with no path allows everything.
+				continue
+			}
+			for _, rules := range current {
+				if key == "allow" {
+					rules.allow = append(rules.allow, value)
+				} else {
+					rules.disallow = append(rules.disallow, value)
+				}
+			}
+		}
+	}
+
+	if hasNamed {
+		return named
+	}
+	return wildcard
+}
+
+// allowed reports whether path may be fetched. The longest matching rule
+// wins, and Allow wins a tie.
+func (r robotsRules) allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	allowLen := longestMatch(r.allow, path)
+	disallowLen := longestMatch(r.disallow, path)
+	return disallowLen < 0 || allowLen >= disallowLen
+}
+
+func longestMatch(patterns []string, path string) int {
+	longest := -1
+	for _, pattern := range patterns {
+		if len(pattern) > longest && matchRobotsPattern(pattern, path) {
+			longest = len(pattern)
+		}
+	}
+	return longest
+}
+
+// matchRobotsPattern matches a robots.txt path pattern, where * matches any
+// run of characters and a trailing $ anchors the end of the path.
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
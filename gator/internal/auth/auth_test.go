@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMakeToken(t *testing.T) {
+	token, prefix, hash, err := MakeToken()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(token, prefix) || !strings.HasPrefix(prefix, tokenPrefix) {
+		t.Fatalf("expected %q to start with %q", token, prefix)
+	}
+	if hash != HashToken(token) || hash == token {
+		t.Fatalf("expected the stored hash to be derived from the token")
+	}
+
+	other, _, _, _ := MakeToken()
+	if other == token {
+		t.Fatalf("expected two tokens to differ")
+	}
+}
+
+func TestGetBearerToken(t *testing.T) {
+	tests := map[string]string{
+		"Bearer gator_abc":   "gator_abc",
+		"bearer  gator_abc ": "gator_abc",
+	}
+	for header, want := range tests {
+		got, err := GetBearerToken(http.Header{"Authorization": {header}})
+		if err != nil || got != want {
+			t.Fatalf("GetBearerToken(%q) = %q, %v; want %q", header, got, err, want)
+		}
+	}
+
+	for _, header := range []string{"", "Bearer", "Bearer ", "Basic abc"} {
+		_, err := GetBearerToken(http.Header{"Authorization": {header}})
+		if !errors.Is(err, ErrNoToken) {
+			t.Fatalf("GetBearerToken(%q): expected ErrNoToken, got %v", header, err)
+		}
+	}
+}
+
+func TestPasswordHash(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hash == "correct horse" {
+		t.Fatalf("expected the password to be hashed")
+	}
+
+	tests := map[string]bool{
+		"correct horse":  true,
+		"correct horse ": false,
+		"":               false,
+	}
+	for password, want := range tests {
+		ok, err := CheckPasswordHash(password, hash)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ok != want {
+			t.Fatalf("%q: expected %v, got %v", password, want, ok)
+		}
+	}
+}
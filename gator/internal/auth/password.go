@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"github.com/alexedwards/argon2id"
+)
+
+const MinPasswordLength = 8
+
+func HashPassword(password string) (string, error) {
+	hash, err := argon2id.CreateHash(password, argon2id.DefaultParams)
+	if err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+func CheckPasswordHash(password, hash string) (bool, error) {
+	isValid, err := argon2id.ComparePasswordAndHash(password, hash)
+	if err != nil {
+		return false, err
+	}
+
+	return isValid, nil
+}
@@ -0,0 +1,66 @@
+// Package auth holds gator's credentials: passwords, login sessions and API
+// tokens for the HTTP server.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const tokenPrefix = "gator_"
+
+var ErrNoToken = errors.New("no bearer token in authorization header")
+
+// MakeToken generates a new random API token. It returns the token to show
+// the user once, a short prefix that identifies it in listings, and the
+// hash that gets stored.
+func MakeToken() (token, prefix, hash string, err error) {
+	raw, err := randomHex()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	token = tokenPrefix + raw
+	prefix = token[:len(tokenPrefix)+8]
+
+	return token, prefix, HashToken(token), nil
+}
+
+// MakeSessionToken generates the token a login stores in the config file,
+// and the hash that gets stored in the database.
+func MakeSessionToken() (token, hash string, err error) {
+	token, err = randomHex()
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+func randomHex() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// HashToken returns the stored form of a token. Tokens are long and random,
+// so a fast hash is enough and lets us look them up directly.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetBearerToken extracts the token from an "Authorization: Bearer <token>"
+// header.
+func GetBearerToken(headers http.Header) (string, error) {
+	scheme, token, ok := strings.Cut(headers.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrNoToken
+	}
+	return strings.TrimSpace(token), nil
+}
@@ -0,0 +1,297 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/article"
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/netguard"
+	"github.com/ckm54/go-projects/gator/internal/notify"
+)
+
+const aggUsage = "usage: gator agg <duration> [--workers N] [--per-host N] [--timeout D] [--extract N] [--notify-commands] (e.g. 30s, 5m, 1h)"
+
+func HandlerAggregate(s *State, cmd Command) error {
+	flags := flag.NewFlagSet("agg", flag.ContinueOnError)
+	opts := addAggregatorFlags(flags)
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) != 1 {
+		return errors.New(aggUsage)
+	}
+
+	interval, err := time.ParseDuration(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid time duration: %w", err)
+	}
+
+	agg, err := opts.newAggregator(s)
+	if err != nil {
+		return fmt.Errorf("%w\n%s", err, aggUsage)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	agg.run(ctx, interval)
+	return nil
+}
+
+// aggregatorOptions are the flags shared by the commands that run the
+// aggregator.
+type aggregatorOptions struct {
+	workers *int
+	perHost *int
+	timeout *time.Duration
+	extract *int
+	// notifyCommands allows command notification sinks, which run programs
+	// chosen by any user.
+	notifyCommands *bool
+}
+
+func addAggregatorFlags(flags *flag.FlagSet) aggregatorOptions {
+	return aggregatorOptions{
+		workers:        flags.Int("workers", 4, "number of feeds fetched at once"),
+		perHost:        flags.Int("per-host", 2, "number of feeds fetched at once from the same host"),
+		timeout:        flags.Duration("timeout", 30*time.Second, "how long a single feed fetch may take"),
+		extract:        flags.Int("extract", 0, "save the full article of up to N new posts after each cycle"),
+		notifyCommands: flags.Bool("notify-commands", false, "run the programs of command notification sinks"),
+	}
+}
+
+func (o aggregatorOptions) newAggregator(s *State) (*aggregator, error) {
+	if *o.workers < 1 || *o.perHost < 1 {
+		return nil, errors.New("--workers and --per-host must be at least 1")
+	}
+	if *o.timeout <= 0 {
+		return nil, errors.New("--timeout must be positive")
+	}
+	if *o.extract < 0 {
+		return nil, errors.New("--extract must not be negative")
+	}
+
+	agg := &aggregator{
+		state:        s,
+		workers:      *o.workers,
+		hosts:        newHostLimiter(*o.perHost),
+		fetcher:      newFetcher(*o.timeout, netguard.IsPublic),
+		extractLimit: *o.extract,
+		digests: &digestSender{
+			db:       s.DB,
+			smtp:     s.Config.SMTP,
+			retry:    notify.DefaultRetry,
+			commands: *o.notifyCommands,
+		},
+	}
+	if agg.extractLimit > 0 {
+		agg.articles = article.NewFetcher(*o.timeout)
+	}
+	return agg, nil
+}
+
+// aggregator fetches due feeds with a pool of workers. Each worker claims a
+// feed with a locking query, so several gator processes can share the work
+// without fetching the same feed twice.
+type aggregator struct {
+	state   *State
+	workers int
+	hosts   *hostLimiter
+	fetcher *fetcher
+
+	// articles is set when the aggregator also saves full articles, up to
+	// extractLimit per cycle.
+	articles     *article.Fetcher
+	extractLimit int
+
+	digests *digestSender
+}
+
+// run checks for due feeds every interval until ctx is cancelled.
+func (a *aggregator) run(ctx context.Context, interval time.Duration) {
+	fmt.Printf("⏳ Checking for due feeds every %s with %d workers\n", interval, a.workers)
+
+	if n, err := backfillPostKeys(ctx, a.state.DB); err != nil {
+		log.Printf("⚠️ failed backfilling post keys: %v", err)
+	} else if n > 0 {
+		log.Printf("🧹 backfilled the dedup keys of %d posts", n)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		a.runCycle(ctx)
+		if a.articles != nil {
+			a.extractArticles(ctx)
+		}
+		a.digests.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("👋 stopped collecting feeds")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type cycleStats struct {
+	fetched  atomic.Int64
+	failed   atomic.Int64
+	newPosts atomic.Int64
+}
+
+// runCycle fetches every feed whose next fetch is due, then logs a summary.
+func (a *aggregator) runCycle(ctx context.Context) {
+	start := time.Now()
+	stats := &cycleStats{}
+
+	var wg sync.WaitGroup
+	for range a.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a.work(ctx, stats)
+		}()
+	}
+	wg.Wait()
+
+	log.Printf("🔁 cycle finished in %s: %d feeds fetched, %d failed, %d new posts",
+		time.Since(start).Round(time.Millisecond), stats.fetched.Load(), stats.failed.Load(), stats.newPosts.Load())
+}
+
+// extractArticles saves the full article of up to extractLimit posts that
+// haven't been tried yet, newest first. Each post is only tried once.
+func (a *aggregator) extractArticles(ctx context.Context) {
+	posts, err := a.state.DB.ClaimPostsForExtraction(ctx, int32(a.extractLimit))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("⚠️ could not claim posts for extraction: %v", err)
+		}
+		return
+	}
+	if len(posts) == 0 {
+		return
+	}
+
+	queue := make(chan database.ClaimPostsForExtractionRow)
+	var saved atomic.Int64
+	var wg sync.WaitGroup
+	for range a.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for post := range queue {
+				release, err := a.hosts.acquire(ctx, feedHost(post.Url))
+				if err != nil {
+					return
+				}
+				_, err = saveArticle(ctx, a.state.DB, a.articles, post.ID, post.Url)
+				release()
+
+				if err != nil {
+					if ctx.Err() == nil {
+						log.Printf("⚠️ no full text for %s: %v", post.Title, err)
+					}
+					continue
+				}
+				saved.Add(1)
+			}
+		}()
+	}
+
+	for _, post := range posts {
+		select {
+		case queue <- post:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	log.Printf("📄 saved the full text of %d of %d posts", saved.Load(), len(posts))
+}
+
+func (a *aggregator) work(ctx context.Context, stats *cycleStats) {
+	for ctx.Err() == nil {
+		feed, err := a.state.DB.ClaimNextFeed(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️ could not claim a feed: %v", err)
+			}
+			return
+		}
+
+		release, err := a.hosts.acquire(ctx, feedHost(feed.Url))
+		if err != nil {
+			return
+		}
+
+		newPosts, err := scrapeFeed(ctx, a.state, a.fetcher, feed)
+		release()
+
+		if err != nil {
+			stats.failed.Add(1)
+			if ctx.Err() == nil {
+				log.Printf("⚠️ %s: %v", feed.Name, err)
+			}
+			continue
+		}
+		stats.fetched.Add(1)
+		stats.newPosts.Add(int64(newPosts))
+	}
+}
+
+func feedHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// hostLimiter caps how many fetches run against the same host at once, so
+// a user following dozens of feeds on one site doesn't hammer it.
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, slots: make(map[string]chan struct{})}
+}
+
+// acquire waits for a free slot for host and returns a function that gives
+// it back.
+func (h *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	h.mu.Lock()
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.limit)
+		h.slots[host] = slots
+	}
+	h.mu.Unlock()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+)
+
+// testDBEnv names the Postgres database the database tests run against,
+// migrated with goose as described in the README. Tests that need it are
+// skipped when it isn't set.
+const testDBEnv = "GATOR_TEST_DB_URL"
+
+// testDB opens the test database, skipping the test if there isn't one.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbURL := os.Getenv(testDBEnv)
+	if dbURL == "" {
+		t.Skipf("%s is not set", testDBEnv)
+	}
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("unexpected error opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// testTx opens a transaction on the test database that is rolled back when
+// the test ends, so tests never see each other's rows.
+func testTx(t *testing.T) (*sql.Tx, *database.Queries) {
+	t.Helper()
+
+	tx, err := testDB(t).Begin()
+	if err != nil {
+		t.Fatalf("unexpected error starting transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx, database.New(tx)
+}
+
+// createTestFeed creates a feed owned by a new user.
+func createTestFeed(t *testing.T, q *database.Queries) database.Feed {
+	t.Helper()
+
+	ctx := context.Background()
+	user, err := q.CreateUser(ctx, database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      "test-" + uuid.NewString(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	feed, err := q.CreateFeed(ctx, database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      "test feed",
+		Url:       "https://example.com/" + uuid.NewString() + "/feed.xml",
+		UserID:    user.ID,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating feed: %v", err)
+	}
+	return feed
+}
@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/feed"
+	"github.com/google/uuid"
+)
+
+// trackingParams are query parameters that only identify where a click came
+// from. Parameters starting with utm_, pk_ or mtm_ are dropped as well.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"ref":     true,
+	"ref_src": true,
+}
+
+func isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	return trackingParams[name] ||
+		strings.HasPrefix(name, "utm_") ||
+		strings.HasPrefix(name, "pk_") ||
+		strings.HasPrefix(name, "mtm_")
+}
+
+// normalizeURL returns the form of rawURL used to spot the same post under
+// different links: lowercase scheme and host, no default port, fragment or
+// tracking parameters, and the remaining parameters sorted. It returns ""
+// for a URL without a host, which doesn't say what it links to.
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	query := u.Query()
+	for name := range query {
+		if isTrackingParam(name) {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// postKey returns the key a post linking to rawURL is stored under. A post
+// whose link has no host can't be matched by URL, so it is keyed by its own
+// ID instead.
+func postKey(postID uuid.UUID, rawURL string) string {
+	if normalized := normalizeURL(rawURL); normalized != "" {
+		return normalized
+	}
+	return "urn:uuid:" + postID.String()
+}
+
+// contentHash identifies an item by what it says rather than where it
+// links, for feeds that change their links and GUIDs.
+func contentHash(item feed.Item) string {
+	body := item.Content
+	if body == "" {
+		body = item.Description
+	}
+
+	sum := sha256.Sum256([]byte(item.Title + "\x00" + strings.TrimSpace(body)))
+	return hex.EncodeToString(sum[:])
+}
+
+// storePost saves item as a post of feedID, returning its ID and whether it
+// is new to that feed. The item is matched against the feed's posts by GUID, then by
+// normalized URL, then by title and content hash. A URL linked from several
+// feeds is stored once and linked to each of them. fetched holds the GUIDs
+// of every item in the same fetch.
+func storePost(ctx context.Context, db *database.Queries, feedID uuid.UUID, item feed.Item, fetched []string) (uuid.UUID, bool, error) {
+	_, err := db.GetFeedPostByGuid(ctx, database.GetFeedPostByGuidParams{FeedID: feedID, Guid: item.GUID})
+	if err == nil {
+		return uuid.Nil, false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, false, err
+	}
+
+	newID := uuid.New()
+	normalized := postKey(newID, item.Link)
+	hash := contentHash(item)
+
+	post, err := db.GetPostByNormalizedUrl(ctx, normalized)
+	if errors.Is(err, sql.ErrNoRows) {
+		postID, err := db.GetFeedPostByContentHash(ctx, database.GetFeedPostByContentHashParams{FeedID: feedID, ContentHash: hash})
+		if err == nil {
+			// The feed moved this post to a new link; remember its new GUID.
+			return postID, false, updateGuid(ctx, db, feedID, postID, item.GUID, fetched)
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, false, err
+		}
+
+		post, err = db.CreatePost(ctx, database.CreatePostParams{
+			ID:            newID,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
+			Title:         item.Title,
+			Url:           item.Link,
+			NormalizedUrl: normalized,
+			ContentHash:   hash,
+			Description:   sql.NullString{String: item.Description, Valid: item.Description != ""},
+			PublishedAt:   sql.NullTime{Time: item.Published, Valid: !item.Published.IsZero()},
+			Author:        nullString(item.Author),
+			// categories is NOT NULL, so nil has to become an empty array.
+			Categories: append([]string{}, item.Categories...),
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// Another feed stored the same URL since we looked.
+			post, err = db.GetPostByNormalizedUrl(ctx, normalized)
+		}
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	linked, err := db.LinkPostToFeed(ctx, database.LinkPostToFeedParams{
+		FeedID:    feedID,
+		PostID:    post.ID,
+		CreatedAt: time.Now(),
+		Guid:      item.GUID,
+	})
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if linked == 0 {
+		// The feed already has this post under another GUID.
+		return post.ID, false, updateGuid(ctx, db, feedID, post.ID, item.GUID, fetched)
+	}
+	return post.ID, true, nil
+}
+
+// updateGuid replaces the GUID the feed's post is known by, unless another
+// item of the same fetch still carries the old one. Otherwise two items
+// sharing a link would swap the GUID back and forth on every fetch.
+func updateGuid(ctx context.Context, db *database.Queries, feedID, postID uuid.UUID, guid string, fetched []string) error {
+	return db.UpdateFeedPostGuid(ctx, database.UpdateFeedPostGuidParams{
+		Guid:         guid,
+		FeedID:       feedID,
+		PostID:       postID,
+		FetchedGuids: fetched,
+	})
+}
+
+// backfillBatch is how many posts backfillPostKeys updates per query.
+const backfillBatch = 500
+
+// backfillPostKeys gives the posts carried over by migration 008 the keys
+// storePost looks them up by. The migration could only copy their raw URL
+// and left their content hash empty, so until then a link with tracking
+// parameters or a fragment would be stored a second time. A post whose
+// normalized URL is already taken keeps its raw one, and one whose URL has
+// no host is keyed by its ID. It returns how many posts were updated.
+func backfillPostKeys(ctx context.Context, db *database.Queries) (int, error) {
+	updated := 0
+	for {
+		posts, err := db.GetPostsToBackfill(ctx, backfillBatch)
+		if err != nil {
+			return updated, err
+		}
+		if len(posts) == 0 {
+			return updated, nil
+		}
+
+		for _, post := range posts {
+			err := db.BackfillPostKeys(ctx, database.BackfillPostKeysParams{
+				ID:            post.ID,
+				NormalizedUrl: postKey(post.ID, post.Url),
+				ContentHash:   contentHash(feed.Item{Title: post.Title, Description: post.Description.String}),
+			})
+			if err != nil {
+				return updated, fmt.Errorf("failed backfilling post %s: %w", post.ID, err)
+			}
+			updated++
+		}
+	}
+}
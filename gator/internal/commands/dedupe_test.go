@@ -0,0 +1,179 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/feed"
+	"github.com/google/uuid"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := map[string]string{
+		"https://Example.com/post?utm_source=rss&utm_medium=feed":  "https://example.com/post",
+		"https://example.com:443/post#comments":                    "https://example.com/post",
+		"http://example.com:80":                                    "http://example.com/",
+		"https://example.com/post?b=2&fbclid=abc&a=1":              "https://example.com/post?a=1&b=2",
+		"https://example.com/post?id=7&pk_campaign=x&mtm_source=y": "https://example.com/post?id=7",
+		"  https://example.com/post  ":                             "https://example.com/post",
+		"not a url":                                                "",
+		"/blog/post":                                               "",
+	}
+
+	for input, want := range tests {
+		if got := normalizeURL(input); got != want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	a := feed.Item{Title: "Hello", Link: "https://example.com/a", Content: "<p>Body</p>"}
+	moved := feed.Item{Title: "Hello", Link: "https://example.com/b", Content: "<p>Body</p>"}
+	edited := feed.Item{Title: "Hello", Link: "https://example.com/a", Content: "<p>Body, edited</p>"}
+	described := feed.Item{Title: "Hello", Description: "<p>Body</p>"}
+
+	if contentHash(a) != contentHash(moved) {
+		t.Fatalf("expected the hash to ignore the link")
+	}
+	if contentHash(a) == contentHash(edited) {
+		t.Fatalf("expected different content to hash differently")
+	}
+	if contentHash(a) != contentHash(described) {
+		t.Fatalf("expected the description to stand in for missing content")
+	}
+}
+
+// Test that posts carried over by migration 008, with their raw URL as
+// normalized URL and GUID and no content hash, are found again once
+// backfilled.
+func TestStorePost_MigratedRows(t *testing.T) {
+	tx, q := testTx(t)
+	ctx := context.Background()
+	f := createTestFeed(t, q)
+	host := "https://" + uuid.NewString() + ".example.com"
+
+	post := insertMigratedPost(t, tx, f, "Hello", host+"/post?utm_source=rss#top", "Body")
+	// Both normalize to host+"/other", which only one of them can keep.
+	taken := insertMigratedPost(t, tx, f, "Other", host+"/other", "")
+	raw := insertMigratedPost(t, tx, f, "Other again", host+"/other?fbclid=1", "")
+
+	if _, err := backfillPostKeys(ctx, q); err != nil {
+		t.Fatalf("unexpected error backfilling: %v", err)
+	}
+	for id, want := range map[uuid.UUID]string{post: host + "/post", taken: host + "/other", raw: host + "/other?fbclid=1"} {
+		var normalized, hash string
+		err := tx.QueryRow("SELECT normalized_url, content_hash FROM posts WHERE id = $1", id).Scan(&normalized, &hash)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if normalized != want || hash == "" {
+			t.Fatalf("expected %s with a content hash, got %s and %q", want, normalized, hash)
+		}
+	}
+
+	tests := []struct {
+		name string
+		item feed.Item
+	}{
+		{"same link, new guid", feed.Item{GUID: "tag:1", Title: "Hello", Link: host + "/post", Description: "Body"}},
+		{"new link, same content", feed.Item{GUID: "tag:2", Title: "Hello", Link: host + "/moved", Description: "Body"}},
+	}
+	for _, tt := range tests {
+		postID, isNew, err := storePost(ctx, q, f.ID, tt.item, []string{tt.item.GUID})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if isNew || postID != post {
+			t.Fatalf("%s: expected the migrated post %s, got %s (new: %v)", tt.name, post, postID, isNew)
+		}
+	}
+}
+
+// insertMigratedPost stores a post of f the way migration 008 leaves it.
+func insertMigratedPost(t *testing.T, tx *sql.Tx, f database.Feed, title, url, description string) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	_, err := tx.Exec(`INSERT INTO posts (id, created_at, updated_at, title, url, description, normalized_url)
+		VALUES ($1, NOW(), NOW(), $2, $3, NULLIF($4, ''), $3)`, id, title, url, description)
+	if err != nil {
+		t.Fatalf("unexpected error inserting post: %v", err)
+	}
+	_, err = tx.Exec("INSERT INTO feed_posts (feed_id, post_id, guid) VALUES ($1, $2, $3)", f.ID, id, url)
+	if err != nil {
+		t.Fatalf("unexpected error linking post: %v", err)
+	}
+	return id
+}
+
+// Test that two items of one fetch sharing a link don't swap the post's GUID
+// on every fetch, and that the GUID moves on once the first item is gone.
+func TestStorePost_SharedLink(t *testing.T) {
+	tx, q := testTx(t)
+	ctx := context.Background()
+	f := createTestFeed(t, q)
+	link := "https://" + uuid.NewString() + ".example.com/post"
+
+	first := feed.Item{GUID: "tag:1", Title: "Hello", Link: link}
+	second := feed.Item{GUID: "tag:2", Title: "Hello again", Link: link + "?utm_source=rss"}
+	fetched := []string{first.GUID, second.GUID}
+
+	var postID uuid.UUID
+	for fetch := range 3 {
+		for i, item := range []feed.Item{first, second} {
+			id, isNew, err := storePost(ctx, q, f.ID, item, fetched)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fetch == 0 && i == 0 {
+				postID = id
+			}
+			if id != postID || isNew != (fetch == 0 && i == 0) {
+				t.Fatalf("fetch %d, item %d: expected post %s to be new only once, got %s (new: %v)", fetch, i, postID, id, isNew)
+			}
+			if got := feedPostGuid(t, tx, f, postID); got != first.GUID {
+				t.Fatalf("fetch %d, item %d: expected GUID %s to stay, got %s", fetch, i, first.GUID, got)
+			}
+		}
+	}
+
+	if _, _, err := storePost(ctx, q, f.ID, second, []string{second.GUID}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := feedPostGuid(t, tx, f, postID); got != second.GUID {
+		t.Fatalf("expected GUID %s once %s left the feed, got %s", second.GUID, first.GUID, got)
+	}
+}
+
+// Test that items whose links have no host are never taken for each other.
+func TestStorePost_HostlessLink(t *testing.T) {
+	_, q := testTx(t)
+	ctx := context.Background()
+	first, second := createTestFeed(t, q), createTestFeed(t, q)
+
+	a, _, err := storePost(ctx, q, first.ID, feed.Item{GUID: "tag:a", Title: "A", Link: "/post"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, isNew, err := storePost(ctx, q, second.ID, feed.Item{GUID: "tag:b", Title: "B", Link: "/post"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a == b || !isNew {
+		t.Fatalf("expected a new post for the second feed, got %s and %s", a, b)
+	}
+}
+
+func feedPostGuid(t *testing.T, tx *sql.Tx, f database.Feed, postID uuid.UUID) string {
+	t.Helper()
+
+	var guid string
+	err := tx.QueryRow("SELECT guid FROM feed_posts WHERE feed_id = $1 AND post_id = $2", f.ID, postID).Scan(&guid)
+	if err != nil {
+		t.Fatalf("unexpected error getting GUID: %v", err)
+	}
+	return guid
+}
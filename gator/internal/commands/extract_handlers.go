@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/article"
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+const extractUsage = "usage: gator extract <post id>... [--timeout D]"
+
+// HandlerExtract saves the full article behind posts, for reading offline
+// with browse --full or in the TUI.
+func HandlerExtract(s *State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	timeout := flags.Duration("timeout", 30*time.Second, "how long fetching one article may take")
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) == 0 || *timeout <= 0 {
+		return errors.New(extractUsage)
+	}
+
+	ctx := context.Background()
+	f := article.NewFetcher(*timeout)
+
+	failed := 0
+	for _, ref := range args {
+		post, err := findPost(ctx, s, user, ref)
+		if err != nil {
+			return err
+		}
+
+		saved, err := saveArticle(ctx, s.DB, f, post.ID, post.Url)
+		if err != nil {
+			fmt.Printf("⚠️ %s: %v\n", post.Title, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ saved full text: %s (%d words)\n", post.Title, len(strings.Fields(saved.Text)))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("could not save %d of %d articles", failed, len(args))
+	}
+	return nil
+}
+
+// saveArticle fetches the article at url and stores it on the post.
+func saveArticle(ctx context.Context, db *database.Queries, f *article.Fetcher, postID uuid.UUID, url string) (article.Article, error) {
+	fetched, err := f.Fetch(ctx, url)
+	if err != nil {
+		return article.Article{}, err
+	}
+
+	err = db.SetPostContent(ctx, database.SetPostContentParams{
+		ID:          postID,
+		ContentHtml: sql.NullString{String: fetched.HTML, Valid: true},
+		ContentText: sql.NullString{String: fetched.Text, Valid: true},
+	})
+	if err != nil {
+		return article.Article{}, fmt.Errorf("could not save article: %w", err)
+	}
+	return fetched, nil
+}
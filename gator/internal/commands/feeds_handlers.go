@@ -3,8 +3,10 @@ package commands
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"strconv"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/ckm54/go-projects/gator/internal/database"
@@ -12,29 +14,6 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
-func HandlerAggregate(s *State, cmd Command) error {
-	if len(cmd.Args) != 1 {
-		return fmt.Errorf("usage: gator agg <duration> (e.g. 30s, 5m, 1h)")
-	}
-
-	interval, err := time.ParseDuration(cmd.Args[0])
-	if err != nil {
-		return fmt.Errorf("invalid time duration: %w", err)
-	}
-
-	fmt.Printf("⏳ Collecting feeds every %s\n", interval)
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	for {
-		if err = scrapeFeeds(s); err != nil {
-			fmt.Println("⚠️", err)
-		}
-		<-ticker.C
-	}
-}
-
 func HandlerAddFeed(s *State, cmd Command, user database.User) error {
 	switch len(cmd.Args) {
 	case 0:
@@ -89,11 +68,14 @@ func HandlerGetFeeds(s *State, cmd Command) error {
 }
 
 func HandlerFollowFeed(s *State, cmd Command, user database.User) error {
-	if len(cmd.Args) == 0 {
-		return fmt.Errorf("missing url.\nusage: gator follow <url>")
+	flags := flag.NewFlagSet("follow", flag.ContinueOnError)
+	folder := flags.String("folder", "", "file the feed under this folder")
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) == 0 {
+		return fmt.Errorf("missing url.\nusage: gator follow <url> [--folder <folder>]")
 	}
 
-	feedUrl := cmd.Args[0]
+	feedUrl := args[0]
 	feed, err := getFeedByUrl(s, feedUrl)
 	if err != nil {
 		return err
@@ -118,10 +100,23 @@ func HandlerFollowFeed(s *State, cmd Command, user database.User) error {
 	}
 
 	fmt.Printf("✅ %s now follows %s\n", feedFollow.UserName, feedFollow.FeedName)
+
+	if *folder != "" {
+		return setFolder(s, user, normalizeFolder(*folder), []string{feed.Url})
+	}
 	return nil
 }
 
+// HandlerFollowing lists the user's feeds by folder, with their tags.
 func HandlerFollowing(s *State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("following", flag.ContinueOnError)
+	folder := flags.String("folder", "", "only list feeds in this folder")
+	tag := flags.String("tag", "", "only list feeds with this tag")
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) > 0 {
+		return errors.New("usage: gator following [--folder <folder>] [--tag <tag>]")
+	}
+
 	userFeeds, err := s.DB.GetFeedFollowsForUser(context.Background(), user.ID)
 	if err != nil {
 		return fmt.Errorf("error getting user feeds: %w", err)
@@ -129,11 +124,45 @@ func HandlerFollowing(s *State, cmd Command, user database.User) error {
 
 	if len(userFeeds) == 0 {
 		fmt.Println("You are not following any feeds yet")
-	} else {
-		fmt.Println("You are following:")
-		for _, feed := range userFeeds {
-			fmt.Printf("- %s\n", feed.FeedName)
+		return nil
+	}
+
+	onlyFolder := normalizeFolder(*folder)
+	onlyTag := strings.ToLower(strings.TrimPrefix(*tag, "#"))
+
+	// Follows come sorted by folder, top level first.
+	listed := 0
+	current := ""
+	for _, feed := range userFeeds {
+		if onlyFolder != "" && !inFolder(feed.Category, onlyFolder) {
+			continue
+		}
+		if onlyTag != "" && !slices.Contains(feed.Tags, onlyTag) {
+			continue
+		}
+
+		if listed == 0 {
+			fmt.Println("You are following:")
 		}
+		if feed.Category.String != current {
+			current = feed.Category.String
+			fmt.Printf("📁 %s\n", current)
+		}
+
+		indent := ""
+		if current != "" {
+			indent = "  "
+		}
+		line := fmt.Sprintf("%s- %s", indent, feed.FeedName)
+		if len(feed.Tags) > 0 {
+			line += "  🏷️ " + strings.Join(feed.Tags, ", ")
+		}
+		fmt.Println(line)
+		listed++
+	}
+
+	if listed == 0 {
+		fmt.Println("no matching feeds")
 	}
 	return nil
 }
@@ -168,35 +197,3 @@ func getFeedByUrl(s *State, feedUrl string) (database.Feed, error) {
 
 	return feed, nil
 }
-
-func HandlerBrowse(s *State, cmd Command, user database.User) error {
-	limit := 2
-	if len(cmd.Args) == 1 {
-		parsed, err := strconv.Atoi(cmd.Args[0])
-		if err != nil || parsed < 1 {
-			return fmt.Errorf("invalid limit\nusage: gator browse [limit]")
-		}
-
-		limit = parsed
-	}
-
-	posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
-	if err != nil {
-		return err
-	}
-
-	if len(posts) == 0 {
-		fmt.Println("no posts yet - try running gator agg 1m")
-		return nil
-	}
-
-	for _, post := range posts {
-		fmt.Printf("\n📌 %s\n🔗 %s\n📰 %s\n", post.FeedName, post.Url, post.Title)
-	}
-
-	return nil
-
-}
@@ -2,61 +2,189 @@ package commands
 
 import (
 	"context"
-	"encoding/xml"
+	"errors"
 	"fmt"
-	"html"
 	"io"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/feed"
+	"github.com/ckm54/go-projects/gator/internal/netguard"
+)
+
+const (
+	// maxFeedBytes caps how much of a feed we download. Real feeds are well
+	// under this; anything bigger is a misconfigured server or an attack.
+	maxFeedBytes    = 10 << 20
+	maxFeedRedirect = 5
 )
 
-type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+// errFeedGone is returned when the server says the feed was removed for
+// good (410 Gone).
+var errFeedGone = errors.New("feed is gone")
+
+// statusError is returned for responses we can't use. RetryAfter is set
+// when the server told us when to come back.
+type statusError struct {
+	Status     int
+	RetryAfter time.Duration
 }
 
-type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s", e.Status, http.StatusText(e.Status))
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	client := &http.Client{Timeout: 5 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
-	if err != nil {
-		return nil, err
+// fetchResult describes a successful fetch. Feed is nil when the server
+// answered 304 Not Modified.
+type fetchResult struct {
+	Status       int
+	Feed         *feed.Feed
+	ETag         string
+	LastModified string
+	// MovedTo is the feed's new URL if every redirect on the way was
+	// permanent (301 or 308).
+	MovedTo string
+}
+
+type fetcher struct {
+	client *http.Client
+}
+
+// newFetcher returns a fetcher that only connects to addresses allow
+// accepts. Feed URLs come from any user, including over the HTTP API, so
+// the aggregator passes netguard.IsPublic to keep them off internal
+// services.
+func newFetcher(timeout time.Duration, allow func(netip.Addr) bool) *fetcher {
+	return &fetcher{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext:           netguard.Dialer(timeout, allow).DialContext,
+				TLSHandshakeTimeout:   timeout,
+				ResponseHeaderTimeout: timeout,
+				MaxIdleConns:          100,
+				IdleConnTimeout:       90 * time.Second,
+			},
+			Timeout: timeout,
+			// Redirects are followed by hand so we can tell permanent
+			// moves from temporary ones.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
 	}
+}
 
-	req.Header.Set("User-Agent", "gator")
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// fetch downloads feedURL, sending the validators from the previous fetch
+// so an unchanged feed costs a 304 instead of the full body.
+func (f *fetcher) fetch(ctx context.Context, feedURL, etag, lastModified string) (fetchResult, error) {
+	target := feedURL
+	permanent := true
+
+	for range maxFeedRedirect + 1 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+		if err != nil {
+			return fetchResult{}, err
+		}
+
+		req.Header.Set("User-Agent", "gator")
+		req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, */*;q=0.8")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return fetchResult{}, err
+		}
+
+		result := fetchResult{
+			Status:       resp.StatusCode,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}
+		if permanent && target != feedURL {
+			result.MovedTo = target
+		}
+
+		switch resp.StatusCode {
+		case http.StatusMovedPermanently, http.StatusPermanentRedirect,
+			http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect:
+			resp.Body.Close()
+
+			location, err := resp.Location()
+			if err != nil {
+				return fetchResult{Status: resp.StatusCode}, fmt.Errorf("redirect without a location: %w", err)
+			}
+			if resp.StatusCode != http.StatusMovedPermanently && resp.StatusCode != http.StatusPermanentRedirect {
+				permanent = false
+			}
+			target = location.String()
+			continue
+
+		case http.StatusNotModified:
+			resp.Body.Close()
+			// Servers may leave the validators out of a 304; keep ours.
+			if result.ETag == "" {
+				result.ETag = etag
+			}
+			if result.LastModified == "" {
+				result.LastModified = lastModified
+			}
+			return result, nil
+
+		case http.StatusOK:
+			parsed, err := readFeed(resp.Body, target)
+			resp.Body.Close()
+			if err != nil {
+				return fetchResult{Status: resp.StatusCode}, err
+			}
+			result.Feed = parsed
+			return result, nil
+
+		case http.StatusGone:
+			resp.Body.Close()
+			return fetchResult{Status: resp.StatusCode}, errFeedGone
+
+		default:
+			resp.Body.Close()
+			return fetchResult{Status: resp.StatusCode}, &statusError{
+				Status:     resp.StatusCode,
+				RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			}
+		}
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	return fetchResult{}, fmt.Errorf("more than %d redirects", maxFeedRedirect)
+}
+
+func readFeed(body io.Reader, feedURL string) (*feed.Feed, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxFeedBytes+1))
 	if err != nil {
 		return nil, err
 	}
-
-	var result RSSFeed
-	err = xml.Unmarshal(data, &result)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	if len(data) > maxFeedBytes {
+		return nil, fmt.Errorf("feed is larger than %d MiB", maxFeedBytes>>20)
 	}
 
-	result.Channel.Title = html.UnescapeString(result.Channel.Title)
-	result.Channel.Description = html.UnescapeString(result.Channel.Description)
+	return feed.Parse(data, feedURL)
+}
 
-	for i := range result.Channel.Item {
-		result.Channel.Item[i].Title = html.UnescapeString(result.Channel.Item[i].Title)
-		result.Channel.Item[i].Description = html.UnescapeString(result.Channel.Item[i].Description)
+// parseRetryAfter reads a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
 	}
-
-	return &result, nil
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
 }
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/netguard"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Test</title>
+<item><title>Hello</title><link>https://example.com/hello</link></item>
+</channel></rss>`
+
+func TestFetchSendsValidatorsAndHandlesNotModified(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 19 Oct 2026 10:00:00 GMT")
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	f := newFetcher(time.Second, allowAll)
+
+	first, err := f.fetch(context.Background(), srv.URL, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Feed == nil || len(first.Feed.Items) != 1 {
+		t.Fatalf("expected a feed with one item, got %+v", first.Feed)
+	}
+	if first.ETag != `"v1"` {
+		t.Fatalf("expected etag \"v1\", got %q", first.ETag)
+	}
+
+	second, err := f.fetch(context.Background(), srv.URL, first.ETag, first.LastModified)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.Status != http.StatusNotModified || second.Feed != nil {
+		t.Fatalf("expected 304 without a feed, got %d %+v", second.Status, second.Feed)
+	}
+	if second.ETag != first.ETag || second.LastModified != first.LastModified {
+		t.Fatalf("expected validators to be kept, got %q %q", second.ETag, second.LastModified)
+	}
+}
+
+func TestFetchReportsPermanentMoves(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/temp", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newFetcher(time.Second, allowAll)
+
+	result, err := f.fetch(context.Background(), srv.URL+"/old", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MovedTo != srv.URL+"/new" {
+		t.Fatalf("expected move to /new, got %q", result.MovedTo)
+	}
+
+	result, err = f.fetch(context.Background(), srv.URL+"/temp", "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.MovedTo != "" {
+		t.Fatalf("expected no move for a temporary redirect, got %q", result.MovedTo)
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	mux.HandleFunc("/busy", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("/huge", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("a", maxFeedBytes+1)))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	f := newFetcher(5*time.Second, allowAll)
+
+	if _, err := f.fetch(context.Background(), srv.URL+"/gone", "", ""); !errors.Is(err, errFeedGone) {
+		t.Fatalf("expected errFeedGone, got %v", err)
+	}
+
+	_, err := f.fetch(context.Background(), srv.URL+"/busy", "", "")
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("expected a status error, got %v", err)
+	}
+	if statusErr.Status != http.StatusTooManyRequests || statusErr.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected 429 with a 2m retry, got %d %s", statusErr.Status, statusErr.RetryAfter)
+	}
+
+	if _, err := f.fetch(context.Background(), srv.URL+"/huge", "", ""); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("expected a size error, got %v", err)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		errors int32
+		want   time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{4, 8 * time.Minute},
+		{30, retryMax},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.errors); got != tt.want {
+			t.Errorf("retryDelay(%d) = %s, want %s", tt.errors, got, tt.want)
+		}
+	}
+}
+
+// Test that feeds on loopback or private addresses are never fetched.
+func TestFetchRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(testRSS))
+	}))
+	defer srv.Close()
+
+	f := newFetcher(time.Second, netguard.IsPublic)
+	_, err := f.fetch(context.Background(), srv.URL, "", "")
+	if !errors.Is(err, netguard.ErrForbiddenAddress) {
+		t.Fatalf("expected ErrForbiddenAddress, got %v", err)
+	}
+}
+
+// allowAll lets tests fetch from httptest servers on loopback.
+func allowAll(netip.Addr) bool { return true }
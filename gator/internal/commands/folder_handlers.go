@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/rules"
+)
+
+const (
+	folderUsage = `usage: gator folder list
+       gator folder set <folder> <feed url>...
+       gator folder clear <feed url>...
+       gator folder rename <folder> <new folder>`
+
+	feedTagUsage = `usage: gator tag add <feed url> <tag>...
+       gator tag remove <feed url> <tag>...`
+)
+
+// HandlerFolder files followed feeds into folders. Folders nest with "/",
+// as in "Tech/Go", and are kept as nested outlines by import and export.
+func HandlerFolder(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New(folderUsage)
+	}
+
+	switch cmd.Args[0] {
+	case "list":
+		if len(cmd.Args) != 1 {
+			return errors.New(folderUsage)
+		}
+		return listFolders(s, user)
+	case "set":
+		if len(cmd.Args) < 3 {
+			return errors.New(folderUsage)
+		}
+		folder := normalizeFolder(cmd.Args[1])
+		if folder == "" {
+			return errors.New("missing folder name, use gator folder clear to move feeds to the top level")
+		}
+		return setFolder(s, user, folder, cmd.Args[2:])
+	case "clear":
+		if len(cmd.Args) < 2 {
+			return errors.New(folderUsage)
+		}
+		return setFolder(s, user, "", cmd.Args[1:])
+	case "rename":
+		if len(cmd.Args) != 3 {
+			return errors.New(folderUsage)
+		}
+		return renameFolder(s, user, normalizeFolder(cmd.Args[1]), normalizeFolder(cmd.Args[2]))
+	default:
+		return errors.New(folderUsage)
+	}
+}
+
+func listFolders(s *State, user database.User) error {
+	follows, err := s.DB.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting user feeds: %w", err)
+	}
+
+	counts := map[string]int{}
+	var folders []string
+	for _, follow := range follows {
+		folder := follow.Category.String
+		if _, ok := counts[folder]; !ok {
+			folders = append(folders, folder)
+		}
+		counts[folder]++
+	}
+
+	if len(folders) == 0 || (len(folders) == 1 && folders[0] == "") {
+		fmt.Println("no folders yet, file a feed with: gator folder set <folder> <feed url>")
+		return nil
+	}
+
+	slices.Sort(folders)
+	for _, folder := range folders {
+		name := folder
+		if name == "" {
+			name = "(top level)"
+		}
+		fmt.Printf("📁 %s · %s\n", name, plural(counts[folder], "feed"))
+	}
+	return nil
+}
+
+// setFolder files feeds under folder, moving them out of the folder they
+// were in. An empty folder moves them to the top level.
+func setFolder(s *State, user database.User, folder string, feedURLs []string) error {
+	ctx := context.Background()
+	for _, feedURL := range feedURLs {
+		feed, err := getFeedByUrl(s, feedURL)
+		if err != nil {
+			return err
+		}
+
+		n, err := s.DB.SetFeedFollowCategory(ctx, database.SetFeedFollowCategoryParams{
+			UserID:   user.ID,
+			FeedID:   feed.ID,
+			Category: nullString(folder),
+		})
+		if err != nil {
+			return fmt.Errorf("could not move %s: %w", feed.Name, err)
+		}
+		if n == 0 {
+			return fmt.Errorf("you don't follow %s, follow it with: gator follow %s --folder %q", feed.Name, feed.Url, folder)
+		}
+
+		if folder == "" {
+			fmt.Printf("✅ %s moved to the top level\n", feed.Name)
+		} else {
+			fmt.Printf("✅ %s filed under 📁 %s\n", feed.Name, folder)
+		}
+	}
+	return nil
+}
+
+func renameFolder(s *State, user database.User, from, to string) error {
+	if from == "" || to == "" {
+		return errors.New(folderUsage)
+	}
+	if to == from || strings.HasPrefix(to, from+"/") {
+		return fmt.Errorf("can't move %s into itself", from)
+	}
+
+	n, err := s.DB.MoveFeedFollowFolder(context.Background(), database.MoveFeedFollowFolderParams{
+		NewFolder: to,
+		OldFolder: from,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("could not rename %s: %w", from, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no folder %s, see gator folder list", from)
+	}
+
+	fmt.Printf("✅ 📁 %s is now 📁 %s (%s moved)\n", from, to, plural(int(n), "feed"))
+	return nil
+}
+
+// HandlerFeedTag tags followed feeds. Their posts carry the feed's tags, so
+// browse --tag finds them along with posts tagged by rules.
+func HandlerFeedTag(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) < 3 || (cmd.Args[0] != "add" && cmd.Args[0] != "remove") {
+		return errors.New(feedTagUsage)
+	}
+
+	tags := make([]string, 0, len(cmd.Args)-2)
+	for _, arg := range cmd.Args[2:] {
+		tag, err := rules.NormalizeTag(strings.TrimPrefix(arg, "#"))
+		if err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+
+	feed, err := getFeedByUrl(s, cmd.Args[1])
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var n int64
+	if cmd.Args[0] == "add" {
+		n, err = s.DB.AddFeedFollowTags(ctx, database.AddFeedFollowTagsParams{Tags: tags, UserID: user.ID, FeedID: feed.ID})
+	} else {
+		n, err = s.DB.RemoveFeedFollowTags(ctx, database.RemoveFeedFollowTagsParams{Tags: tags, UserID: user.ID, FeedID: feed.ID})
+	}
+	if err != nil {
+		return fmt.Errorf("could not update tags of %s: %w", feed.Name, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("you don't follow %s", feed.Name)
+	}
+
+	if cmd.Args[0] == "add" {
+		fmt.Printf("✅ tagged %s: %s\n", feed.Name, strings.Join(tags, ", "))
+	} else {
+		fmt.Printf("✅ untagged %s: %s\n", feed.Name, strings.Join(tags, ", "))
+	}
+	return nil
+}
+
+// normalizeFolder tidies a folder path typed by a user or read from OPML:
+// "/Tech / Go/" becomes "Tech/Go".
+func normalizeFolder(path string) string {
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// inFolder reports whether category is folder or one of its subfolders.
+func inFolder(category sql.NullString, folder string) bool {
+	return category.Valid && (category.String == folder || strings.HasPrefix(category.String, folder+"/"))
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
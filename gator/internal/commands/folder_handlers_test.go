@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestNormalizeFolder(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"Tech", "Tech"},
+		{"/Tech / Go/", "Tech/Go"},
+		{"Tech//Go", "Tech/Go"},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeFolder(tt.path); got != tt.want {
+			t.Fatalf("normalizeFolder(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInFolder(t *testing.T) {
+	tests := []struct {
+		category sql.NullString
+		folder   string
+		want     bool
+	}{
+		{sql.NullString{String: "Tech", Valid: true}, "Tech", true},
+		{sql.NullString{String: "Tech/Go", Valid: true}, "Tech", true},
+		{sql.NullString{String: "Technology", Valid: true}, "Tech", false},
+		{sql.NullString{String: "Tech", Valid: true}, "Tech/Go", false},
+		{sql.NullString{}, "Tech", false},
+	}
+
+	for _, tt := range tests {
+		if got := inFolder(tt.category, tt.folder); got != tt.want {
+			t.Fatalf("inFolder(%q, %q) = %v, want %v", tt.category.String, tt.folder, got, tt.want)
+		}
+	}
+}
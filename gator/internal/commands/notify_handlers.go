@@ -0,0 +1,382 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/config"
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/notify"
+	"github.com/google/uuid"
+)
+
+const notifyUsage = `usage: gator notify add webhook <url> [--every D]
+       gator notify add email <address> [--every D]
+       gator notify add command [--every D] -- <program> [args...]
+       gator notify list
+       gator notify remove <id>
+       gator notify test <id>`
+
+// Kinds of notification sinks.
+const (
+	sinkWebhook = "webhook"
+	sinkEmail   = "email"
+	sinkCommand = "command"
+)
+
+const (
+	// maxDigestPosts caps how many posts a digest lists; the rest are
+	// only counted.
+	maxDigestPosts = 50
+
+	// maxDigestFailures is how many digests in a row may fail before the
+	// posts waiting for a sink are dropped.
+	maxDigestFailures = 10
+
+	digestTimeout = 2 * time.Minute
+)
+
+// HandlerNotify manages where digests of new posts are sent. Posts are
+// queued for the user's sinks by rules with the notify action, and gator agg
+// sends each sink a digest of its queue.
+func HandlerNotify(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New(notifyUsage)
+	}
+
+	switch cmd.Args[0] {
+	case "add":
+		return addSink(s, user, cmd.Args[1:])
+	case "list":
+		if len(cmd.Args) != 1 {
+			return errors.New(notifyUsage)
+		}
+		return listSinks(s, user)
+	case "remove":
+		if len(cmd.Args) != 2 {
+			return errors.New(notifyUsage)
+		}
+		return removeSink(s, user, cmd.Args[1])
+	case "test":
+		if len(cmd.Args) != 2 {
+			return errors.New(notifyUsage)
+		}
+		return testSink(s, user, cmd.Args[1])
+	default:
+		return errors.New(notifyUsage)
+	}
+}
+
+func addSink(s *State, user database.User, args []string) error {
+	// A command's own arguments follow "--", so they aren't taken for
+	// gator's flags.
+	var commandArgs []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, commandArgs = args[:i], args[i+1:]
+	}
+
+	flags := flag.NewFlagSet("notify add", flag.ContinueOnError)
+	every := flags.Duration("every", 0, "send at most one digest per interval; 0 sends one after each aggregator cycle")
+	args, err := parseFlags(flags, args)
+	if err != nil || len(args) == 0 || *every < 0 {
+		return errors.New(notifyUsage)
+	}
+
+	sink := database.CreateNotificationSinkParams{
+		ID:                    uuid.New(),
+		CreatedAt:             time.Now(),
+		UserID:                user.ID,
+		Kind:                  args[0],
+		Args:                  []string{},
+		DigestIntervalSeconds: int32(*every / time.Second),
+	}
+
+	switch sink.Kind {
+	case sinkWebhook:
+		if len(args) != 2 {
+			return errors.New(notifyUsage)
+		}
+		u, err := url.Parse(args[1])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook url %q: use an http or https url", args[1])
+		}
+		sink.Target = u.String()
+
+	case sinkEmail:
+		if len(args) != 2 {
+			return errors.New(notifyUsage)
+		}
+		address, err := mail.ParseAddress(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid email address %q: %w", args[1], err)
+		}
+		sink.Target = address.Address
+		if s.Config.SMTP == nil {
+			fmt.Println("⚠️ no smtp server is configured yet; add one under \"smtp\" in ~/.gatorconfig.json")
+		}
+
+	case sinkCommand:
+		argv := append(args[1:], commandArgs...)
+		if len(argv) == 0 {
+			return errors.New(notifyUsage)
+		}
+		sink.Target = argv[0]
+		sink.Args = argv[1:]
+
+	default:
+		return fmt.Errorf("unknown sink %q: use webhook, email or command\n%s", sink.Kind, notifyUsage)
+	}
+
+	saved, err := s.DB.CreateNotificationSink(context.Background(), sink)
+	if err != nil {
+		return fmt.Errorf("could not save sink: %w", err)
+	}
+
+	fmt.Printf("✅ Added %s\n", describeSink(saved.Kind, saved.Target, saved.Args))
+	fmt.Printf("Send it a test with: gator notify test %s\n", shortID(saved.ID))
+	fmt.Println("Choose the posts with a rule, e.g.: gator rule add 'title contains go -> notify'")
+	return nil
+}
+
+func listSinks(s *State, user database.User) error {
+	sinks, err := s.DB.GetNotificationSinksForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get sinks: %w", err)
+	}
+
+	if len(sinks) == 0 {
+		fmt.Println("no notification sinks yet, add one with: gator notify add webhook <url>")
+		return nil
+	}
+
+	for _, sink := range sinks {
+		every := "after each cycle"
+		if sink.DigestIntervalSeconds > 0 {
+			every = "every " + (time.Duration(sink.DigestIntervalSeconds) * time.Second).String()
+		}
+		lastSent := "never sent"
+		if sink.LastSentAt.Valid {
+			lastSent = "last sent " + sink.LastSentAt.Time.Format("2 Jan 2006 15:04")
+		}
+		fmt.Printf("🔔 %s %s · %s · %d queued · %s\n", shortID(sink.ID), describeSink(sink.Kind, sink.Target, sink.Args), every, sink.Queued, lastSent)
+		if sink.ErrorCount > 0 {
+			fmt.Printf("   ⚠️ %s failed: %s\n", plural(int(sink.ErrorCount), "time"), sink.LastError.String)
+		}
+	}
+	return nil
+}
+
+func removeSink(s *State, user database.User, ref string) error {
+	ctx := context.Background()
+	sinks, err := s.DB.GetNotificationSinksForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get sinks: %w", err)
+	}
+
+	sink, err := findByID(sinks, func(s database.GetNotificationSinksForUserRow) uuid.UUID { return s.ID }, "sink", ref)
+	if err != nil {
+		return fmt.Errorf("%w, see gator notify list", err)
+	}
+
+	err = s.DB.DeleteNotificationSink(ctx, database.DeleteNotificationSinkParams{ID: sink.ID, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("could not remove sink: %w", err)
+	}
+
+	fmt.Printf("✅ Removed %s\n", describeSink(sink.Kind, sink.Target, sink.Args))
+	return nil
+}
+
+// testSink sends a sample digest straight away. Commands are run even
+// though gator agg only runs them with --notify-commands: here they run as
+// the user who added them.
+func testSink(s *State, user database.User, ref string) error {
+	ctx := context.Background()
+	sinks, err := s.DB.GetNotificationSinksForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get sinks: %w", err)
+	}
+
+	sink, err := findByID(sinks, func(s database.GetNotificationSinksForUserRow) uuid.UUID { return s.ID }, "sink", ref)
+	if err != nil {
+		return fmt.Errorf("%w, see gator notify list", err)
+	}
+
+	n, err := newNotifier(sink.Kind, sink.Target, sink.Args, s.Config.SMTP)
+	if err != nil {
+		return err
+	}
+
+	digest := notify.Digest{Posts: []notify.Post{{
+		Title:     "Test notification from gator",
+		URL:       "https://github.com/ckm54/go-projects/tree/main/gator",
+		Feed:      "gator",
+		Published: time.Now(),
+	}}}
+
+	ctx, cancel := context.WithTimeout(ctx, digestTimeout)
+	defer cancel()
+	if err := notify.Send(ctx, n, digest, notify.DefaultRetry); err != nil {
+		return fmt.Errorf("could not notify %s: %w", describeSink(sink.Kind, sink.Target, sink.Args), err)
+	}
+
+	fmt.Printf("✅ Sent a test notification to %s\n", describeSink(sink.Kind, sink.Target, sink.Args))
+	return nil
+}
+
+func describeSink(kind, target string, args []string) string {
+	if kind == sinkCommand && len(args) > 0 {
+		target += " " + strings.Join(args, " ")
+	}
+	return kind + " " + target
+}
+
+func newNotifier(kind, target string, args []string, smtp *config.SMTPConfig) (notify.Notifier, error) {
+	switch kind {
+	case sinkWebhook:
+		return notify.Webhook{URL: target, Client: &http.Client{Timeout: 30 * time.Second}}, nil
+	case sinkEmail:
+		if smtp == nil {
+			return nil, errors.New("no smtp server is configured in ~/.gatorconfig.json")
+		}
+		return notify.Email{
+			Addr:     smtp.Addr,
+			Username: smtp.Username,
+			Password: smtp.Password,
+			From:     smtp.From,
+			To:       target,
+		}, nil
+	case sinkCommand:
+		return notify.Command{Path: target, Args: args}, nil
+	}
+	return nil, fmt.Errorf("unknown sink %q", kind)
+}
+
+// digestSender sends due digests for the aggregator. Any number of gator
+// processes can share the work: each sink is claimed before its digest is
+// sent.
+type digestSender struct {
+	db    *database.Queries
+	smtp  *config.SMTPConfig
+	retry notify.Retry
+	// commands is whether command sinks are run. Any user can add one, so
+	// the person running the aggregator has to allow them.
+	commands bool
+}
+
+// kinds are the sinks this process can deliver to.
+func (d *digestSender) kinds() []string {
+	kinds := []string{sinkWebhook}
+	if d.smtp != nil {
+		kinds = append(kinds, sinkEmail)
+	}
+	if d.commands {
+		kinds = append(kinds, sinkCommand)
+	}
+	return kinds
+}
+
+// sendDue sends a digest to every sink that is due one, then logs how many
+// went out.
+func (d *digestSender) sendDue(ctx context.Context) {
+	sent, failed := 0, 0
+	for ctx.Err() == nil {
+		sink, err := d.db.ClaimDueNotificationSink(ctx, d.kinds())
+		if errors.Is(err, sql.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("⚠️ could not claim a notification sink: %v", err)
+			}
+			break
+		}
+
+		if err := d.send(ctx, sink); err != nil {
+			failed++
+			if ctx.Err() == nil {
+				log.Printf("⚠️ %s: %v", describeSink(sink.Kind, sink.Target, sink.Args), err)
+			}
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 || failed > 0 {
+		log.Printf("🔔 sent %s, %d failed", plural(sent, "digest"), failed)
+	}
+}
+
+// send delivers the sink's queued posts as one digest. A failed digest is
+// tried again later, backing off like a failing feed.
+func (d *digestSender) send(ctx context.Context, sink database.NotificationSink) error {
+	queued, err := d.db.GetQueuedNotifications(ctx, sink.ID)
+	if err != nil {
+		return fmt.Errorf("could not get queued posts: %w", err)
+	}
+
+	var digest notify.Digest
+	postIDs := make([]uuid.UUID, 0, len(queued))
+	for _, post := range queued {
+		postIDs = append(postIDs, post.ID)
+		if len(digest.Posts) == maxDigestPosts {
+			digest.More++
+			continue
+		}
+		digest.Posts = append(digest.Posts, notify.Post{
+			Title:     post.Title,
+			URL:       post.Url,
+			Feed:      post.FeedName,
+			Published: post.PublishedAt.Time,
+		})
+	}
+
+	n, err := newNotifier(sink.Kind, sink.Target, sink.Args, d.smtp)
+	if err == nil {
+		sendCtx, cancel := context.WithTimeout(ctx, digestTimeout)
+		err = notify.Send(sendCtx, n, digest, d.retry)
+		cancel()
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		errorCount := sink.ErrorCount + 1
+		if errorCount >= maxDigestFailures {
+			// Don't let the queue grow forever behind a broken sink.
+			d.dequeue(ctx, sink.ID, postIDs)
+			err = fmt.Errorf("%w; dropped %s after %d failed digests", err, plural(len(postIDs), "post"), errorCount)
+		}
+		failure := d.db.RecordDigestFailure(ctx, database.RecordDigestFailureParams{
+			ID:           sink.ID,
+			NextDigestAt: time.Now().Add(retryDelay(errorCount)),
+			ErrorCount:   errorCount,
+			LastError:    nullString(err.Error()),
+		})
+		if failure != nil {
+			log.Printf("⚠️ could not record failed digest: %v", failure)
+		}
+		return err
+	}
+
+	d.dequeue(ctx, sink.ID, postIDs)
+	return d.db.RecordDigestSent(ctx, sink.ID)
+}
+
+func (d *digestSender) dequeue(ctx context.Context, sinkID uuid.UUID, postIDs []uuid.UUID) {
+	err := d.db.DeleteQueuedNotifications(ctx, database.DeleteQueuedNotificationsParams{SinkID: sinkID, PostIds: postIDs})
+	if err != nil {
+		log.Printf("⚠️ could not clear sent notifications: %v", err)
+	}
+}
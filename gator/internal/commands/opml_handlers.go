@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/opml"
+	"github.com/ckm54/go-projects/gator/internal/rules"
+	"github.com/google/uuid"
+)
+
+// HandlerImport follows every feed in an OPML file, adding the ones gator
+// doesn't know yet. Folders and tags in the file are kept on the follows.
+func HandlerImport(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) != 1 {
+		return errors.New("usage: gator import <file.opml>")
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("could not open %s: %w", cmd.Args[0], err)
+	}
+	defer file.Close()
+
+	subs, err := opml.Parse(file)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", cmd.Args[0], err)
+	}
+
+	ctx := context.Background()
+	added := 0
+	for _, sub := range subs {
+		feed, err := s.DB.GetFeedByUrl(ctx, sub.URL)
+		if errors.Is(err, sql.ErrNoRows) {
+			feed, err = s.DB.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      sub.Title,
+				Url:       sub.URL,
+				UserID:    user.ID,
+			})
+			added++
+		}
+		if err != nil {
+			return fmt.Errorf("could not add %s: %w", sub.URL, err)
+		}
+
+		_, err = s.DB.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			UserID:    user.ID,
+			FeedID:    feed.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("could not follow %s: %w", feed.Name, err)
+		}
+
+		if folder := normalizeFolder(sub.Category); folder != "" {
+			_, err = s.DB.SetFeedFollowCategory(ctx, database.SetFeedFollowCategoryParams{
+				UserID:   user.ID,
+				FeedID:   feed.ID,
+				Category: sql.NullString{String: folder, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("could not file %s under %s: %w", feed.Name, folder, err)
+			}
+		}
+
+		var tags []string
+		for _, tag := range sub.Tags {
+			// Tags gator can't store, with spaces for instance, are dropped.
+			if tag, err := rules.NormalizeTag(tag); err == nil {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > 0 {
+			_, err = s.DB.AddFeedFollowTags(ctx, database.AddFeedFollowTagsParams{Tags: tags, UserID: user.ID, FeedID: feed.ID})
+			if err != nil {
+				return fmt.Errorf("could not tag %s: %w", feed.Name, err)
+			}
+		}
+	}
+
+	fmt.Printf("✅ %s now follows %d feeds from %s (%d new to gator)\n", user.Name, len(subs), cmd.Args[0], added)
+	return nil
+}
+
+// HandlerExport writes the user's follows as OPML, to a file or stdout.
+func HandlerExport(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) > 1 {
+		return errors.New("usage: gator export [file]")
+	}
+
+	follows, err := s.DB.GetFeedFollowsForExport(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("error getting user feeds: %w", err)
+	}
+
+	subs := make([]opml.Subscription, 0, len(follows))
+	for _, follow := range follows {
+		subs = append(subs, opml.Subscription{
+			Title:    follow.Name,
+			URL:      follow.Url,
+			Category: follow.Category.String,
+			Tags:     follow.Tags,
+		})
+	}
+
+	var out io.Writer = os.Stdout
+	if len(cmd.Args) == 1 {
+		file, err := os.Create(cmd.Args[0])
+		if err != nil {
+			return fmt.Errorf("could not create %s: %w", cmd.Args[0], err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := opml.Write(out, fmt.Sprintf("%s's feeds in gator", user.Name), subs); err != nil {
+		return fmt.Errorf("could not write opml: %w", err)
+	}
+
+	if len(cmd.Args) == 1 {
+		fmt.Printf("✅ exported %d feeds to %s\n", len(subs), cmd.Args[0])
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/plaintext"
+	"github.com/google/uuid"
+)
+
+const (
+	browseUsage = "usage: gator browse [limit] [--unread] [--starred] [--archived] [--feed <url>] [--folder <folder>] [--tag <tag>] [--offset N] [--full]"
+
+	// shortIDLength is how much of a post's UUID browse shows. Any unique
+	// prefix of at least minShortIDLength characters is accepted back.
+	shortIDLength    = 8
+	minShortIDLength = 4
+)
+
+func HandlerBrowse(s *State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("browse", flag.ContinueOnError)
+	unread := flags.Bool("unread", false, "only show unread posts")
+	starred := flags.Bool("starred", false, "only show starred posts")
+	archived := flags.Bool("archived", false, "include archived posts")
+	feedURL := flags.String("feed", "", "only show posts from this feed")
+	folder := flags.String("folder", "", "only show posts from feeds in this folder")
+	tag := flags.String("tag", "", "only show posts with this tag, or from feeds with it")
+	offset := flags.Int("offset", 0, "skip this many posts")
+	full := flags.Bool("full", false, "show each post's text, in full when the article was saved")
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) > 1 || *offset < 0 {
+		return errors.New(browseUsage)
+	}
+
+	limit := 2
+	if len(args) == 1 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed < 1 {
+			return fmt.Errorf("invalid limit\n%s", browseUsage)
+		}
+
+		limit = parsed
+	}
+
+	params := database.GetPostsForUserParams{
+		UserID:          user.ID,
+		UnreadOnly:      *unread,
+		StarredOnly:     *starred,
+		IncludeArchived: *archived,
+		Folder:          nullString(normalizeFolder(*folder)),
+		Tag:             nullString(strings.ToLower(strings.TrimPrefix(*tag, "#"))),
+		RowLimit:        int32(limit),
+		RowOffset:       int32(*offset),
+	}
+	if *feedURL != "" {
+		feed, err := getFeedByUrl(s, *feedURL)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	posts, err := s.DB.GetPostsForUser(context.Background(), params)
+	if err != nil {
+		return err
+	}
+
+	if len(posts) == 0 {
+		if *unread || *starred || *feedURL != "" || *folder != "" || *tag != "" || *offset > 0 {
+			fmt.Println("no matching posts")
+		} else {
+			fmt.Println("no posts yet - try running gator agg 1m")
+		}
+		return nil
+	}
+
+	for _, post := range posts {
+		status := "unread"
+		if post.IsRead {
+			status = "read"
+		}
+		if post.IsStarred {
+			status += " · ⭐ starred"
+		}
+		if post.ContentText.Valid {
+			status += " · 📄 saved"
+		}
+		if len(post.Tags) > 0 {
+			status += " · 🏷️ " + strings.Join(post.Tags, ", ")
+		}
+		fmt.Printf("\n📌 %s\n🔗 %s\n📰 %s\n🆔 %s · %s\n", post.FeedName, post.Url, post.Title, shortID(post.ID), status)
+
+		if *full {
+			text := post.ContentText.String
+			if text == "" {
+				text = plaintext.FromHTML(post.Description.String)
+			}
+			if text != "" {
+				fmt.Printf("\n%s\n", text)
+			}
+		}
+	}
+
+	if len(posts) == limit {
+		fmt.Printf("\nmore with --offset %d\n", *offset+limit)
+	}
+
+	return nil
+}
+
+func HandlerRead(s *State, cmd Command, user database.User) error {
+	return setPostState(s, cmd, user, "read", func(ctx context.Context, postID uuid.UUID) error {
+		return s.DB.SetPostRead(ctx, database.SetPostReadParams{UserID: user.ID, PostID: postID, Read: true})
+	})
+}
+
+func HandlerUnread(s *State, cmd Command, user database.User) error {
+	return setPostState(s, cmd, user, "unread", func(ctx context.Context, postID uuid.UUID) error {
+		return s.DB.SetPostRead(ctx, database.SetPostReadParams{UserID: user.ID, PostID: postID, Read: false})
+	})
+}
+
+func HandlerStar(s *State, cmd Command, user database.User) error {
+	return setPostState(s, cmd, user, "starred", func(ctx context.Context, postID uuid.UUID) error {
+		return s.DB.SetPostStarred(ctx, database.SetPostStarredParams{UserID: user.ID, PostID: postID, Starred: true})
+	})
+}
+
+func HandlerUnstar(s *State, cmd Command, user database.User) error {
+	return setPostState(s, cmd, user, "unstarred", func(ctx context.Context, postID uuid.UUID) error {
+		return s.DB.SetPostStarred(ctx, database.SetPostStarredParams{UserID: user.ID, PostID: postID, Starred: false})
+	})
+}
+
+func HandlerArchive(s *State, cmd Command, user database.User) error {
+	return setPostState(s, cmd, user, "archived", func(ctx context.Context, postID uuid.UUID) error {
+		return s.DB.SetPostArchived(ctx, database.SetPostArchivedParams{UserID: user.ID, PostID: postID, Archived: true})
+	})
+}
+
+// setPostState applies set to every post named in cmd.Args.
+func setPostState(s *State, cmd Command, user database.User, state string, set func(context.Context, uuid.UUID) error) error {
+	if len(cmd.Args) == 0 {
+		return fmt.Errorf("missing post id.\nusage: gator %s <post id>...", cmd.Name)
+	}
+
+	ctx := context.Background()
+	for _, ref := range cmd.Args {
+		post, err := findPost(ctx, s, user, ref)
+		if err != nil {
+			return err
+		}
+
+		if err := set(ctx, post.ID); err != nil {
+			return fmt.Errorf("could not update %s: %w", ref, err)
+		}
+		fmt.Printf("✅ %s: %s\n", state, post.Title)
+	}
+	return nil
+}
+
+// findPost resolves a short ID, or any unique prefix of a post's UUID,
+// among the posts of feeds the user follows.
+func findPost(ctx context.Context, s *State, user database.User, ref string) (database.FindPostsForUserByPrefixRow, error) {
+	prefix := strings.ToLower(strings.TrimSpace(ref))
+	if len(prefix) < minShortIDLength || strings.Trim(prefix, "0123456789abcdef-") != "" {
+		return database.FindPostsForUserByPrefixRow{}, fmt.Errorf("invalid post id %q: use the id shown by gator browse", ref)
+	}
+
+	posts, err := s.DB.FindPostsForUserByPrefix(ctx, database.FindPostsForUserByPrefixParams{UserID: user.ID, Prefix: prefix})
+	if err != nil {
+		return database.FindPostsForUserByPrefixRow{}, fmt.Errorf("error finding post: %w", err)
+	}
+
+	switch len(posts) {
+	case 0:
+		return database.FindPostsForUserByPrefixRow{}, fmt.Errorf("no post with id %s", ref)
+	case 1:
+		return posts[0], nil
+	default:
+		return database.FindPostsForUserByPrefixRow{}, fmt.Errorf("post id %s is ambiguous, type more of it", ref)
+	}
+}
+
+func shortID(id uuid.UUID) string {
+	return id.String()[:shortIDLength]
+}
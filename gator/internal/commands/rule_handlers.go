@@ -0,0 +1,303 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/feed"
+	"github.com/ckm54/go-projects/gator/internal/plaintext"
+	"github.com/ckm54/go-projects/gator/internal/rules"
+	"github.com/google/uuid"
+)
+
+const ruleUsage = `usage: gator rule add '<condition> -> <action>'
+       gator rule list
+       gator rule remove <id>
+       gator rule test ['<condition> -> <action>'] [--limit N]`
+
+// HandlerRule manages the rules applied to new posts as they are fetched.
+func HandlerRule(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New(ruleUsage)
+	}
+
+	switch cmd.Args[0] {
+	case "add":
+		if len(cmd.Args) < 2 {
+			return errors.New(ruleUsage)
+		}
+		return addRule(s, user, strings.Join(cmd.Args[1:], " "))
+	case "list":
+		if len(cmd.Args) != 1 {
+			return errors.New(ruleUsage)
+		}
+		return listRules(s, user)
+	case "remove":
+		if len(cmd.Args) != 2 {
+			return errors.New(ruleUsage)
+		}
+		return removeRule(s, user, cmd.Args[1])
+	case "test":
+		return testRules(s, user, cmd.Args[1:])
+	default:
+		return errors.New(ruleUsage)
+	}
+}
+
+func addRule(s *State, user database.User, src string) error {
+	rule, err := rules.ParseRule(src)
+	if err != nil {
+		return fmt.Errorf("invalid rule: %w", err)
+	}
+
+	saved, err := s.DB.CreateRule(context.Background(), database.CreateRuleParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Condition: rule.Condition.String(),
+		Action:    rule.Action.Kind,
+		Tag:       nullString(rule.Action.Tag),
+	})
+	if err != nil {
+		return fmt.Errorf("could not save rule: %w", err)
+	}
+
+	fmt.Printf("✅ Added rule %s: %s → %s\n", shortID(saved.ID), rule.Condition, rule.Action)
+	fmt.Println("Try it on the posts you already have with: gator rule test")
+	return nil
+}
+
+func listRules(s *State, user database.User) error {
+	saved, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get rules: %w", err)
+	}
+
+	if len(saved) == 0 {
+		fmt.Println("no rules yet, add one with: gator rule add 'title contains go -> star'")
+		return nil
+	}
+
+	for _, rule := range saved {
+		fmt.Printf("📏 %s %s → %s\n", shortID(rule.ID), rule.Condition, storedAction(rule))
+	}
+	return nil
+}
+
+func removeRule(s *State, user database.User, ref string) error {
+	ctx := context.Background()
+	saved, err := s.DB.GetRulesForUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get rules: %w", err)
+	}
+
+	rule, err := findByID(saved, func(r database.Rule) uuid.UUID { return r.ID }, "rule", ref)
+	if err != nil {
+		return fmt.Errorf("%w, see gator rule list", err)
+	}
+
+	err = s.DB.DeleteRule(ctx, database.DeleteRuleParams{ID: rule.ID, UserID: user.ID})
+	if err != nil {
+		return fmt.Errorf("could not remove rule: %w", err)
+	}
+
+	fmt.Printf("✅ Removed rule %s: %s → %s\n", shortID(rule.ID), rule.Condition, storedAction(rule))
+	return nil
+}
+
+// findByID picks the item whose UUID starts with ref, the short id list
+// commands show. what names the kind of item in errors.
+func findByID[T any](items []T, id func(T) uuid.UUID, what, ref string) (T, error) {
+	prefix := strings.ToLower(strings.TrimSpace(ref))
+
+	var found []T
+	for _, item := range items {
+		if prefix != "" && strings.HasPrefix(id(item).String(), prefix) {
+			found = append(found, item)
+		}
+	}
+
+	var zero T
+	switch len(found) {
+	case 0:
+		return zero, fmt.Errorf("no %s with id %s", what, ref)
+	case 1:
+		return found[0], nil
+	default:
+		return zero, fmt.Errorf("%s id %s is ambiguous, type more of it", what, ref)
+	}
+}
+
+// testRules is a dry run: it shows what a rule, or all of the user's
+// rules, would do to their latest posts without changing anything.
+func testRules(s *State, user database.User, args []string) error {
+	flags := flag.NewFlagSet("rule test", flag.ContinueOnError)
+	limit := flags.Int("limit", 200, "number of recent posts to try the rules on")
+	args, err := parseFlags(flags, args)
+	if err != nil || *limit < 1 {
+		return errors.New(ruleUsage)
+	}
+
+	ctx := context.Background()
+
+	var toTest []rules.Rule
+	if len(args) > 0 {
+		rule, err := rules.ParseRule(strings.Join(args, " "))
+		if err != nil {
+			return fmt.Errorf("invalid rule: %w", err)
+		}
+		toTest = append(toTest, rule)
+	} else {
+		saved, err := s.DB.GetRulesForUser(ctx, user.ID)
+		if err != nil {
+			return fmt.Errorf("could not get rules: %w", err)
+		}
+		if len(saved) == 0 {
+			return errors.New("no rules to test, pass one or add one with gator rule add")
+		}
+		for _, stored := range saved {
+			rule, err := parseStoredRule(stored)
+			if err != nil {
+				fmt.Printf("⚠️ skipping rule %s: %v\n", shortID(stored.ID), err)
+				continue
+			}
+			toTest = append(toTest, rule)
+		}
+	}
+
+	posts, err := s.DB.GetPostsForRuleTest(ctx, database.GetPostsForRuleTestParams{UserID: user.ID, RowLimit: int32(*limit)})
+	if err != nil {
+		return fmt.Errorf("could not get posts: %w", err)
+	}
+
+	matched := 0
+	for _, post := range posts {
+		content := post.ContentText.String
+		if content == "" {
+			content = plaintext.FromHTML(post.Description.String)
+		}
+		candidate := rules.Post{
+			Title:       post.Title,
+			Description: plaintext.FromHTML(post.Description.String),
+			Content:     content,
+			URL:         post.Url,
+			Author:      post.Author.String,
+			Feed:        post.FeedName,
+			FeedURL:     post.FeedUrl,
+			Categories:  post.Categories,
+		}
+
+		var actions []string
+		for _, rule := range toTest {
+			if rule.Condition.Match(candidate) {
+				actions = append(actions, rule.Action.String())
+			}
+		}
+		if len(actions) == 0 {
+			continue
+		}
+
+		matched++
+		fmt.Printf("📌 %s %s (%s)\n", shortID(post.ID), post.Title, post.FeedName)
+		fmt.Printf("   → %s\n", strings.Join(actions, ", "))
+	}
+
+	fmt.Printf("🧪 %d of the latest %d posts match; nothing was changed\n", matched, len(posts))
+	return nil
+}
+
+// parseStoredRule turns a saved rule back into one that can be matched.
+func parseStoredRule(stored database.Rule) (rules.Rule, error) {
+	condition, err := rules.ParseCondition(stored.Condition)
+	if err != nil {
+		return rules.Rule{}, err
+	}
+	action, err := rules.ParseAction(storedAction(stored))
+	if err != nil {
+		return rules.Rule{}, err
+	}
+	return rules.Rule{Condition: condition, Action: action}, nil
+}
+
+func storedAction(stored database.Rule) string {
+	return rules.Action{Kind: stored.Action, Tag: stored.Tag.String}.String()
+}
+
+// userRule is a rule along with the user it belongs to.
+type userRule struct {
+	userID uuid.UUID
+	rule   rules.Rule
+}
+
+// loadFeedRules returns the rules of everyone following feed. A rule that no
+// longer parses is skipped rather than failing the whole feed.
+func loadFeedRules(ctx context.Context, db *database.Queries, feed database.Feed) []userRule {
+	saved, err := db.GetRulesForFeed(ctx, feed.ID)
+	if err != nil {
+		log.Printf("⚠️ %s: could not load rules: %v", feed.Name, err)
+		return nil
+	}
+
+	loaded := make([]userRule, 0, len(saved))
+	for _, stored := range saved {
+		rule, err := parseStoredRule(stored)
+		if err != nil {
+			log.Printf("⚠️ skipping rule %s: %v", shortID(stored.ID), err)
+			continue
+		}
+		loaded = append(loaded, userRule{userID: stored.UserID, rule: rule})
+	}
+	return loaded
+}
+
+// applyRules runs each matching rule's action on a newly stored post.
+func applyRules(ctx context.Context, db *database.Queries, feedRules []userRule, postID uuid.UUID, post rules.Post) {
+	for _, r := range feedRules {
+		if !r.rule.Condition.Match(post) {
+			continue
+		}
+		if err := applyAction(ctx, db, r.userID, postID, r.rule.Action); err != nil {
+			log.Printf("⚠️ could not %s %s: %v", r.rule.Action, post.Title, err)
+		}
+	}
+}
+
+func applyAction(ctx context.Context, db *database.Queries, userID, postID uuid.UUID, action rules.Action) error {
+	switch action.Kind {
+	case rules.ActionStar:
+		return db.SetPostStarred(ctx, database.SetPostStarredParams{UserID: userID, PostID: postID, Starred: true})
+	case rules.ActionRead:
+		return db.SetPostRead(ctx, database.SetPostReadParams{UserID: userID, PostID: postID, Read: true})
+	case rules.ActionArchive:
+		return db.SetPostArchived(ctx, database.SetPostArchivedParams{UserID: userID, PostID: postID, Archived: true})
+	case rules.ActionTag:
+		return db.TagPost(ctx, database.TagPostParams{UserID: userID, PostID: postID, Tag: action.Tag})
+	case rules.ActionNotify:
+		return db.QueueNotification(ctx, database.QueueNotificationParams{PostID: postID, UserID: userID})
+	}
+	return fmt.Errorf("unknown action %q", action.Kind)
+}
+
+// rulePost is what rules see of a feed item.
+func rulePost(item feed.Item, f database.Feed) rules.Post {
+	content := item.Content
+	if content == "" {
+		content = item.Description
+	}
+	return rules.Post{
+		Title:       item.Title,
+		Description: plaintext.FromHTML(item.Description),
+		Content:     plaintext.FromHTML(content),
+		URL:         item.Link,
+		Author:      item.Author,
+		Feed:        f.Name,
+		FeedURL:     f.Url,
+		Categories:  item.Categories,
+	}
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/feed"
+)
+
+const (
+	minFetchInterval = 5 * time.Minute
+	maxFetchInterval = 24 * time.Hour
+
+	// scheduleSample is how many of a feed's newest posts are used to
+	// estimate how often it publishes.
+	scheduleSample = 10
+)
+
+// nextFetchInterval picks how long to wait before fetching a feed again.
+// The aim is to poll about twice per expected new post: a news feed
+// publishing every few minutes is fetched often, a blog that posts monthly
+// or has gone quiet only once a day. The publisher's own <ttl> or
+// sy:updatePeriod is a lower bound.
+//
+// fetched is nil when the feed wasn't modified, in which case the previous
+// interval is stretched a little.
+func nextFetchInterval(previous time.Duration, fetched *feed.Feed, now time.Time) time.Duration {
+	if previous <= 0 {
+		previous = time.Hour
+	}
+	if fetched == nil {
+		return clampInterval(previous * 5 / 4)
+	}
+
+	interval := previous
+	if gap, ok := postingGap(fetched.Items, now); ok {
+		interval = gap / 2
+	}
+	interval = max(interval, fetched.UpdateInterval)
+
+	return clampInterval(interval)
+}
+
+// postingGap estimates the time between posts from the newest dated items.
+// A feed that has been silent for longer than its usual gap counts as
+// posting at most that rarely.
+func postingGap(items []feed.Item, now time.Time) (time.Duration, bool) {
+	var dates []time.Time
+	for _, item := range items {
+		// Dates in the future are scheduled posts or broken clocks.
+		if !item.Published.IsZero() && !item.Published.After(now) {
+			dates = append(dates, item.Published)
+		}
+	}
+	if len(dates) == 0 {
+		return 0, false
+	}
+
+	slices.SortFunc(dates, func(a, b time.Time) int { return b.Compare(a) })
+	dates = dates[:min(len(dates), scheduleSample)]
+
+	newest := dates[0]
+	silence := now.Sub(newest)
+	if len(dates) == 1 {
+		return silence, true
+	}
+
+	gap := newest.Sub(dates[len(dates)-1]) / time.Duration(len(dates)-1)
+	return max(gap, silence), true
+}
+
+func clampInterval(d time.Duration) time.Duration {
+	return min(max(d, minFetchInterval), maxFetchInterval)
+}
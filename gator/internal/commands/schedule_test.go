@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/feed"
+)
+
+func postsEvery(now time.Time, gap time.Duration, n int) []feed.Item {
+	items := make([]feed.Item, n)
+	for i := range items {
+		items[i].Published = now.Add(-time.Duration(i+1) * gap)
+	}
+	return items
+}
+
+func TestNextFetchInterval(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		previous time.Duration
+		fetched  *feed.Feed
+		want     time.Duration
+	}{
+		{
+			name:     "busy feed is polled twice per post",
+			previous: time.Hour,
+			fetched:  &feed.Feed{Items: postsEvery(now, 20*time.Minute, 10)},
+			want:     10 * time.Minute,
+		},
+		{
+			name:     "very busy feed is capped at the minimum",
+			previous: time.Hour,
+			fetched:  &feed.Feed{Items: postsEvery(now, time.Minute, 10)},
+			want:     minFetchInterval,
+		},
+		{
+			name:     "ttl is a lower bound",
+			previous: time.Hour,
+			fetched:  &feed.Feed{UpdateInterval: time.Hour, Items: postsEvery(now, 20*time.Minute, 10)},
+			want:     time.Hour,
+		},
+		{
+			name:     "dormant blog is polled rarely",
+			previous: time.Hour,
+			fetched:  &feed.Feed{Items: postsEvery(now.AddDate(0, -6, 0), 24*time.Hour, 5)},
+			want:     maxFetchInterval,
+		},
+		{
+			name:     "undated feed keeps its interval",
+			previous: 2 * time.Hour,
+			fetched:  &feed.Feed{Items: []feed.Item{{Title: "no date"}}},
+			want:     2 * time.Hour,
+		},
+		{
+			name:     "not modified stretches the interval",
+			previous: 4 * time.Hour,
+			want:     5 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextFetchInterval(tt.previous, tt.fetched, now); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
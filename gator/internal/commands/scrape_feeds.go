@@ -3,54 +3,166 @@ package commands
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"html"
 	"log"
 	"time"
 
 	"github.com/ckm54/go-projects/gator/internal/database"
-	"github.com/google/uuid"
+	"github.com/ckm54/go-projects/gator/internal/feed"
 )
 
-func scrapeFeeds(s *State) error {
-	ctx := context.Background()
+const (
+	// Failing feeds are retried after retryBase, doubling with every
+	// consecutive failure up to retryMax.
+	retryBase = time.Minute
+	retryMax  = 24 * time.Hour
+)
+
+// scrapeFeed fetches a claimed feed and stores its new posts, returning how
+// many there were. The outcome of the fetch is recorded on the feed either
+// way, so the next fetch can be conditional and failing feeds back off. The
+// new validators are only recorded once every item is stored.
+func scrapeFeed(ctx context.Context, s *State, f *fetcher, feed database.Feed) (int, error) {
+	fmt.Printf("🪐 fetching %s (%s)\n", feed.Name, feed.Url)
 
-	feed, err := s.DB.GetNextFeedToFetch(ctx)
+	result, err := f.fetch(ctx, feed.Url, feed.Etag.String, feed.LastModified.String)
 	if err != nil {
-		return fmt.Errorf("no feeds to fetch: %w", err)
+		if ctx.Err() == nil {
+			recordFetchFailure(ctx, s, feed, result.Status, err)
+		}
+		return 0, fmt.Errorf("failed fetching feed url: %w", err)
 	}
 
-	fmt.Printf("🪐 fetching %s (%s)\n", feed.Name, feed.Url)
-	if err = s.DB.MarkFeedFetched(ctx, feed.ID); err != nil {
-		return err
+	if result.MovedTo != "" {
+		err := s.DB.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{ID: feed.ID, Url: result.MovedTo})
+		if err != nil {
+			log.Printf("⚠️ %s moved to %s but the url could not be updated: %v", feed.Name, result.MovedTo, err)
+		} else {
+			log.Printf("↪️ %s moved permanently to %s", feed.Name, result.MovedTo)
+		}
 	}
 
-	rss, err := fetchFeed(ctx, feed.Url)
+	newPosts := 0
+	if result.Feed != nil {
+		newPosts, err = storePosts(ctx, s, feed, result.Feed.Items)
+		if err != nil {
+			// The new validators aren't recorded, so the next fetch gets the
+			// whole feed again instead of a 304 that skips what wasn't stored.
+			if ctx.Err() == nil {
+				recordFetchFailure(ctx, s, feed, result.Status, err)
+			}
+			return newPosts, fmt.Errorf("failed saving post: %w", err)
+		}
+	}
+
+	now := time.Now()
+	interval := nextFetchInterval(fetchInterval(feed), result.Feed, now)
+
+	err = s.DB.RecordFeedFetch(ctx, database.RecordFeedFetchParams{
+		ID:                   feed.ID,
+		Etag:                 nullString(result.ETag),
+		LastModified:         nullString(result.LastModified),
+		LastStatus:           sql.NullInt32{Int32: int32(result.Status), Valid: true},
+		NextFetchAt:          sql.NullTime{Time: now.Add(interval), Valid: true},
+		Active:               true,
+		FetchIntervalSeconds: int32(interval / time.Second),
+	})
 	if err != nil {
-		return fmt.Errorf("failed fetching feed url: %w", err)
-	}
-
-	for _, item := range rss.Channel.Item {
-		publishedAt := parsePublishedTime(item.PubDate)
-		_, err := s.DB.CreatePost(context.Background(), database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       html.UnescapeString(item.Title),
-			Url:         item.Link,
-			Description: sql.NullString{String: html.UnescapeString(item.Description)},
-			PublishedAt: publishedAt,
-			FeedID:      feed.ID,
-		})
+		return newPosts, fmt.Errorf("failed recording fetch: %w", err)
+	}
+
+	if result.Feed == nil {
+		log.Printf("💤 %s not modified, next fetch in %s", feed.Name, interval)
+		return 0, nil
+	}
+	log.Printf("✅ fetched %s: %d new posts, next fetch in %s", feed.Name, newPosts, interval)
 
+	return newPosts, nil
+}
+
+// storePosts stores the items of a fetched feed and applies the feed's
+// rules to the new ones, returning how many were new.
+func storePosts(ctx context.Context, s *State, feed database.Feed, items []feed.Item) (int, error) {
+	guids := make([]string, 0, len(items))
+	for _, item := range items {
+		guids = append(guids, item.GUID)
+	}
+
+	newPosts := 0
+	// Rules are only loaded once the feed turns out to have new posts.
+	var feedRules []userRule
+	rulesLoaded := false
+	for _, item := range items {
+		if item.Link == "" {
+			continue
+		}
+
+		postID, isNew, err := storePost(ctx, s.DB, feed.ID, item, guids)
 		if err != nil {
-			if isUniqueValidation(err) {
-				continue
-			}
-			return fmt.Errorf("failed saving post: %w", err)
+			return newPosts, err
+		}
+		if !isNew {
+			continue
+		}
+		newPosts++
+
+		if !rulesLoaded {
+			feedRules = loadFeedRules(ctx, s.DB, feed)
+			rulesLoaded = true
 		}
+		applyRules(ctx, s.DB, feedRules, postID, rulePost(item, feed))
+	}
+	return newPosts, nil
+}
+
+// recordFetchFailure bumps the feed's error count and pushes its next fetch
+// back, never sooner than its usual interval. A 410 Gone deactivates the
+// feed so it is never claimed again.
+func recordFetchFailure(ctx context.Context, s *State, feed database.Feed, status int, fetchErr error) {
+	errorCount := feed.ErrorCount + 1
+	delay := max(retryDelay(errorCount), fetchInterval(feed))
+
+	var statusErr *statusError
+	if errors.As(fetchErr, &statusErr) && statusErr.RetryAfter > delay {
+		delay = min(statusErr.RetryAfter, retryMax)
+	}
+
+	active := !errors.Is(fetchErr, errFeedGone)
+	if !active {
+		log.Printf("🪦 %s is gone, it will no longer be fetched", feed.Name)
+	}
+
+	err := s.DB.RecordFeedFetch(ctx, database.RecordFeedFetchParams{
+		ID:                   feed.ID,
+		Etag:                 feed.Etag,
+		LastModified:         feed.LastModified,
+		LastStatus:           sql.NullInt32{Int32: int32(status), Valid: status != 0},
+		ErrorCount:           errorCount,
+		LastError:            nullString(fetchErr.Error()),
+		NextFetchAt:          sql.NullTime{Time: time.Now().Add(delay), Valid: true},
+		Active:               active,
+		FetchIntervalSeconds: feed.FetchIntervalSeconds,
+	})
+	if err != nil {
+		log.Printf("⚠️ %s: failed recording fetch error: %v", feed.Name, err)
+	}
+}
+
+// retryDelay is the exponential backoff after errorCount consecutive
+// failures.
+func retryDelay(errorCount int32) time.Duration {
+	delay := retryBase
+	for i := int32(1); i < errorCount && delay < retryMax; i++ {
+		delay *= 2
 	}
-	log.Printf("✅ fetched and stored posts from %s", feed.Name)
+	return min(delay, retryMax)
+}
+
+func fetchInterval(feed database.Feed) time.Duration {
+	return time.Duration(feed.FetchIntervalSeconds) * time.Second
+}
 
-	return nil
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
 }
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+)
+
+// Test that a feed whose items couldn't all be stored keeps its old
+// validators, so the next fetch isn't answered with a 304.
+func TestScrapeFeed_StorageFailureKeepsValidators(t *testing.T) {
+	// Not in a transaction: the failed insert would abort it.
+	db := testDB(t)
+	q := database.New(db)
+	ctx := context.Background()
+
+	// Postgres refuses NUL bytes in text, so this item can't be stored.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/feed+json")
+		w.Header().Set("ETag", `"v2"`)
+		w.Write([]byte(`{"version":"https://jsonfeed.org/version/1.1","title":"Test","items":[
+			{"id":"1","url":"https://example.com/nul","title":"bad \u0000 title"}]}`))
+	}))
+	defer srv.Close()
+
+	f := createTestFeed(t, q)
+	t.Cleanup(func() { db.Exec("DELETE FROM users WHERE id = $1", f.UserID) })
+	if err := q.UpdateFeedUrl(ctx, database.UpdateFeedUrlParams{ID: f.ID, Url: srv.URL}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := q.RecordFeedFetch(ctx, database.RecordFeedFetchParams{
+		ID:                   f.ID,
+		Etag:                 sql.NullString{String: `"v1"`, Valid: true},
+		LastStatus:           sql.NullInt32{Int32: http.StatusOK, Valid: true},
+		NextFetchAt:          sql.NullTime{Time: time.Now(), Valid: true},
+		Active:               true,
+		FetchIntervalSeconds: f.FetchIntervalSeconds,
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f, err := q.GetFeedById(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := scrapeFeed(ctx, &State{DB: q}, newFetcher(time.Second, allowAll), f); err == nil {
+		t.Fatalf("expected the item to fail to store")
+	}
+
+	got, err := q.GetFeedById(ctx, f.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Etag.String != `"v1"` || got.ErrorCount != 1 {
+		t.Fatalf("expected the old etag and one error, got %s and %d", got.Etag.String, got.ErrorCount)
+	}
+}
@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/plaintext"
+	"github.com/google/uuid"
+)
+
+const searchUsage = `usage: gator search <query> [--feed <url>] [--since DATE] [--until DATE] [--sort rank|date] [--limit N] [--offset N]
+the query supports "quoted phrases", OR and -excluded words; dates are YYYY-MM-DD`
+
+func HandlerSearch(s *State, cmd Command, user database.User) error {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	feedURL := flags.String("feed", "", "only search posts from this feed")
+	since := flags.String("since", "", "only posts published on or after this date")
+	until := flags.String("until", "", "only posts published on or before this date")
+	sortBy := flags.String("sort", "rank", "order results by rank or date")
+	limit := flags.Int("limit", 10, "number of results")
+	offset := flags.Int("offset", 0, "skip this many results")
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) == 0 || *limit < 1 || *offset < 0 {
+		return errors.New(searchUsage)
+	}
+	if *sortBy != "rank" && *sortBy != "date" {
+		return fmt.Errorf("--sort must be rank or date\n%s", searchUsage)
+	}
+
+	params := database.SearchPostsParams{
+		Query:     strings.Join(args, " "),
+		UserID:    user.ID,
+		ByDate:    *sortBy == "date",
+		RowLimit:  int32(*limit),
+		RowOffset: int32(*offset),
+	}
+
+	if params.Since, err = parseSearchDate(*since, false); err != nil {
+		return fmt.Errorf("invalid --since: %w\n%s", err, searchUsage)
+	}
+	if params.Until, err = parseSearchDate(*until, true); err != nil {
+		return fmt.Errorf("invalid --until: %w\n%s", err, searchUsage)
+	}
+
+	if *feedURL != "" {
+		feed, err := getFeedByUrl(s, *feedURL)
+		if err != nil {
+			return err
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+
+	results, err := s.DB.SearchPosts(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("could not search posts: %w", err)
+	}
+
+	if len(results) == 0 {
+		fmt.Printf("no posts match %q\n", params.Query)
+		return nil
+	}
+
+	for _, result := range results {
+		published := ""
+		if result.PublishedAt.Valid {
+			published = " · " + result.PublishedAt.Time.Format("2 Jan 2006")
+		}
+
+		fmt.Printf("\n📰 %s\n📌 %s%s\n🔗 %s\n🆔 %s\n", result.Title, result.FeedName, published, result.Url, shortID(result.ID))
+		if snippet := strings.Join(strings.Fields(plaintext.FromHTML(result.Snippet)), " "); snippet != "" {
+			fmt.Printf("   %s\n", snippet)
+		}
+	}
+
+	if len(results) == *limit {
+		fmt.Printf("\nmore with --offset %d\n", *offset+*limit)
+	}
+
+	return nil
+}
+
+// parseSearchDate parses a YYYY-MM-DD or RFC 3339 date. A plain date used
+// as an upper bound covers the whole of that day.
+func parseSearchDate(value string, endOfDay bool) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("%q is not a date", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSearchDate(t *testing.T) {
+	tests := []struct {
+		value    string
+		endOfDay bool
+		want     time.Time
+		valid    bool
+	}{
+		{"", false, time.Time{}, false},
+		{"2024-03-01", false, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{"2024-03-01", true, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), true},
+		{"2024-03-01T10:00:00+02:00", true, time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSearchDate(tt.value, tt.endOfDay)
+		if err != nil {
+			t.Fatalf("parseSearchDate(%q): unexpected error: %v", tt.value, err)
+		}
+		if got.Valid != tt.valid || !got.Time.Equal(tt.want) {
+			t.Fatalf("parseSearchDate(%q, %v) = %v, want %v", tt.value, tt.endOfDay, got, tt.want)
+		}
+	}
+
+	if _, err := parseSearchDate("last week", false); err == nil {
+		t.Fatalf("expected an error for an invalid date")
+	}
+}
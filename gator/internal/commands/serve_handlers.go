@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/auth"
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/server"
+	"github.com/google/uuid"
+)
+
+const serveUsage = "usage: gator serve [--addr HOST:PORT] [--agg DURATION] [--workers N] [--per-host N] [--timeout D]"
+
+const tokenUsage = `usage: gator token create [name]
+       gator token list
+       gator token revoke <prefix>`
+
+// HandlerServe runs the HTTP API. With --agg it also runs the aggregator in
+// the same process, so a single gator can back a web or mobile reader.
+func HandlerServe(s *State, cmd Command) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flags.String("addr", ":8080", "address to listen on")
+	aggInterval := flags.Duration("agg", 0, "also collect feeds at this interval (0 disables)")
+	opts := addAggregatorFlags(flags)
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) != 0 || *aggInterval < 0 {
+		return errors.New(serveUsage)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	aggDone := make(chan struct{})
+	if *aggInterval > 0 {
+		agg, err := opts.newAggregator(s)
+		if err != nil {
+			return fmt.Errorf("%w\n%s", err, serveUsage)
+		}
+		go func() {
+			defer close(aggDone)
+			agg.run(ctx, *aggInterval)
+		}()
+	} else {
+		close(aggDone)
+	}
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(s.DB).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("🌐 Serving the API on %s\n", *addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		stop()
+		<-aggDone
+		return fmt.Errorf("could not serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("could not shut down cleanly: %w", err)
+	}
+	<-aggDone
+
+	fmt.Println("👋 server stopped")
+	return nil
+}
+
+// HandlerToken manages the API tokens of the logged in user.
+func HandlerToken(s *State, cmd Command, user database.User) error {
+	if len(cmd.Args) == 0 {
+		return errors.New(tokenUsage)
+	}
+
+	switch cmd.Args[0] {
+	case "create":
+		if len(cmd.Args) > 2 {
+			return errors.New(tokenUsage)
+		}
+		name := "default"
+		if len(cmd.Args) == 2 {
+			name = cmd.Args[1]
+		}
+		return createToken(s, user, name)
+	case "list":
+		if len(cmd.Args) != 1 {
+			return errors.New(tokenUsage)
+		}
+		return listTokens(s, user)
+	case "revoke":
+		if len(cmd.Args) != 2 {
+			return errors.New(tokenUsage)
+		}
+		return revokeToken(s, user, cmd.Args[1])
+	default:
+		return errors.New(tokenUsage)
+	}
+}
+
+func createToken(s *State, user database.User, name string) error {
+	token, prefix, hash, err := auth.MakeToken()
+	if err != nil {
+		return fmt.Errorf("could not generate token: %w", err)
+	}
+
+	_, err = s.DB.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    prefix,
+		TokenHash: hash,
+	})
+	if err != nil {
+		return fmt.Errorf("could not save token: %w", err)
+	}
+
+	fmt.Printf("✅ Created token %q for %s\n", name, user.Name)
+	fmt.Printf("🔑 %s\n", token)
+	fmt.Println("⚠️ This is the only time the token is shown; store it somewhere safe.")
+	return nil
+}
+
+func listTokens(s *State, user database.User) error {
+	tokens, err := s.DB.GetAPITokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("could not get tokens: %w", err)
+	}
+
+	if len(tokens) == 0 {
+		fmt.Println("no tokens yet, create one with: gator token create [name]")
+		return nil
+	}
+
+	for _, token := range tokens {
+		lastUsed := "never used"
+		if token.LastUsedAt.Valid {
+			lastUsed = "last used " + token.LastUsedAt.Time.Format("2 Jan 2006 15:04")
+		}
+		fmt.Printf("🔑 %s… %s · created %s · %s\n", token.Prefix, token.Name, token.CreatedAt.Format("2 Jan 2006"), lastUsed)
+	}
+	return nil
+}
+
+func revokeToken(s *State, user database.User, prefix string) error {
+	prefix = strings.TrimSuffix(prefix, "…")
+
+	n, err := s.DB.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{UserID: user.ID, Prefix: prefix})
+	if err != nil {
+		return fmt.Errorf("could not revoke token: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no token %q, see gator token list", prefix)
+	}
+
+	fmt.Printf("✅ Revoked token %s\n", prefix)
+	return nil
+}
@@ -0,0 +1,10 @@
+package commands
+
+import (
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/tui"
+)
+
+func HandlerTUI(s *State, cmd Command, user database.User) error {
+	return tui.Run(s.DB, user)
+}
@@ -1,26 +1,61 @@
 package commands
 
 import (
+	"bufio"
 	"context"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/ckm54/go-projects/gator/internal/auth"
 	"github.com/ckm54/go-projects/gator/internal/database"
 	"github.com/google/uuid"
+	"golang.org/x/term"
 )
 
+// sessionDuration is how long a login lasts before gator asks for the
+// password again.
+const sessionDuration = 30 * 24 * time.Hour
+
+var errBadCredentials = errors.New("invalid username or password")
+
 func HandlerLogin(s *State, cmd Command) error {
 	if len(cmd.Args) == 0 {
 		return fmt.Errorf("the login handler expects a single argument, the username")
 	}
 
 	user, err := s.DB.GetUser(context.Background(), cmd.Args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		return errBadCredentials
+	}
 	if err != nil {
 		return err
 	}
 
-	err = s.Config.SetUser(user.Name)
+	if !user.HashedPassword.Valid {
+		// Accounts made before passwords existed can't be claimed by whoever
+		// logs in first; the database owner sets their password.
+		return fmt.Errorf("%s has no password yet, ask whoever runs gator's database to set one with: gator passwd %s", user.Name, user.Name)
+	}
+
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+	ok, err := auth.CheckPasswordHash(password, user.HashedPassword.String)
 	if err != nil {
+		return fmt.Errorf("could not check password: %w", err)
+	}
+	if !ok {
+		return errBadCredentials
+	}
+
+	if err := startSession(s, user); err != nil {
 		return err
 	}
 
@@ -29,16 +64,41 @@ func HandlerLogin(s *State, cmd Command) error {
 	return nil
 }
 
+func HandlerLogout(s *State, cmd Command) error {
+	if s.Config.SessionToken == "" {
+		return errors.New("not logged in")
+	}
+
+	err := s.DB.DeleteSession(context.Background(), auth.HashToken(s.Config.SessionToken))
+	if err != nil {
+		return fmt.Errorf("could not end session: %w", err)
+	}
+
+	name := s.Config.CurrentUserName
+	if err := s.Config.ClearSession(); err != nil {
+		return fmt.Errorf("could not update config: %w", err)
+	}
+
+	fmt.Printf("👋 logged out %s\n", name)
+	return nil
+}
+
 func HandlerRegister(s *State, cmd Command) error {
 	if len(cmd.Args) == 0 {
 		return fmt.Errorf("missing required argument, the username")
 	}
 
+	hash, err := newPassword()
+	if err != nil {
+		return err
+	}
+
 	userInfo := database.CreateUserParams{
-		ID:        uuid.New(),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		Name:      cmd.Args[0],
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+		Name:           cmd.Args[0],
+		HashedPassword: hash,
 	}
 
 	user, err := s.DB.CreateUser(context.Background(), userInfo)
@@ -46,15 +106,96 @@ func HandlerRegister(s *State, cmd Command) error {
 		return err
 	}
 
-	err = s.Config.SetUser(user.Name)
+	if err := startSession(s, user); err != nil {
+		return err
+	}
+
+	fmt.Printf("user successfuly created: %s\n", user.Name)
+	return nil
+}
+
+// startSession records a new session for user and stores its token in the
+// config file, ending whichever session the file held before.
+func startSession(s *State, user database.User) error {
+	ctx := context.Background()
+
+	token, hash, err := auth.MakeSessionToken()
 	if err != nil {
-		return fmt.Errorf("could not set user: %w", err)
+		return fmt.Errorf("could not create session: %w", err)
 	}
 
-	fmt.Printf("user successfuly created: %v\n", user)
+	session, err := s.DB.CreateSession(ctx, database.CreateSessionParams{
+		TokenHash: hash,
+		CreatedAt: time.Now(),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(sessionDuration),
+	})
+	if err != nil {
+		return fmt.Errorf("could not create session: %w", err)
+	}
+
+	if s.Config.SessionToken != "" {
+		_ = s.DB.DeleteSession(ctx, auth.HashToken(s.Config.SessionToken))
+	}
+	_ = s.DB.DeleteExpiredSessions(ctx)
+
+	if err := s.Config.SetSession(user.Name, token, session.ExpiresAt); err != nil {
+		return fmt.Errorf("could not update config: %w", err)
+	}
 	return nil
 }
 
+// newPassword asks for a new password, twice when typed at a terminal, and
+// returns its hash.
+func newPassword() (sql.NullString, error) {
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	if len(password) < auth.MinPasswordLength {
+		return sql.NullString{}, fmt.Errorf("password must be at least %d characters", auth.MinPasswordLength)
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		confirm, err := readPassword("Confirm password: ")
+		if err != nil {
+			return sql.NullString{}, err
+		}
+		if confirm != password {
+			return sql.NullString{}, errors.New("passwords do not match")
+		}
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("could not hash password: %w", err)
+	}
+	return sql.NullString{String: hash, Valid: true}, nil
+}
+
+var stdin = bufio.NewReader(os.Stdin)
+
+// readPassword reads a password without echoing it. When stdin isn't a
+// terminal it reads a line instead, so scripts can pipe the password in.
+func readPassword(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdin.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			return "", fmt.Errorf("could not read password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	fmt.Print(prompt)
+	password, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("could not read password: %w", err)
+	}
+	return string(password), nil
+}
+
 func HandlerGetUsers(s *State, cmd Command) error {
 	users, err := s.DB.GetUsers(context.Background())
 	if err != nil {
@@ -72,7 +213,72 @@ func HandlerGetUsers(s *State, cmd Command) error {
 	return nil
 }
 
+const (
+	passwdUsage = "usage: gator passwd <username>"
+	resetUsage  = "usage: gator reset --yes (deletes every user and everything they own)"
+)
+
+var errNotDBOwner = errors.New("only the owner of gator's database can do this, connect as the role that ran the migrations")
+
+// requireDBOwner refuses commands that act on other people's accounts unless
+// the database role gator connects as owns the users table.
+func requireDBOwner(s *State) error {
+	owner, err := s.DB.OwnsUsersTable(context.Background())
+	if err != nil {
+		return fmt.Errorf("could not check database privileges: %w", err)
+	}
+	if !owner {
+		return errNotDBOwner
+	}
+	return nil
+}
+
+// HandlerPasswd sets a user's password, e.g. for accounts made before gator
+// had passwords or a forgotten one. The user's sessions are ended.
+func HandlerPasswd(s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return errors.New(passwdUsage)
+	}
+	if err := requireDBOwner(s); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	user, err := s.DB.GetUser(ctx, cmd.Args[0])
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no user named %s", cmd.Args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	hash, err := newPassword()
+	if err != nil {
+		return err
+	}
+	err = s.DB.SetUserPassword(ctx, database.SetUserPasswordParams{ID: user.ID, HashedPassword: hash})
+	if err != nil {
+		return fmt.Errorf("could not set password: %w", err)
+	}
+	if err := s.DB.DeleteUserSessions(ctx, user.ID); err != nil {
+		return fmt.Errorf("could not end sessions: %w", err)
+	}
+
+	fmt.Printf("🔑 password set for %s\n", user.Name)
+	return nil
+}
+
 func HandlerReset(s *State, cmd Command) error {
+	flags := flag.NewFlagSet("reset", flag.ContinueOnError)
+	yes := flags.Bool("yes", false, "confirm deleting every user")
+	args, err := parseFlags(flags, cmd.Args)
+	if err != nil || len(args) != 0 || !*yes {
+		return errors.New(resetUsage)
+	}
+	if err := requireDBOwner(s); err != nil {
+		return err
+	}
+
 	if err := s.DB.DeleteUsers(context.Background()); err != nil {
 		return err
 	}
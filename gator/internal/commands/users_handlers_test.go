@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/config"
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+// Test that an account without a password can't be claimed by logging in.
+func TestHandlerLogin_NoPassword(t *testing.T) {
+	tx, q := testTx(t)
+	user, err := q.CreateUser(context.Background(), database.CreateUserParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      "test-" + uuid.NewString(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %v", err)
+	}
+
+	s := &State{DB: q, Config: &config.Config{}}
+	err = HandlerLogin(s, Command{Name: "login", Args: []string{user.Name}})
+	if err == nil || !strings.Contains(err.Error(), "gator passwd "+user.Name) {
+		t.Fatalf("expected to be sent to gator passwd, got %v", err)
+	}
+
+	var sessions int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM sessions WHERE user_id = $1", user.ID).Scan(&sessions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sessions != 0 || s.Config.SessionToken != "" {
+		t.Fatalf("expected no session, got %d", sessions)
+	}
+}
+
+func TestHandlerReset_RequiresConfirmation(t *testing.T) {
+	// No database: the command must give up before touching it.
+	s := &State{}
+	for _, args := range [][]string{nil, {"now"}, {"--yes=false"}} {
+		err := HandlerReset(s, Command{Name: "reset", Args: args})
+		if err == nil || err.Error() != resetUsage {
+			t.Fatalf("expected usage for %v, got %v", args, err)
+		}
+	}
+}
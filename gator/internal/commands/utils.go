@@ -1,33 +1,43 @@
 package commands
 
 import (
-	"database/sql"
 	"errors"
-	"time"
+	"flag"
+	"io"
 
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
 )
 
+// isUniqueValidation reports whether err is a unique constraint violation.
+// gator talks to Postgres through lib/pq, but pgx errors are accepted too.
 func isUniqueValidation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+
 	var pgErr *pgconn.PgError
 	return errors.As(err, &pgErr) && pgErr.Code == "23505"
 }
 
-func parsePublishedTime(input string) sql.NullTime {
-	layouts := []string{
-		time.RFC1123Z,
-		time.RFC1123,
-		time.RFC822Z,
-		time.RFC850,
-		time.RFC3339,
-	}
+// parseFlags parses args with flags, allowing flags to come after
+// positional arguments ("gator agg 1m --workers 8"), and returns the
+// positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	flags.SetOutput(io.Discard)
 
-	for _, layout := range layouts {
-		t, err := time.Parse(layout, input)
-		if err == nil {
-			return sql.NullTime{Time: t, Valid: true}
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
 		}
-	}
 
-	return sql.NullTime{}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
@@ -4,17 +4,41 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const configFileName = ".gatorconfig.json"
 
 type Config struct {
-	DBURL           string `json:"db_url"`
-	CurrentUserName string `json:"current_user_name"`
+	DBURL string `json:"db_url"`
+	// CurrentUserName is only for display; commands authenticate with the
+	// session token.
+	CurrentUserName  string    `json:"current_user_name"`
+	SessionToken     string    `json:"session_token,omitempty"`
+	SessionExpiresAt time.Time `json:"session_expires_at,omitzero"`
+	// SMTP is the server email digests are sent through.
+	SMTP *SMTPConfig `json:"smtp,omitempty"`
 }
 
-func (cfg *Config) SetUser(userName string) error {
+type SMTPConfig struct {
+	// Addr is the server's host:port, such as "smtp.example.com:587".
+	Addr     string `json:"addr"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	From     string `json:"from"`
+}
+
+func (cfg *Config) SetSession(userName, token string, expiresAt time.Time) error {
 	cfg.CurrentUserName = userName
+	cfg.SessionToken = token
+	cfg.SessionExpiresAt = expiresAt
+	return write(*cfg)
+}
+
+func (cfg *Config) ClearSession() error {
+	cfg.CurrentUserName = ""
+	cfg.SessionToken = ""
+	cfg.SessionExpiresAt = time.Time{}
 	return write(*cfg)
 }
 
@@ -55,12 +79,17 @@ func write(cfg Config) error {
 		return err
 	}
 
-	file, err := os.Create(fullPath)
+	// The file holds a session token, so only its owner may read it.
+	file, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
+	if err := file.Chmod(0o600); err != nil {
+		return err
+	}
+
 	encoder := json.NewEncoder(file)
 	err = encoder.Encode(cfg)
 	if err != nil {
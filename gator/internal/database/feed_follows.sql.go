@@ -7,11 +7,34 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const addFeedFollowTags = `-- name: AddFeedFollowTags :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    tags = ARRAY(SELECT DISTINCT unnest(tags || $1::text[]) ORDER BY 1)
+WHERE user_id = $2 AND feed_id = $3
+`
+
+type AddFeedFollowTagsParams struct {
+	Tags   []string
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) AddFeedFollowTags(ctx context.Context, arg AddFeedFollowTagsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addFeedFollowTags, pq.Array(arg.Tags), arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH inserted AS (
   INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
@@ -80,21 +103,29 @@ SELECT
   ff.id,
   ff.created_at,
   ff.updated_at,
+  ff.feed_id,
+  ff.category,
+  ff.tags,
   u.name AS user_name,
-  f.name AS feed_name
+  f.name AS feed_name,
+  f.url AS feed_url
 FROM feed_follows ff
 JOIN users u ON ff.user_id = u.id
 JOIN feeds f ON ff.feed_id = f.id
 WHERE ff.user_id = $1
-ORDER BY ff.created_at DESC
+ORDER BY ff.category NULLS FIRST, f.name
 `
 
 type GetFeedFollowsForUserRow struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	FeedID    uuid.UUID
+	Category  sql.NullString
+	Tags      []string
 	UserName  string
 	FeedName  string
+	FeedUrl   string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -110,8 +141,55 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.ID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.FeedID,
+			&i.Category,
+			pq.Array(&i.Tags),
 			&i.UserName,
 			&i.FeedName,
+			&i.FeedUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFeedFollowsForExport = `-- name: GetFeedFollowsForExport :many
+SELECT f.name, f.url, ff.category, ff.tags
+FROM feed_follows ff
+JOIN feeds f ON ff.feed_id = f.id
+WHERE ff.user_id = $1
+ORDER BY ff.category NULLS FIRST, f.name
+`
+
+type GetFeedFollowsForExportRow struct {
+	Name     string
+	Url      string
+	Category sql.NullString
+	Tags     []string
+}
+
+func (q *Queries) GetFeedFollowsForExport(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForExportRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFollowsForExport, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedFollowsForExportRow
+	for rows.Next() {
+		var i GetFeedFollowsForExportRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.Category,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -126,6 +204,116 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	return items, nil
 }
 
+const getFollowedFeedsWithUnread = `-- name: GetFollowedFeedsWithUnread :many
+SELECT
+  feeds.id,
+  feeds.name,
+  COUNT(feed_posts.post_id) FILTER (
+    WHERE post_states.read_at IS NULL AND post_states.archived_at IS NULL
+  ) AS unread
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN feed_posts ON feed_posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = feed_posts.post_id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id, feeds.name
+ORDER BY feeds.name
+`
+
+type GetFollowedFeedsWithUnreadRow struct {
+	ID     uuid.UUID
+	Name   string
+	Unread int64
+}
+
+func (q *Queries) GetFollowedFeedsWithUnread(ctx context.Context, userID uuid.UUID) ([]GetFollowedFeedsWithUnreadRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsWithUnread, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsWithUnreadRow
+	for rows.Next() {
+		var i GetFollowedFeedsWithUnreadRow
+		if err := rows.Scan(&i.ID, &i.Name, &i.Unread); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const moveFeedFollowFolder = `-- name: MoveFeedFollowFolder :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    category = $1::text || substr(category, length($2::text) + 1)
+WHERE user_id = $3
+  AND (category = $2 OR starts_with(category, $2 || '/'))
+`
+
+type MoveFeedFollowFolderParams struct {
+	NewFolder string
+	OldFolder string
+	UserID    uuid.UUID
+}
+
+// Moves a folder, along with the folders inside it.
+func (q *Queries) MoveFeedFollowFolder(ctx context.Context, arg MoveFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, moveFeedFollowFolder, arg.NewFolder, arg.OldFolder, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const removeFeedFollowTags = `-- name: RemoveFeedFollowTags :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    tags = ARRAY(SELECT tag FROM unnest(tags) AS tag WHERE tag <> ALL($1::text[]) ORDER BY tag)
+WHERE user_id = $2 AND feed_id = $3
+`
+
+type RemoveFeedFollowTagsParams struct {
+	Tags   []string
+	UserID uuid.UUID
+	FeedID uuid.UUID
+}
+
+func (q *Queries) RemoveFeedFollowTags(ctx context.Context, arg RemoveFeedFollowTagsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeFeedFollowTags, pq.Array(arg.Tags), arg.UserID, arg.FeedID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowCategory = `-- name: SetFeedFollowCategory :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    category = $3
+WHERE user_id = $1 AND feed_id = $2
+`
+
+type SetFeedFollowCategoryParams struct {
+	UserID   uuid.UUID
+	FeedID   uuid.UUID
+	Category sql.NullString
+}
+
+func (q *Queries) SetFeedFollowCategory(ctx context.Context, arg SetFeedFollowCategoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowCategory, arg.UserID, arg.FeedID, arg.Category)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const unfollowFeed = `-- name: UnfollowFeed :exec
 DELETE FROM feed_follows WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = $2
 `
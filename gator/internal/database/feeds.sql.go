@@ -7,15 +7,56 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimNextFeed = `-- name: ClaimNextFeed :one
+UPDATE feeds
+SET updated_at = NOW(),
+    last_fetched_at = NOW(),
+    next_fetch_at = NOW() + make_interval(secs => fetch_interval_seconds)
+WHERE id = (
+  SELECT id
+  FROM feeds
+  WHERE active
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+  ORDER BY next_fetch_at ASC NULLS FIRST
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_status, error_count, last_error, next_fetch_at, active, fetch_interval_seconds
+`
+
+func (q *Queries) ClaimNextFeed(ctx context.Context) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, claimNextFeed)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastStatus,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.Active,
+		&i.FetchIntervalSeconds,
+	)
+	return i, err
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_status, error_count, last_error, next_fetch_at, active, fetch_interval_seconds
 `
 
 type CreateFeedParams struct {
@@ -45,12 +86,47 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastStatus,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.Active,
+		&i.FetchIntervalSeconds,
+	)
+	return i, err
+}
+
+const getFeedById = `-- name: GetFeedById :one
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_status, error_count, last_error, next_fetch_at, active, fetch_interval_seconds FROM feeds WHERE id = $1
+`
+
+func (q *Queries) GetFeedById(ctx context.Context, id uuid.UUID) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, getFeedById, id)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastStatus,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.Active,
+		&i.FetchIntervalSeconds,
 	)
 	return i, err
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, last_status, error_count, last_error, next_fetch_at, active, fetch_interval_seconds FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
@@ -64,6 +140,14 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastStatus,
+		&i.ErrorCount,
+		&i.LastError,
+		&i.NextFetchAt,
+		&i.Active,
+		&i.FetchIntervalSeconds,
 	)
 	return i, err
 }
@@ -110,36 +194,60 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	return items, nil
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
-FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1
+const recordFeedFetch = `-- name: RecordFeedFetch :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    etag = $2,
+    last_modified = $3,
+    last_status = $4,
+    error_count = $5,
+    last_error = $6,
+    next_fetch_at = $7,
+    active = $8,
+    fetch_interval_seconds = $9
+WHERE id = $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
+type RecordFeedFetchParams struct {
+	ID                   uuid.UUID
+	Etag                 sql.NullString
+	LastModified         sql.NullString
+	LastStatus           sql.NullInt32
+	ErrorCount           int32
+	LastError            sql.NullString
+	NextFetchAt          sql.NullTime
+	Active               bool
+	FetchIntervalSeconds int32
+}
+
+func (q *Queries) RecordFeedFetch(ctx context.Context, arg RecordFeedFetchParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedFetch,
+		arg.ID,
+		arg.Etag,
+		arg.LastModified,
+		arg.LastStatus,
+		arg.ErrorCount,
+		arg.LastError,
+		arg.NextFetchAt,
+		arg.Active,
+		arg.FetchIntervalSeconds,
 	)
-	return i, err
+	return err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const updateFeedUrl = `-- name: UpdateFeedUrl :exec
 UPDATE feeds
 SET updated_at = NOW(),
-    last_fetched_at = NOW()
+    url = $2
 WHERE id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type UpdateFeedUrlParams struct {
+	ID  uuid.UUID
+	Url string
+}
+
+func (q *Queries) UpdateFeedUrl(ctx context.Context, arg UpdateFeedUrlParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedUrl, arg.ID, arg.Url)
 	return err
 }
@@ -11,14 +11,32 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UserID     uuid.UUID
+	Name       string
+	Prefix     string
+	TokenHash  string
+	LastUsedAt sql.NullTime
+}
+
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                   uuid.UUID
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+	Name                 string
+	Url                  string
+	UserID               uuid.UUID
+	LastFetchedAt        sql.NullTime
+	Etag                 sql.NullString
+	LastModified         sql.NullString
+	LastStatus           sql.NullInt32
+	ErrorCount           int32
+	LastError            sql.NullString
+	NextFetchAt          sql.NullTime
+	Active               bool
+	FetchIntervalSeconds int32
 }
 
 type FeedFollow struct {
@@ -27,22 +45,93 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
+	Tags      []string
+}
+
+type FeedPost struct {
+	FeedID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	Guid      string
+}
+
+type NotificationQueue struct {
+	SinkID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type NotificationSink struct {
+	ID                    uuid.UUID
+	CreatedAt             time.Time
+	UserID                uuid.UUID
+	Kind                  string
+	Target                string
+	Args                  []string
+	DigestIntervalSeconds int32
+	NextDigestAt          time.Time
+	LastSentAt            sql.NullTime
+	ErrorCount            int32
+	LastError             sql.NullString
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID                 uuid.UUID
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	Title              string
+	Url                string
+	Description        sql.NullString
+	PublishedAt        sql.NullTime
+	NormalizedUrl      string
+	ContentHash        string
+	Search             interface{}
+	ContentHtml        sql.NullString
+	ContentText        sql.NullString
+	ExtractedAt        sql.NullTime
+	ExtractAttemptedAt sql.NullTime
+	Author             sql.NullString
+	Categories         []string
 }
 
-type User struct {
+type PostState struct {
+	UserID     uuid.UUID
+	PostID     uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ReadAt     sql.NullTime
+	StarredAt  sql.NullTime
+	ArchivedAt sql.NullTime
+}
+
+type PostTag struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type Rule struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Name      string
+	UserID    uuid.UUID
+	Condition string
+	Action    string
+	Tag       sql.NullString
+}
+
+type Session struct {
+	TokenHash string
+	CreatedAt time.Time
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+type User struct {
+	ID             uuid.UUID
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Name           string
+	HashedPassword sql.NullString
 }
@@ -11,8 +11,51 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const claimPostsForExtraction = `-- name: ClaimPostsForExtraction :many
+UPDATE posts
+SET extract_attempted_at = NOW()
+WHERE id IN (
+  SELECT id FROM posts
+  WHERE extract_attempted_at IS NULL
+  ORDER BY created_at DESC
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, title, url
+`
+
+type ClaimPostsForExtractionRow struct {
+	ID    uuid.UUID
+	Title string
+	Url   string
+}
+
+func (q *Queries) ClaimPostsForExtraction(ctx context.Context, limit int32) ([]ClaimPostsForExtractionRow, error) {
+	rows, err := q.db.QueryContext(ctx, claimPostsForExtraction, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ClaimPostsForExtractionRow
+	for rows.Next() {
+		var i ClaimPostsForExtractionRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.Url); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO posts (
   id,
@@ -20,23 +63,30 @@ INSERT INTO posts (
   updated_at,
   title,
   url,
+  normalized_url,
+  content_hash,
   description,
   published_at,
-  feed_id
+  author,
+  categories
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (normalized_url) DO NOTHING
+RETURNING id, created_at, updated_at, title, url, description, published_at, normalized_url, content_hash, search, content_html, content_text, extracted_at, extract_attempted_at, author, categories
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt sql.NullTime
-	FeedID      uuid.UUID
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Title         string
+	Url           string
+	NormalizedUrl string
+	ContentHash   string
+	Description   sql.NullString
+	PublishedAt   sql.NullTime
+	Author        sql.NullString
+	Categories    []string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -46,9 +96,12 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.UpdatedAt,
 		arg.Title,
 		arg.Url,
+		arg.NormalizedUrl,
+		arg.ContentHash,
 		arg.Description,
 		arg.PublishedAt,
-		arg.FeedID,
+		arg.Author,
+		pq.Array(arg.Categories),
 	)
 	var i Post
 	err := row.Scan(
@@ -59,13 +112,85 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Url,
 		&i.Description,
 		&i.PublishedAt,
-		&i.FeedID,
+		&i.NormalizedUrl,
+		&i.ContentHash,
+		&i.Search,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.ExtractedAt,
+		&i.ExtractAttemptedAt,
+		&i.Author,
+		pq.Array(&i.Categories),
+	)
+	return i, err
+}
+
+const getFeedPostByContentHash = `-- name: GetFeedPostByContentHash :one
+SELECT feed_posts.post_id
+FROM feed_posts
+JOIN posts ON feed_posts.post_id = posts.id
+WHERE feed_posts.feed_id = $1 AND posts.content_hash = $2
+LIMIT 1
+`
+
+type GetFeedPostByContentHashParams struct {
+	FeedID      uuid.UUID
+	ContentHash string
+}
+
+func (q *Queries) GetFeedPostByContentHash(ctx context.Context, arg GetFeedPostByContentHashParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostByContentHash, arg.FeedID, arg.ContentHash)
+	var post_id uuid.UUID
+	err := row.Scan(&post_id)
+	return post_id, err
+}
+
+const getFeedPostByGuid = `-- name: GetFeedPostByGuid :one
+SELECT post_id FROM feed_posts WHERE feed_id = $1 AND guid = $2
+`
+
+type GetFeedPostByGuidParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) GetFeedPostByGuid(ctx context.Context, arg GetFeedPostByGuidParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getFeedPostByGuid, arg.FeedID, arg.Guid)
+	var post_id uuid.UUID
+	err := row.Scan(&post_id)
+	return post_id, err
+}
+
+const getPostByNormalizedUrl = `-- name: GetPostByNormalizedUrl :one
+SELECT id, created_at, updated_at, title, url, description, published_at, normalized_url, content_hash, search, content_html, content_text, extracted_at, extract_attempted_at, author, categories FROM posts WHERE normalized_url = $1
+`
+
+func (q *Queries) GetPostByNormalizedUrl(ctx context.Context, normalizedUrl string) (Post, error) {
+	row := q.db.QueryRowContext(ctx, getPostByNormalizedUrl, normalizedUrl)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.NormalizedUrl,
+		&i.ContentHash,
+		&i.Search,
+		&i.ContentHtml,
+		&i.ContentText,
+		&i.ExtractedAt,
+		&i.ExtractAttemptedAt,
+		&i.Author,
+		pq.Array(&i.Categories),
 	)
 	return i, err
 }
 
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT 
+SELECT
   posts.id,
   posts.created_at,
   posts.updated_at,
@@ -73,19 +198,64 @@ SELECT
   posts.url,
   posts.description,
   posts.published_at,
-  posts.feed_id,
-  feeds.name as feed_name
+  posts.content_html,
+  posts.content_text,
+  source.feed_id,
+  source.feed_name,
+  post_states.read_at IS NOT NULL AS is_read,
+  post_states.starred_at IS NOT NULL AS is_starred,
+  ARRAY(
+    SELECT post_tags.tag FROM post_tags
+    WHERE post_tags.post_id = posts.id AND post_tags.user_id = $1
+    UNION
+    SELECT unnest(feed_follows.tags) FROM feed_posts
+    JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+    WHERE feed_posts.post_id = posts.id AND feed_follows.user_id = $1
+    ORDER BY 1
+  )::text[] AS tags
 FROM posts
-JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2
+JOIN LATERAL (
+  SELECT feeds.id AS feed_id, feeds.name AS feed_name
+  FROM feed_posts
+  JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+  JOIN feeds ON feed_posts.feed_id = feeds.id
+  WHERE feed_posts.post_id = posts.id
+    AND feed_follows.user_id = $1
+    AND ($2::uuid IS NULL OR feed_posts.feed_id = $2)
+    AND ($3::text IS NULL
+      OR feed_follows.category = $3
+      OR starts_with(feed_follows.category, $3 || '/'))
+  ORDER BY feed_posts.created_at
+  LIMIT 1
+) source ON TRUE
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = $1
+WHERE (NOT $4::bool OR post_states.read_at IS NULL)
+  AND (NOT $5::bool OR post_states.starred_at IS NOT NULL)
+  AND ($6::bool OR post_states.archived_at IS NULL)
+  AND ($7::text IS NULL
+    OR EXISTS (
+      SELECT 1 FROM post_tags
+      WHERE post_tags.post_id = posts.id AND post_tags.user_id = $1 AND post_tags.tag = $7
+    )
+    OR EXISTS (
+      SELECT 1 FROM feed_posts
+      JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+      WHERE feed_posts.post_id = posts.id AND feed_follows.user_id = $1 AND $7 = ANY(feed_follows.tags)
+    ))
+ORDER BY posts.published_at DESC NULLS LAST, posts.id
+LIMIT $8 OFFSET $9
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID          uuid.UUID
+	FeedID          uuid.NullUUID
+	Folder          sql.NullString
+	UnreadOnly      bool
+	StarredOnly     bool
+	IncludeArchived bool
+	Tag             sql.NullString
+	RowLimit        int32
+	RowOffset       int32
 }
 
 type GetPostsForUserRow struct {
@@ -96,12 +266,28 @@ type GetPostsForUserRow struct {
 	Url         string
 	Description sql.NullString
 	PublishedAt sql.NullTime
+	ContentHtml sql.NullString
+	ContentText sql.NullString
 	FeedID      uuid.UUID
 	FeedName    string
+	IsRead      bool
+	IsStarred   bool
+	Tags        []string
 }
 
+// A post's tags are its own and those of the feeds it came from.
 func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.FeedID,
+		arg.Folder,
+		arg.UnreadOnly,
+		arg.StarredOnly,
+		arg.IncludeArchived,
+		arg.Tag,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -117,8 +303,13 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Url,
 			&i.Description,
 			&i.PublishedAt,
+			&i.ContentHtml,
+			&i.ContentText,
 			&i.FeedID,
 			&i.FeedName,
+			&i.IsRead,
+			&i.IsStarred,
+			pq.Array(&i.Tags),
 		); err != nil {
 			return nil, err
 		}
@@ -132,3 +323,239 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const linkPostToFeed = `-- name: LinkPostToFeed :execrows
+INSERT INTO feed_posts (feed_id, post_id, created_at, guid)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id, post_id) DO NOTHING
+`
+
+type LinkPostToFeedParams struct {
+	FeedID    uuid.UUID
+	PostID    uuid.UUID
+	CreatedAt time.Time
+	Guid      string
+}
+
+func (q *Queries) LinkPostToFeed(ctx context.Context, arg LinkPostToFeedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, linkPostToFeed,
+		arg.FeedID,
+		arg.PostID,
+		arg.CreatedAt,
+		arg.Guid,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateFeedPostGuid = `-- name: UpdateFeedPostGuid :exec
+UPDATE feed_posts SET guid = $1
+WHERE feed_id = $2 AND post_id = $3 AND guid <> ALL($4::text[])
+`
+
+type UpdateFeedPostGuidParams struct {
+	Guid         string
+	FeedID       uuid.UUID
+	PostID       uuid.UUID
+	FetchedGuids []string
+}
+
+func (q *Queries) UpdateFeedPostGuid(ctx context.Context, arg UpdateFeedPostGuidParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedPostGuid,
+		arg.Guid,
+		arg.FeedID,
+		arg.PostID,
+		pq.Array(arg.FetchedGuids),
+	)
+	return err
+}
+
+const getPostsToBackfill = `-- name: GetPostsToBackfill :many
+SELECT id, title, url, description FROM posts
+WHERE content_hash = ''
+ORDER BY id
+LIMIT $1
+`
+
+type GetPostsToBackfillRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	Description sql.NullString
+}
+
+func (q *Queries) GetPostsToBackfill(ctx context.Context, limit int32) ([]GetPostsToBackfillRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsToBackfill, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPostsToBackfillRow
+	for rows.Next() {
+		var i GetPostsToBackfillRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const backfillPostKeys = `-- name: BackfillPostKeys :exec
+UPDATE posts
+SET content_hash = $1,
+    normalized_url = CASE
+      WHEN EXISTS (
+        SELECT 1 FROM posts other
+        WHERE other.normalized_url = $2 AND other.id <> posts.id
+      ) THEN posts.normalized_url
+      ELSE $2
+    END
+WHERE id = $3
+`
+
+type BackfillPostKeysParams struct {
+	ContentHash   string
+	NormalizedUrl string
+	ID            uuid.UUID
+}
+
+func (q *Queries) BackfillPostKeys(ctx context.Context, arg BackfillPostKeysParams) error {
+	_, err := q.db.ExecContext(ctx, backfillPostKeys, arg.ContentHash, arg.NormalizedUrl, arg.ID)
+	return err
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  source.feed_id,
+  source.feed_name,
+  ts_rank_cd(posts.search, query)::real AS rank,
+  ts_headline(
+    'english',
+    coalesce(posts.description, ''),
+    query,
+    'MaxFragments=1, MinWords=10, MaxWords=25, StartSel=«, StopSel=»'
+  ) AS snippet
+FROM posts
+CROSS JOIN websearch_to_tsquery('english', $1) AS query
+JOIN LATERAL (
+  SELECT feeds.id AS feed_id, feeds.name AS feed_name
+  FROM feed_posts
+  JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+  JOIN feeds ON feed_posts.feed_id = feeds.id
+  WHERE feed_posts.post_id = posts.id
+    AND feed_follows.user_id = $2
+    AND ($3::uuid IS NULL OR feed_posts.feed_id = $3)
+  ORDER BY feed_posts.created_at
+  LIMIT 1
+) source ON TRUE
+WHERE posts.search @@ query
+  AND ($4::timestamp IS NULL OR posts.published_at >= $4)
+  AND ($5::timestamp IS NULL OR posts.published_at < $5)
+ORDER BY
+  CASE WHEN $6::bool THEN posts.published_at END DESC NULLS LAST,
+  rank DESC,
+  posts.published_at DESC NULLS LAST
+LIMIT $7 OFFSET $8
+`
+
+type SearchPostsParams struct {
+	Query     string
+	UserID    uuid.UUID
+	FeedID    uuid.NullUUID
+	Since     sql.NullTime
+	Until     sql.NullTime
+	ByDate    bool
+	RowLimit  int32
+	RowOffset int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt sql.NullTime
+	FeedID      uuid.UUID
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts,
+		arg.Query,
+		arg.UserID,
+		arg.FeedID,
+		arg.Since,
+		arg.Until,
+		arg.ByDate,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchPostsRow
+	for rows.Next() {
+		var i SearchPostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Url,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setPostContent = `-- name: SetPostContent :exec
+UPDATE posts
+SET content_html = $2,
+    content_text = $3,
+    extracted_at = NOW(),
+    extract_attempted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetPostContentParams struct {
+	ID          uuid.UUID
+	ContentHtml sql.NullString
+	ContentText sql.NullString
+}
+
+func (q *Queries) SetPostContent(ctx context.Context, arg SetPostContentParams) error {
+	_, err := q.db.ExecContext(ctx, setPostContent, arg.ID, arg.ContentHtml, arg.ContentText)
+	return err
+}
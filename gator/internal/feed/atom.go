@@ -0,0 +1,127 @@
+package feed
+
+import (
+	"net/url"
+	"strings"
+)
+
+type atomDocument struct {
+	// Base is the element's xml:base, which relative links inside it are
+	// resolved against. Entries and links may carry their own.
+	Base     string      `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomPerson  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+	syndication
+}
+
+type atomEntry struct {
+	Base       string         `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	ID         string         `xml:"id"`
+	Title      atomText       `xml:"title"`
+	Links      []atomLink     `xml:"link"`
+	Summary    atomText       `xml:"summary"`
+	Content    atomText       `xml:"content"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Categories []atomCategory `xml:"category"`
+}
+
+// atomText is a text construct. type="xhtml" content is inline markup,
+// which we keep as HTML.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return t.Body
+}
+
+type atomLink struct {
+	Base string `xml:"http://www.w3.org/XML/1998/namespace base,attr"`
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+// alternateLink returns the link to the HTML version, which is the one
+// with rel="alternate" or no rel at all, resolved against base.
+func alternateLink(base *url.URL, links []atomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return resolveURL(xmlBase(base, link.Base), link.Href)
+		}
+	}
+	return ""
+}
+
+func parseAtom(data []byte, base *url.URL) (*Feed, error) {
+	doc := atomDocument{}
+	if err := decodeXML(data, &doc); err != nil {
+		return nil, err
+	}
+	base = xmlBase(base, doc.Base)
+
+	feed := &Feed{
+		Title:          doc.Title.String(),
+		Link:           alternateLink(base, doc.Links),
+		Description:    doc.Subtitle.String(),
+		UpdateInterval: doc.interval(),
+	}
+
+	for _, entry := range doc.Entries {
+		published := ParseDate(entry.Published)
+		if published.IsZero() {
+			published = ParseDate(entry.Updated)
+		}
+
+		// Entries inherit the feed's author when they have none.
+		author := doc.Author.Name
+		if len(entry.Authors) > 0 {
+			names := make([]string, 0, len(entry.Authors))
+			for _, person := range entry.Authors {
+				names = append(names, person.Name)
+			}
+			author = strings.Join(names, ", ")
+		}
+
+		var categories []string
+		for _, category := range entry.Categories {
+			if category.Label != "" {
+				categories = append(categories, category.Label)
+			} else {
+				categories = append(categories, category.Term)
+			}
+		}
+
+		feed.Items = append(feed.Items, Item{
+			GUID:        entry.ID,
+			Title:       entry.Title.String(),
+			Link:        alternateLink(xmlBase(base, entry.Base), entry.Links),
+			Description: entry.Summary.String(),
+			Content:     entry.Content.String(),
+			Author:      author,
+			Categories:  categories,
+			Published:   published,
+		})
+	}
+
+	return feed, nil
+}
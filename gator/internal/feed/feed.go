@@ -0,0 +1,277 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Format is the syndication format a document was written in.
+type Format string
+
+const (
+	FormatRSS2     Format = "rss2"
+	FormatRSS1     Format = "rss1"
+	FormatAtom     Format = "atom"
+	FormatJSONFeed Format = "jsonfeed"
+)
+
+var ErrUnknownFormat = errors.New("not an RSS, Atom or JSON feed")
+
+// Feed is a parsed feed in any supported format.
+type Feed struct {
+	Format      Format
+	Title       string
+	Link        string
+	Description string
+	// UpdateInterval is how often the publisher says the feed changes, from
+	// RSS <ttl> or the syndication module's sy:updatePeriod. Zero when the
+	// feed doesn't say.
+	UpdateInterval time.Duration
+	Items          []Item
+}
+
+// Item is a single entry of a feed, normalized across formats. Fields a
+// format doesn't have are left empty.
+type Item struct {
+	// GUID identifies the item within its feed: RSS <guid>, Atom <id>,
+	// JSON Feed "id", or the link for formats without one.
+	GUID        string
+	Title       string
+	Link        string
+	Description string
+	// Content is the full body when the feed carries one separately from
+	// the description, as HTML.
+	Content    string
+	Author     string
+	Categories []string
+	// Published is zero when the feed gives no usable date.
+	Published time.Time
+}
+
+// Parse detects the format of data and parses it. Relative links are
+// resolved against base, the URL the document was fetched from.
+func Parse(data []byte, base string) (*Feed, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	baseURL := xmlBase(nil, base)
+
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var feed *Feed
+	switch format {
+	case FormatJSONFeed:
+		feed, err = parseJSONFeed(data)
+	case FormatAtom:
+		feed, err = parseAtom(data, baseURL)
+	case FormatRSS1:
+		feed, err = parseRSS1(data)
+	default:
+		feed, err = parseRSS2(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s feed: %w", format, err)
+	}
+
+	feed.Format = format
+	feed.normalize(baseURL)
+	return feed, nil
+}
+
+// Detect reports the format of data from its first JSON character or XML
+// root element.
+func Detect(data []byte) (Format, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		return FormatJSONFeed, nil
+	}
+
+	decoder := newXMLDecoder(bytes.NewReader(trimmed))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", ErrUnknownFormat
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(start.Name.Local) {
+		case "rss":
+			return FormatRSS2, nil
+		case "rdf":
+			return FormatRSS1, nil
+		case "feed":
+			return FormatAtom, nil
+		}
+		return "", ErrUnknownFormat
+	}
+}
+
+func newXMLDecoder(r io.Reader) *xml.Decoder {
+	decoder := xml.NewDecoder(r)
+	// Feeds in the wild are often not quite XML: undeclared HTML entities,
+	// unescaped ampersands, and legacy encodings.
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		encoding, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, err
+		}
+		return encoding.NewDecoder().Reader(input), nil
+	}
+	return decoder
+}
+
+func decodeXML(data []byte, v any) error {
+	return newXMLDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (f *Feed) normalize(base *url.URL) {
+	f.Title = cleanText(f.Title)
+	f.Description = cleanText(f.Description)
+	f.Link = resolveURL(base, f.Link)
+
+	for i := range f.Items {
+		item := &f.Items[i]
+		item.Title = cleanText(item.Title)
+		item.Link = resolveURL(base, item.Link)
+		item.GUID = strings.TrimSpace(item.GUID)
+		item.Author = cleanText(item.Author)
+		item.Description = strings.TrimSpace(html.UnescapeString(item.Description))
+		item.Content = strings.TrimSpace(item.Content)
+
+		if item.GUID == "" {
+			item.GUID = item.Link
+		}
+		if item.Description == "" && item.Content != "" {
+			item.Description = item.Content
+		}
+
+		categories := item.Categories[:0]
+		for _, category := range item.Categories {
+			if category = cleanText(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+		item.Categories = categories
+	}
+}
+
+// xmlBase returns the base URL in effect under an element whose xml:base is
+// ref, given the one in effect outside it. It is nil while no absolute base
+// is known.
+func xmlBase(base *url.URL, ref string) *url.URL {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.String() == "" {
+		return base
+	}
+	if base != nil {
+		return base.ResolveReference(u)
+	}
+	if u.IsAbs() && u.Host != "" {
+		return u
+	}
+	return nil
+}
+
+// resolveURL resolves a link against base. Links stay as they are when
+// there is no base to resolve them against.
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if base == nil || ref == "" {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// cleanText unescapes entities left in a plain text field and collapses
+// whitespace.
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
+
+// dateLayouts are the date formats seen in feeds, strictest first.
+var dateLayouts = []string{
+	time.RFC3339,
+	time.RFC3339Nano,
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC850,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// syndication holds the RSS syndication module elements, which RSS 1.0,
+// RSS 2.0 and Atom feeds may all carry on their channel.
+type syndication struct {
+	UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+	UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+}
+
+// interval returns updatePeriod divided by updateFrequency, or zero if the
+// period is missing or unknown.
+func (s syndication) interval() time.Duration {
+	var period time.Duration
+	switch strings.ToLower(strings.TrimSpace(s.UpdatePeriod)) {
+	case "hourly":
+		period = time.Hour
+	case "daily":
+		period = 24 * time.Hour
+	case "weekly":
+		period = 7 * 24 * time.Hour
+	case "monthly":
+		period = 30 * 24 * time.Hour
+	case "yearly":
+		period = 365 * 24 * time.Hour
+	default:
+		return 0
+	}
+
+	frequency, err := strconv.Atoi(strings.TrimSpace(s.UpdateFrequency))
+	if err != nil || frequency < 1 {
+		frequency = 1
+	}
+	return period / time.Duration(frequency)
+}
+
+// ParseDate parses a feed date, returning the zero time if it matches no
+// known layout.
+func ParseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
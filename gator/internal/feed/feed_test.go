@@ -0,0 +1,294 @@
+package feed_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/feed"
+)
+
+func parseFixture(t *testing.T, name string) *feed.Feed {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("could not read fixture: %v", err)
+	}
+
+	parsed, err := feed.Parse(data, "")
+	if err != nil {
+		t.Fatalf("unexpected error parsing %s: %v", name, err)
+	}
+	return parsed
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// checkItems compares items field by field so failures point at the field.
+func checkItems(t *testing.T, got, want []feed.Item) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d items, got %d: %+v", len(want), len(got), got)
+	}
+
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.Published.Equal(w.Published) {
+			t.Fatalf("item %d: expected published %v, got %v", i, w.Published, g.Published)
+		}
+		g.Published, w.Published = time.Time{}, time.Time{}
+		if len(g.Categories) == 0 && len(w.Categories) == 0 {
+			g.Categories, w.Categories = nil, nil
+		}
+		if !reflect.DeepEqual(g, w) {
+			t.Fatalf("item %d:\nexpected %+v\ngot      %+v", i, w, g)
+		}
+	}
+}
+
+// Test RSS 2.0 with the content and Dublin Core modules, and foreign
+// elements that share names with RSS ones.
+func TestParseRSS2(t *testing.T) {
+	got := parseFixture(t, "rss2.xml")
+
+	if got.Format != feed.FormatRSS2 {
+		t.Fatalf("expected rss2, got %s", got.Format)
+	}
+	if got.Title != "Go Blog & Friends" || got.Link != "https://go.dev/blog" {
+		t.Fatalf("unexpected channel: %q %q", got.Title, got.Link)
+	}
+	if got.UpdateInterval != 90*time.Minute {
+		t.Fatalf("expected a 90m ttl, got %s", got.UpdateInterval)
+	}
+
+	checkItems(t, got.Items, []feed.Item{
+		{
+			GUID:        "tag:go.dev,2024:go1.23",
+			Title:       "Go 1.23 is released",
+			Link:        "https://go.dev/blog/go1.23",
+			Description: "<p>Today we release Go 1.23.</p>",
+			Content:     "<p>Today we release Go 1.23, with <b>iterators</b>.</p>",
+			Author:      "The Go Team",
+			Categories:  []string{"release", "go"},
+			Published:   date("2024-08-13T10:00:00Z"),
+		},
+		{
+			GUID:      "https://go.dev/blog/range-functions",
+			Title:     "Range over func",
+			Link:      "https://go.dev/blog/range-functions",
+			Author:    "rsc@golang.org (Russ Cox)",
+			Published: date("2024-08-01T09:30:00Z"),
+		},
+	})
+}
+
+// Test RSS 1.0, where items sit beside the channel.
+func TestParseRSS1(t *testing.T) {
+	got := parseFixture(t, "rss1.rdf")
+
+	if got.Format != feed.FormatRSS1 || got.Title != "Slashdot Example" {
+		t.Fatalf("unexpected feed: %s %q", got.Format, got.Title)
+	}
+	if got.UpdateInterval != 30*time.Minute {
+		t.Fatalf("expected sy:updatePeriod to give 30m, got %s", got.UpdateInterval)
+	}
+
+	checkItems(t, got.Items, []feed.Item{
+		{
+			GUID:        "https://slashdot.example/story/1",
+			Title:       "Linux 6.10 released",
+			Link:        "https://slashdot.example/story/1",
+			Description: "Another kernel.",
+			Author:      "msmash",
+			Categories:  []string{"linux"},
+			Published:   date("2024-07-14T21:10:00Z"),
+		},
+		{
+			GUID:  "https://slashdot.example/story/2",
+			Title: "Second story",
+			Link:  "https://slashdot.example/story/2",
+		},
+	})
+}
+
+// Test Atom, including xhtml content, inherited authors and picking the
+// alternate link.
+func TestParseAtom(t *testing.T) {
+	got := parseFixture(t, "atom.xml")
+
+	if got.Format != feed.FormatAtom {
+		t.Fatalf("expected atom, got %s", got.Format)
+	}
+	if got.Title != "Release notes from gator" || got.Link != "https://github.com/ckm54/gator/releases" {
+		t.Fatalf("unexpected feed: %q %q", got.Title, got.Link)
+	}
+
+	checkItems(t, got.Items, []feed.Item{
+		{
+			GUID:        "tag:github.com,2008:Repository/1/v1.2.0",
+			Title:       "v1.2.0",
+			Link:        "https://github.com/ckm54/gator/releases/tag/v1.2.0",
+			Description: "<p>Bug fixes</p>",
+			Content:     `<div xmlns="http://www.w3.org/1999/xhtml"><p>Fixes <code>agg</code>.</p></div>`,
+			Author:      "ckm54",
+			Categories:  []string{"Release", "stable"},
+			Published:   date("2024-09-01T08:00:00Z"),
+		},
+		{
+			GUID:        "tag:github.com,2008:Repository/1/v1.1.0",
+			Title:       "v1.1.0 <beta>",
+			Link:        "https://github.com/ckm54/gator/releases/tag/v1.1.0",
+			Description: "<p>First release</p>",
+			Content:     "<p>First release</p>",
+			Author:      "alice, bob",
+			Published:   date("2024-08-01T08:00:00Z"),
+		},
+	})
+}
+
+func TestParseJSONFeed(t *testing.T) {
+	got := parseFixture(t, "jsonfeed.json")
+
+	if got.Format != feed.FormatJSONFeed || got.Link != "https://daring.example/" {
+		t.Fatalf("unexpected feed: %s %q", got.Format, got.Link)
+	}
+
+	checkItems(t, got.Items, []feed.Item{
+		{
+			GUID:        "https://daring.example/2024/09/one",
+			Title:       "First post",
+			Link:        "https://daring.example/2024/09/one",
+			Description: "Hello",
+			Content:     "<p>Hello, JSON Feed.</p>",
+			Author:      "John Example",
+			Categories:  []string{"apple", "web"},
+			Published:   date("2024-09-03T17:00:00Z"),
+		},
+		{
+			GUID:        "2",
+			Link:        "https://elsewhere.example/linked",
+			Description: "<p>A linked item with no title. 1 &lt; 2</p>",
+			Content:     "<p>A linked item with no title. 1 &lt; 2</p>",
+			Author:      "Guest",
+			Published:   date("2024-09-04T10:00:00Z"),
+		},
+	})
+}
+
+// Test that relative links are resolved against xml:base and the URL the
+// feed was fetched from.
+func TestParseRelativeLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		base string
+		want string
+	}{
+		{
+			"rss",
+			`<rss version="2.0"><channel><item><link>/posts/1</link></item></channel></rss>`,
+			"https://example.com/feed.xml",
+			"https://example.com/posts/1",
+		},
+		{
+			"atom xml:base",
+			`<feed xmlns="http://www.w3.org/2005/Atom" xml:base="https://example.com/blog/">
+				<entry xml:base="2024/"><id>1</id><link href="post.html"/></entry></feed>`,
+			"https://feeds.example.net/atom",
+			"https://example.com/blog/2024/post.html",
+		},
+		{
+			"atom relative xml:base",
+			`<feed xmlns="http://www.w3.org/2005/Atom" xml:base="/blog/"><entry><id>1</id><link href="post.html"/></entry></feed>`,
+			"https://example.com/feeds/atom.xml",
+			"https://example.com/blog/post.html",
+		},
+		{
+			"json feed",
+			`{"version":"https://jsonfeed.org/version/1.1","items":[{"id":"1","url":"../posts/1"}]}`,
+			"https://example.com/feeds/feed.json",
+			"https://example.com/posts/1",
+		},
+		{
+			"no base",
+			`<rss version="2.0"><channel><item><link>/posts/1</link></item></channel></rss>`,
+			"",
+			"/posts/1",
+		},
+	}
+
+	for _, tt := range tests {
+		got, err := feed.Parse([]byte(tt.data), tt.base)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if len(got.Items) != 1 || got.Items[0].Link != tt.want {
+			t.Fatalf("%s: expected link %s, got %+v", tt.name, tt.want, got.Items)
+		}
+	}
+}
+
+// Test the JSON Feed 1.0 single author field and a numeric id.
+func TestParseJSONFeedV1(t *testing.T) {
+	got := parseFixture(t, "jsonfeed-v1.json")
+
+	if len(got.Items) != 1 {
+		t.Fatalf("expected 1 item, got %d", len(got.Items))
+	}
+	if item := got.Items[0]; item.GUID != "42" || item.Author != "Legacy" {
+		t.Fatalf("unexpected item: %+v", item)
+	}
+}
+
+// Test that legacy encodings and HTML entities are decoded.
+func TestParseLatin1(t *testing.T) {
+	got := parseFixture(t, "latin1.xml")
+
+	if got.Title != "Café news" {
+		t.Fatalf("unexpected title %q", got.Title)
+	}
+	if len(got.Items) != 1 || got.Items[0].Title != "Crème brûlée recipe" {
+		t.Fatalf("unexpected items: %+v", got.Items)
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "not-a-feed.html"))
+	if err != nil {
+		t.Fatalf("could not read fixture: %v", err)
+	}
+
+	if _, err := feed.Parse(data, ""); !errors.Is(err, feed.ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat, got %v", err)
+	}
+	if _, err := feed.Parse([]byte(`{"title": "not a json feed"}`), ""); !errors.Is(err, feed.ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat for JSON without a version, got %v", err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := map[string]time.Time{
+		"Mon, 02 Jan 2006 15:04:05 -0700": date("2006-01-02T15:04:05-07:00"),
+		"Mon, 2 Jan 2006 15:04:05 +0000":  date("2006-01-02T15:04:05Z"),
+		"2006-01-02T15:04:05Z":            date("2006-01-02T15:04:05Z"),
+		"2006-01-02":                      date("2006-01-02T00:00:00Z"),
+		"yesterday":                       {},
+	}
+
+	for input, want := range tests {
+		if got := feed.ParseDate(input); !got.Equal(want) {
+			t.Fatalf("ParseDate(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
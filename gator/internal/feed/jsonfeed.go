@@ -0,0 +1,110 @@
+package feed
+
+import (
+	"encoding/json"
+	"html"
+	"strings"
+)
+
+type jsonFeedDocument struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Authors     []jsonAuthor   `json:"authors"`
+	Author      *jsonAuthor    `json:"author"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	// id may be a number in sloppy feeds, so take it raw.
+	ID            json.RawMessage `json:"id"`
+	URL           string          `json:"url"`
+	ExternalURL   string          `json:"external_url"`
+	Title         string          `json:"title"`
+	ContentHTML   string          `json:"content_html"`
+	ContentText   string          `json:"content_text"`
+	Summary       string          `json:"summary"`
+	DatePublished string          `json:"date_published"`
+	DateModified  string          `json:"date_modified"`
+	Authors       []jsonAuthor    `json:"authors"`
+	// Author is the JSON Feed 1.0 form, replaced by Authors in 1.1.
+	Author *jsonAuthor `json:"author"`
+	Tags   []string    `json:"tags"`
+}
+
+type jsonAuthor struct {
+	Name string `json:"name"`
+}
+
+func jsonAuthors(authors []jsonAuthor, author *jsonAuthor) string {
+	if len(authors) == 0 && author != nil {
+		authors = []jsonAuthor{*author}
+	}
+
+	names := make([]string, 0, len(authors))
+	for _, a := range authors {
+		if a.Name != "" {
+			names = append(names, a.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func parseJSONFeed(data []byte) (*Feed, error) {
+	doc := jsonFeedDocument{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.Version, "https://jsonfeed.org/version/") {
+		return nil, ErrUnknownFormat
+	}
+
+	feed := &Feed{
+		Title:       doc.Title,
+		Link:        doc.HomePageURL,
+		Description: doc.Description,
+	}
+	feedAuthor := jsonAuthors(doc.Authors, doc.Author)
+
+	for _, item := range doc.Items {
+		var id string
+		if err := json.Unmarshal(item.ID, &id); err != nil {
+			id = string(item.ID)
+		}
+
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		// Content is HTML, so plain text content has to be escaped.
+		content := item.ContentHTML
+		if content == "" && item.ContentText != "" {
+			content = "<p>" + html.EscapeString(item.ContentText) + "</p>"
+		}
+
+		published := ParseDate(item.DatePublished)
+		if published.IsZero() {
+			published = ParseDate(item.DateModified)
+		}
+
+		author := jsonAuthors(item.Authors, item.Author)
+		if author == "" {
+			author = feedAuthor
+		}
+
+		feed.Items = append(feed.Items, Item{
+			GUID:        id,
+			Title:       item.Title,
+			Link:        link,
+			Description: item.Summary,
+			Content:     content,
+			Author:      author,
+			Categories:  item.Tags,
+			Published:   published,
+		})
+	}
+
+	return feed, nil
+}
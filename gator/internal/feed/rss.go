@@ -0,0 +1,159 @@
+package feed
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nsRSS1 = "http://purl.org/rss/1.0/"
+
+// rssElement is a plain RSS element such as <title> or <link>. Go matches
+// untagged fields against elements in any namespace, so <atom:link> or
+// <media:title> would otherwise overwrite the RSS value.
+type rssElement struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// rssValue returns the value of the first element in the RSS namespace.
+func rssValue(elements []rssElement) string {
+	for _, element := range elements {
+		if element.XMLName.Space == "" || element.XMLName.Space == nsRSS1 {
+			return element.Value
+		}
+	}
+	return ""
+}
+
+// rssValues returns the values of all the elements in the RSS namespace.
+func rssValues(elements []rssElement) []string {
+	var values []string
+	for _, element := range elements {
+		if element.XMLName.Space == "" || element.XMLName.Space == nsRSS1 {
+			values = append(values, element.Value)
+		}
+	}
+	return values
+}
+
+type rss2Document struct {
+	Channel struct {
+		Title       []rssElement `xml:"title"`
+		Link        []rssElement `xml:"link"`
+		Description []rssElement `xml:"description"`
+		TTL         string       `xml:"ttl"`
+		Items       []rss2Item   `xml:"item"`
+		syndication
+	} `xml:"channel"`
+}
+
+type rss2Item struct {
+	Title       []rssElement `xml:"title"`
+	Link        []rssElement `xml:"link"`
+	Description []rssElement `xml:"description"`
+	Content     string       `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	GUID        []rssElement `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	DCDate      string       `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      []rssElement `xml:"author"`
+	Creator     string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []rssElement `xml:"category"`
+}
+
+func parseRSS2(data []byte) (*Feed, error) {
+	doc := rss2Document{}
+	if err := decodeXML(data, &doc); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:       rssValue(doc.Channel.Title),
+		Link:        rssValue(doc.Channel.Link),
+		Description: rssValue(doc.Channel.Description),
+	}
+
+	if minutes, err := strconv.Atoi(strings.TrimSpace(doc.Channel.TTL)); err == nil && minutes > 0 {
+		feed.UpdateInterval = time.Duration(minutes) * time.Minute
+	} else {
+		feed.UpdateInterval = doc.Channel.interval()
+	}
+
+	for _, item := range doc.Channel.Items {
+		published := ParseDate(item.PubDate)
+		if published.IsZero() {
+			published = ParseDate(item.DCDate)
+		}
+
+		author := item.Creator
+		if author == "" {
+			author = rssValue(item.Author)
+		}
+
+		feed.Items = append(feed.Items, Item{
+			GUID:        rssValue(item.GUID),
+			Title:       rssValue(item.Title),
+			Link:        rssValue(item.Link),
+			Description: rssValue(item.Description),
+			Content:     item.Content,
+			Author:      author,
+			Categories:  rssValues(item.Categories),
+			Published:   published,
+		})
+	}
+
+	return feed, nil
+}
+
+// RSS 1.0 puts items next to the channel under rdf:RDF rather than inside
+// it, and uses Dublin Core for dates, authors and subjects.
+type rss1Document struct {
+	Channel struct {
+		Title       []rssElement `xml:"title"`
+		Link        []rssElement `xml:"link"`
+		Description []rssElement `xml:"description"`
+		syndication
+	} `xml:"channel"`
+	Items []rss1Item `xml:"item"`
+}
+
+type rss1Item struct {
+	About       string       `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       []rssElement `xml:"title"`
+	Link        []rssElement `xml:"link"`
+	Description []rssElement `xml:"description"`
+	Content     string       `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Date        string       `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Subjects    []string     `xml:"http://purl.org/dc/elements/1.1/ subject"`
+}
+
+func parseRSS1(data []byte) (*Feed, error) {
+	doc := rss1Document{}
+	if err := decodeXML(data, &doc); err != nil {
+		return nil, err
+	}
+
+	feed := &Feed{
+		Title:          rssValue(doc.Channel.Title),
+		Link:           rssValue(doc.Channel.Link),
+		Description:    rssValue(doc.Channel.Description),
+		UpdateInterval: doc.Channel.interval(),
+	}
+
+	for _, item := range doc.Items {
+		feed.Items = append(feed.Items, Item{
+			GUID:        item.About,
+			Title:       rssValue(item.Title),
+			Link:        rssValue(item.Link),
+			Description: rssValue(item.Description),
+			Content:     item.Content,
+			Author:      item.Creator,
+			Categories:  item.Subjects,
+			Published:   ParseDate(item.Date),
+		})
+	}
+
+	return feed, nil
+}
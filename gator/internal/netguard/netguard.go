@@ -0,0 +1,48 @@
+// Package netguard keeps outbound requests to URLs that other servers or
+// users chose away from loopback and private networks.
+package netguard
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned when a URL resolves to an address we won't
+// connect to, such as loopback or a private network.
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+// Dialer returns a dialer that refuses any address allow rejects. The
+// address is checked when connecting, after DNS resolution, so a hostname
+// can't point us at an internal service, including by changing what it
+// resolves to between lookups.
+func Dialer(timeout time.Duration, allow func(netip.Addr) bool) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allow(addrPort.Addr().Unmap()) {
+				return fmt.Errorf("%s: %w", addrPort.Addr(), ErrForbiddenAddress)
+			}
+			return nil
+		},
+	}
+}
+
+// cgnat is the shared address space carriers use (RFC 6598), which
+// netip doesn't count as private.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublic reports whether addr is a globally routable unicast address.
+func IsPublic(addr netip.Addr) bool {
+	return addr.IsValid() &&
+		addr.IsGlobalUnicast() &&
+		!addr.IsPrivate() &&
+		!cgnat.Contains(addr)
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+)
+
+// Command runs a local program for each digest, with the digest's subject
+// and text as its last two arguments and the digest as JSON on stdin. With
+// Path "notify-send" this shows a desktop notification.
+//
+// The program is run directly, not through a shell, so post titles can't
+// inject commands.
+type Command struct {
+	Path string
+	Args []string
+}
+
+func (c Command) Notify(ctx context.Context, d Digest) error {
+	input, err := json.Marshal(d)
+	if err != nil {
+		return permanent(err)
+	}
+
+	args := append(c.Args[:len(c.Args):len(c.Args)], d.Subject(), d.Text())
+	cmd := exec.CommandContext(ctx, c.Path, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(output.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		var execErr *exec.Error
+		if errors.As(err, &execErr) || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+			// The program doesn't exist or can't be run.
+			return permanent(err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// Email sends each digest as a plain text email through an SMTP server,
+// using STARTTLS when the server offers it. Username and Password are
+// optional.
+type Email struct {
+	// Addr is the server's host:port.
+	Addr     string
+	Username string
+	Password string
+	From     string
+	To       string
+}
+
+func (e Email) Notify(ctx context.Context, d Digest) error {
+	from, err := mail.ParseAddress(e.From)
+	if err != nil {
+		return permanent(fmt.Errorf("invalid from address %q: %w", e.From, err))
+	}
+	to, err := mail.ParseAddress(e.To)
+	if err != nil {
+		return permanent(fmt.Errorf("invalid address %q: %w", e.To, err))
+	}
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return permanent(fmt.Errorf("invalid smtp server %q: %w", e.Addr, err))
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return smtpError(err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return smtpError(err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return smtpError(err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return smtpError(err)
+	}
+	if _, err := w.Write(message(from, to, d)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(err)
+	}
+	return client.Quit()
+}
+
+// smtpError marks 5xx replies, which mean the server won't ever take the
+// message, as permanent.
+func smtpError(err error) error {
+	if tpErr, ok := err.(*textproto.Error); ok && tpErr.Code >= 500 {
+		return permanent(err)
+	}
+	return err
+}
+
+func message(from, to *mail.Address, d Digest) []byte {
+	var b bytes.Buffer
+	header := func(name, value string) {
+		b.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", d.Subject()))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(d.Text()))
+	qp.Close()
+	return b.Bytes()
+}
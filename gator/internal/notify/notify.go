@@ -0,0 +1,106 @@
+// Package notify delivers digests of new posts to places outside gator: a
+// webhook, an email address or a local command such as notify-send.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Post is a post as it appears in a digest.
+type Post struct {
+	Title     string    `json:"title"`
+	URL       string    `json:"url"`
+	Feed      string    `json:"feed"`
+	Published time.Time `json:"published_at,omitzero"`
+}
+
+// Digest is a batch of new posts sent in one notification. More counts the
+// posts left out to keep the digest short; they are not sent later.
+type Digest struct {
+	Posts []Post `json:"posts"`
+	More  int    `json:"more"`
+}
+
+// Subject is a one line summary, used as an email subject or a desktop
+// notification's title.
+func (d Digest) Subject() string {
+	if len(d.Posts) == 1 && d.More == 0 {
+		return "gator: " + d.Posts[0].Title
+	}
+	return fmt.Sprintf("gator: %d new posts", len(d.Posts)+d.More)
+}
+
+// Text lists the posts as plain text.
+func (d Digest) Text() string {
+	var b strings.Builder
+	for i, post := range d.Posts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(post.Title)
+		if post.Feed != "" {
+			b.WriteString(" (" + post.Feed + ")")
+		}
+		b.WriteString("\n" + post.URL + "\n")
+	}
+	if d.More > 0 {
+		fmt.Fprintf(&b, "\n…and %d more\n", d.More)
+	}
+	return b.String()
+}
+
+// Notifier sends digests somewhere.
+type Notifier interface {
+	Notify(ctx context.Context, d Digest) error
+}
+
+// Retry is how often Send tries a notifier before giving up. The wait
+// between attempts starts at Backoff and doubles each time.
+type Retry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+var DefaultRetry = Retry{Attempts: 3, Backoff: 2 * time.Second}
+
+// Send delivers d with n, retrying failures that may be temporary.
+func Send(ctx context.Context, n Notifier, d Digest, retry Retry) error {
+	wait := retry.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = n.Notify(ctx, d)
+		if err == nil || IsPermanent(err) || attempt >= retry.Attempts {
+			return err
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		}
+		wait *= 2
+	}
+}
+
+// permanentError marks a failure that retrying won't fix, such as a webhook
+// rejecting the request or an unknown email address.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err is a failure that retrying won't fix.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
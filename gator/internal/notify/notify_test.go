@@ -0,0 +1,238 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testDigest = Digest{
+	Posts: []Post{
+		{Title: "Go 1.24 is released", URL: "https://go.dev/blog/go1.24", Feed: "The Go Blog"},
+		{Title: "Ünïcode in titles", URL: "https://example.com/unicode", Feed: "Example"},
+	},
+	More: 3,
+}
+
+var fastRetry = Retry{Attempts: 3, Backoff: time.Millisecond}
+
+func TestDigestText(t *testing.T) {
+	if got := testDigest.Subject(); got != "gator: 5 new posts" {
+		t.Fatalf("unexpected subject %q", got)
+	}
+	text := testDigest.Text()
+	for _, want := range []string{"Go 1.24 is released (The Go Blog)\nhttps://go.dev/blog/go1.24\n", "…and 3 more"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("expected %q in:\n%s", want, text)
+		}
+	}
+
+	single := Digest{Posts: testDigest.Posts[:1]}
+	if got := single.Subject(); got != "gator: Go 1.24 is released" {
+		t.Fatalf("unexpected subject %q", got)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var got Digest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected %s request with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("could not decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := Send(context.Background(), Webhook{URL: srv.URL}, testDigest, fastRetry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Posts) != 2 || got.Posts[0].URL != "https://go.dev/blog/go1.24" || got.More != 3 {
+		t.Fatalf("unexpected digest %+v", got)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		failures  int32
+		wantCalls int32
+		wantErr   bool
+		permanent bool
+	}{
+		{"recovers", http.StatusServiceUnavailable, 2, 3, false, false},
+		{"gives up", http.StatusBadGateway, 5, 3, true, false},
+		{"rate limited", http.StatusTooManyRequests, 1, 2, false, false},
+		{"rejected", http.StatusBadRequest, 5, 1, true, true},
+	}
+
+	for _, tt := range tests {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) <= tt.failures {
+				w.WriteHeader(tt.status)
+			}
+		}))
+
+		err := Send(context.Background(), Webhook{URL: srv.URL}, testDigest, fastRetry)
+		srv.Close()
+
+		if (err != nil) != tt.wantErr || IsPermanent(err) != tt.permanent {
+			t.Fatalf("%s: unexpected error %v", tt.name, err)
+		}
+		if calls.Load() != tt.wantCalls {
+			t.Fatalf("%s: expected %d calls, got %d", tt.name, tt.wantCalls, calls.Load())
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan smtpSession, 1)
+	go serveSMTP(t, ln, received)
+
+	email := Email{
+		Addr:     ln.Addr().String(),
+		Username: "gator",
+		Password: "secret",
+		From:     "gator <gator@example.com>",
+		To:       "collins@example.com",
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Send(ctx, email, testDigest, fastRetry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	session := <-received
+	if !session.authed {
+		t.Fatalf("expected the client to authenticate")
+	}
+	if session.from != "gator@example.com" || session.to != "collins@example.com" {
+		t.Fatalf("unexpected envelope from %q to %q", session.from, session.to)
+	}
+	for _, want := range []string{"Subject: gator: 5 new posts", "Content-Transfer-Encoding: quoted-printable", "https://go.dev/blog/go1.24"} {
+		if !strings.Contains(session.data, want) {
+			t.Fatalf("expected %q in message:\n%s", want, session.data)
+		}
+	}
+}
+
+func TestEmailInvalidAddress(t *testing.T) {
+	err := Send(context.Background(), Email{Addr: "127.0.0.1:1", From: "gator@example.com", To: "not an address"}, testDigest, fastRetry)
+	if !IsPermanent(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	out := filepath.Join(t.TempDir(), "out")
+	// $1 is the output file, $2 the subject; the digest comes on stdin.
+	cmd := Command{Path: "sh", Args: []string{"-c", `printf '%s\n' "$2" > "$1" && cat >> "$1"`, "sh", out}}
+	if err := Send(context.Background(), cmd, testDigest, fastRetry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("could not read output: %v", err)
+	}
+	subject, input, _ := strings.Cut(string(data), "\n")
+	if subject != "gator: 5 new posts" {
+		t.Fatalf("unexpected subject argument %q", subject)
+	}
+	var got Digest
+	if err := json.Unmarshal([]byte(input), &got); err != nil || len(got.Posts) != 2 {
+		t.Fatalf("unexpected stdin %q (%v)", input, err)
+	}
+
+	err = Send(context.Background(), Command{Path: filepath.Join(t.TempDir(), "missing")}, testDigest, fastRetry)
+	if !IsPermanent(err) {
+		t.Fatalf("expected a permanent error for a missing program, got %v", err)
+	}
+}
+
+type smtpSession struct {
+	authed   bool
+	from, to string
+	data     string
+}
+
+// serveSMTP is a stand-in SMTP server that accepts one message.
+func serveSMTP(t *testing.T, ln net.Listener, received chan<- smtpSession) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	var session smtpSession
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Errorf("smtp: %v", err)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			session.authed = true
+			reply("235 authenticated")
+		case "MAIL":
+			session.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+			reply("250 ok")
+		case "RCPT":
+			session.to = strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>")
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			session.data = data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			received <- session
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
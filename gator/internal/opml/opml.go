@@ -0,0 +1,188 @@
+// Package opml reads and writes OPML subscription lists, the format feed
+// readers use to move a user's feeds between them.
+package opml
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Subscription is a feed listed in an OPML file.
+type Subscription struct {
+	Title string
+	URL   string
+	// Category is the path of folders the feed was filed under, joined
+	// with "/". Empty for feeds at the top level.
+	Category string
+	// Tags come from the outline's category attribute, where OPML 2.0
+	// keeps tags as entries without a slash.
+	Tags []string
+}
+
+var ErrNotOPML = errors.New("not an OPML document")
+
+// outline is read with all its attributes so their names can be matched
+// regardless of case; exporters disagree on xmlUrl versus xmlurl.
+type outline struct {
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Outlines []outline  `xml:"outline"`
+}
+
+func (o outline) attr(name string) string {
+	for _, attr := range o.Attrs {
+		if strings.EqualFold(attr.Name.Local, name) {
+			return strings.TrimSpace(attr.Value)
+		}
+	}
+	return ""
+}
+
+// Parse reads the subscriptions in an OPML document. Outlines without an
+// xmlUrl are treated as folders. A feed outside any folder is filed under
+// the first path in its category attribute, such as "/Tech/Go".
+func Parse(r io.Reader) ([]Subscription, error) {
+	var doc struct {
+		XMLName xml.Name
+		Body    struct {
+			Outlines []outline `xml:"outline"`
+		} `xml:"body"`
+	}
+
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		encoding, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, err
+		}
+		return encoding.NewDecoder().Reader(input), nil
+	}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(doc.XMLName.Local, "opml") {
+		return nil, ErrNotOPML
+	}
+
+	var subs []Subscription
+	var walk func(outlines []outline, folders []string)
+	walk = func(outlines []outline, folders []string) {
+		for _, o := range outlines {
+			title := o.attr("title")
+			if title == "" {
+				title = o.attr("text")
+			}
+
+			url := o.attr("xmlUrl")
+			if url == "" {
+				if title != "" {
+					walk(o.Outlines, append(folders[:len(folders):len(folders)], title))
+				} else {
+					walk(o.Outlines, folders)
+				}
+				continue
+			}
+
+			if title == "" {
+				title = url
+			}
+			sub := Subscription{
+				Title:    title,
+				URL:      url,
+				Category: strings.Join(folders, "/"),
+			}
+			for _, category := range strings.Split(o.attr("category"), ",") {
+				category = strings.TrimSpace(category)
+				switch {
+				case category == "":
+				case !strings.Contains(category, "/"):
+					sub.Tags = append(sub.Tags, category)
+				case sub.Category == "":
+					sub.Category = strings.Trim(category, "/")
+				}
+			}
+			subs = append(subs, sub)
+		}
+	}
+	walk(doc.Body.Outlines, nil)
+
+	return subs, nil
+}
+
+type document struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated"`
+	} `xml:"head"`
+	Body struct {
+		Outlines []*writeOutline `xml:"outline"`
+	} `xml:"body"`
+}
+
+type writeOutline struct {
+	Text     string          `xml:"text,attr"`
+	Title    string          `xml:"title,attr,omitempty"`
+	Type     string          `xml:"type,attr,omitempty"`
+	XMLURL   string          `xml:"xmlUrl,attr,omitempty"`
+	Category string          `xml:"category,attr,omitempty"`
+	Outlines []*writeOutline `xml:"outline"`
+}
+
+// Write writes subs as an OPML 2.0 document, filing each subscription in
+// nested folders following its category.
+func Write(w io.Writer, title string, subs []Subscription) error {
+	doc := document{Version: "2.0"}
+	doc.Head.Title = title
+	doc.Head.DateCreated = time.Now().UTC().Format(time.RFC1123Z)
+
+	// folders maps a category path to its outline so feeds sharing a
+	// folder end up in the same one.
+	folders := map[string]*writeOutline{}
+	var folder func(path string) *[]*writeOutline
+	folder = func(path string) *[]*writeOutline {
+		if path == "" {
+			return &doc.Body.Outlines
+		}
+		if o, ok := folders[path]; ok {
+			return &o.Outlines
+		}
+
+		parent, name := "", path
+		if i := strings.LastIndex(path, "/"); i >= 0 {
+			parent, name = path[:i], path[i+1:]
+		}
+		o := &writeOutline{Text: name, Title: name}
+		siblings := folder(parent)
+		*siblings = append(*siblings, o)
+		folders[path] = o
+		return &o.Outlines
+	}
+
+	for _, sub := range subs {
+		outlines := folder(strings.Trim(sub.Category, "/"))
+		*outlines = append(*outlines, &writeOutline{
+			Text:     sub.Title,
+			Title:    sub.Title,
+			Type:     "rss",
+			XMLURL:   sub.URL,
+			Category: strings.Join(sub.Tags, ","),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,78 @@
+package opml_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ckm54/go-projects/gator/internal/opml"
+)
+
+const exported = `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>My feeds</title></head>
+  <body>
+    <outline text="Go blog" type="rss" xmlUrl="https://go.dev/blog/feed.atom"/>
+    <outline text="Tech" title="Tech">
+      <outline text="Hacker News" xmlurl="https://news.ycombinator.com/rss"/>
+      <outline text="Languages">
+        <outline title="Rust" text="ignored" xmlUrl="https://blog.rust-lang.org/feed.xml"/>
+      </outline>
+    </outline>
+    <outline xmlUrl="https://untitled.example/feed"/>
+    <outline text="Go weekly" xmlUrl="https://golangweekly.com/rss" category="/Tech/Go, go,newsletter"/>
+  </body>
+</opml>`
+
+func TestParse(t *testing.T) {
+	got, err := opml.Parse(strings.NewReader(exported))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []opml.Subscription{
+		{Title: "Go blog", URL: "https://go.dev/blog/feed.atom"},
+		{Title: "Hacker News", URL: "https://news.ycombinator.com/rss", Category: "Tech"},
+		{Title: "Rust", URL: "https://blog.rust-lang.org/feed.xml", Category: "Tech/Languages"},
+		{Title: "https://untitled.example/feed", URL: "https://untitled.example/feed"},
+		{Title: "Go weekly", URL: "https://golangweekly.com/rss", Category: "Tech/Go", Tags: []string{"go", "newsletter"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v\ngot      %+v", want, got)
+	}
+}
+
+func TestParseNotOPML(t *testing.T) {
+	_, err := opml.Parse(strings.NewReader(`<rss version="2.0"><channel/></rss>`))
+	if !errors.Is(err, opml.ErrNotOPML) {
+		t.Fatalf("expected ErrNotOPML, got %v", err)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	subs := []opml.Subscription{
+		{Title: "Go blog", URL: "https://go.dev/blog/feed.atom"},
+		{Title: "Hacker News", URL: "https://news.ycombinator.com/rss", Category: "Tech"},
+		{Title: "Rust & friends", URL: "https://blog.rust-lang.org/feed.xml?a=1&b=2", Category: "Tech/Languages"},
+		{Title: "Lobsters", URL: "https://lobste.rs/rss", Category: "Tech", Tags: []string{"news", "programming"}},
+	}
+
+	var buf bytes.Buffer
+	if err := opml.Write(&buf, "gator export", subs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<opml version="2.0">`) {
+		t.Fatalf("expected an OPML 2.0 document, got:\n%s", buf.String())
+	}
+
+	got, err := opml.Parse(&buf)
+	if err != nil {
+		t.Fatalf("could not parse written document: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, subs) {
+		t.Fatalf("expected %+v\ngot      %+v", subs, got)
+	}
+}
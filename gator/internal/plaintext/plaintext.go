@@ -0,0 +1,107 @@
+// Package plaintext turns the HTML found in feeds into readable text.
+package plaintext
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockElements start on a new line when rendered as text.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Hr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Table: true, atom.Tr: true, atom.Section: true, atom.Article: true, atom.Figure: true,
+}
+
+// FromHTML renders HTML as plain paragraphs: tags are dropped, block
+// elements become paragraph breaks, list items get a bullet and links keep
+// their target.
+func FromHTML(s string) string {
+	tokenizer := html.NewTokenizer(strings.NewReader(s))
+
+	var b strings.Builder
+	skip := 0
+	var href string
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return tidyText(b.String())
+
+		case html.TextToken:
+			if skip == 0 {
+				b.Write(tokenizer.Text())
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Script, atom.Style:
+				if token.Type == html.StartTagToken {
+					skip++
+				}
+			case atom.Li:
+				b.WriteString("\n• ")
+			case atom.A:
+				href = attr(token, "href")
+			case atom.Img:
+				if alt := attr(token, "alt"); alt != "" {
+					b.WriteString("[" + alt + "]")
+				}
+			default:
+				if blockElements[token.DataAtom] {
+					b.WriteString("\n\n")
+				}
+			}
+
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Script, atom.Style:
+				if skip > 0 {
+					skip--
+				}
+			case atom.A:
+				if href != "" && !strings.HasPrefix(href, "#") {
+					b.WriteString(" <" + href + ">")
+				}
+				href = ""
+			default:
+				if blockElements[token.DataAtom] && token.DataAtom != atom.Li {
+					b.WriteString("\n\n")
+				}
+			}
+		}
+	}
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// tidyText collapses whitespace within lines and keeps at most one blank
+// line between paragraphs.
+func tidyText(s string) string {
+	var lines []string
+	blank := true
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,23 @@
+package plaintext
+
+import "testing"
+
+func TestFromHTML(t *testing.T) {
+	tests := map[string]string{
+		"plain text":                                  "plain text",
+		"<p>One</p><p>Two  <b>bold</b></p>":           "One\n\nTwo bold",
+		"Line<br>break":                               "Line\n\nbreak",
+		"<ul><li>a</li><li>b</li></ul>":               "• a\n• b",
+		`See <a href="https://go.dev">Go</a>`:         "See Go <https://go.dev>",
+		`<a href="#top">top</a>`:                      "top",
+		"<style>p{}</style><script>x()</script>Shown": "Shown",
+		`<img src="x.png" alt="A chart">`:             "[A chart]",
+		"Caf&eacute; &amp; bar":                       "Café & bar",
+	}
+
+	for input, want := range tests {
+		if got := FromHTML(input); got != want {
+			t.Errorf("FromHTML(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
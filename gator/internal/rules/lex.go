@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokArrow
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos is the byte offset of the token in the source, for errors.
+	pos int
+}
+
+// lex splits src into words, quoted strings, operators, parentheses and
+// arrows. Strings use double quotes; inside them \" is a quote and \\ a
+// backslash, and any other backslash is kept so regular expressions can be
+// written as usual.
+func lex(src string) ([]token, error) {
+	var toks []token
+
+	for i := 0; i < len(src); {
+		rest := src[i:]
+		r := rune(src[i])
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			toks = append(toks, token{kind: tokLParen, text: "(", pos: i})
+			i++
+
+		case r == ')':
+			toks = append(toks, token{kind: tokRParen, text: ")", pos: i})
+			i++
+
+		case strings.HasPrefix(rest, "->"):
+			toks = append(toks, token{kind: tokArrow, text: "->", pos: i})
+			i += 2
+
+		case strings.HasPrefix(rest, "→"):
+			toks = append(toks, token{kind: tokArrow, text: "→", pos: i})
+			i += len("→")
+
+		case strings.HasPrefix(rest, "!="):
+			toks = append(toks, token{kind: tokOp, text: "!=", pos: i})
+			i += 2
+
+		case r == '=':
+			toks = append(toks, token{kind: tokOp, text: "=", pos: i})
+			i++
+
+		case r == '"':
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			toks = append(toks, token{kind: tokString, text: unquote(rest[1:end]), pos: i})
+			i += end + 1
+
+		default:
+			end := strings.IndexFunc(rest, func(r rune) bool {
+				return unicode.IsSpace(r) || strings.ContainsRune(`()"=!`, r) || r == '→'
+			})
+			if strings.HasPrefix(rest, "!") {
+				return nil, fmt.Errorf("unexpected ! at %d: use not or !=", i)
+			}
+			if end < 0 {
+				end = len(rest)
+			}
+			// A word runs up to an arrow, so "star->read" isn't one word.
+			if arrow := strings.Index(rest[:end], "->"); arrow > 0 {
+				end = arrow
+			}
+			toks = append(toks, token{kind: tokWord, text: rest[:end], pos: i})
+			i += end
+		}
+	}
+
+	return toks, nil
+}
+
+// quote is the inverse of unquote.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			b.WriteString(`\"`)
+		case s[i] == '\\' && (i+1 == len(s) || s[i+1] == '"' || s[i+1] == '\\'):
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func unquote(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// closingQuote returns the index of the quote ending the string that s
+// starts with, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,386 @@
+// Package rules implements the small language gator users write filters
+// in. A rule is a condition and an action joined by an arrow:
+//
+//	title contains "kubernetes" and not feed = "Hacker News" -> star
+//	category = golang -> tag go
+//	feed = "Go Blog" and title matches "(?i)^go 1\.\d+" -> read
+//	author = "Russ Cox" -> notify
+//
+// Conditions compare a post's fields with contains, =, != and matches
+// (a regular expression), and combine with and, or, not and parentheses.
+// Comparisons other than matches ignore case.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Post holds the fields a condition can look at.
+type Post struct {
+	Title       string
+	Description string
+	Content     string
+	URL         string
+	Author      string
+	Feed        string
+	FeedURL     string
+	Categories  []string
+}
+
+// Fields are the names conditions may use.
+var Fields = []string{"title", "description", "content", "url", "author", "feed", "feed_url", "category"}
+
+// Actions a rule can take.
+const (
+	ActionStar    = "star"
+	ActionRead    = "read"
+	ActionArchive = "archive"
+	ActionTag     = "tag"
+	ActionNotify  = "notify" // send the post to the user's notification sinks
+)
+
+type Action struct {
+	Kind string
+	// Tag is the tag to add, for ActionTag.
+	Tag string
+}
+
+func (a Action) String() string {
+	if a.Kind == ActionTag {
+		return a.Kind + " " + a.Tag
+	}
+	return a.Kind
+}
+
+// NormalizeTag lowercases tag and checks it can be stored, and listed
+// with commas, as a tag. Rules tag posts; users tag feeds the same way.
+func NormalizeTag(tag string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(tag))
+	if normalized == "" || strings.ContainsFunc(normalized, func(r rune) bool { return r == ' ' || r == '\t' || r == ',' }) {
+		return "", fmt.Errorf("invalid tag %q: tags can't be empty or contain spaces or commas", tag)
+	}
+	return normalized, nil
+}
+
+// Condition is a parsed condition, ready to be matched against posts.
+type Condition struct {
+	root node
+}
+
+func (c Condition) Match(p Post) bool {
+	return c.root.match(&p)
+}
+
+// String renders the condition in a canonical form.
+func (c Condition) String() string {
+	return c.root.String()
+}
+
+type Rule struct {
+	Condition Condition
+	Action    Action
+}
+
+// ParseRule parses "<condition> -> <action>". → works as the arrow too.
+func ParseRule(src string) (Rule, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return Rule{}, err
+	}
+
+	arrow := slices.IndexFunc(toks, func(t token) bool { return t.kind == tokArrow })
+	if arrow < 0 {
+		return Rule{}, fmt.Errorf(`missing "->" between condition and action`)
+	}
+
+	cond, err := parseTokens(toks[:arrow])
+	if err != nil {
+		return Rule{}, err
+	}
+	action, err := parseActionTokens(toks[arrow+1:])
+	if err != nil {
+		return Rule{}, err
+	}
+	return Rule{Condition: cond, Action: action}, nil
+}
+
+// ParseCondition parses a condition on its own.
+func ParseCondition(src string) (Condition, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return Condition{}, err
+	}
+	return parseTokens(toks)
+}
+
+// ParseAction parses an action such as "star" or "tag golang".
+func ParseAction(src string) (Action, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return Action{}, err
+	}
+	return parseActionTokens(toks)
+}
+
+func parseActionTokens(toks []token) (Action, error) {
+	if len(toks) == 0 || toks[0].kind != tokWord {
+		return Action{}, fmt.Errorf("missing action: use star, read, archive, notify or tag <name>")
+	}
+
+	kind := strings.ToLower(toks[0].text)
+	switch kind {
+	case ActionStar, ActionRead, ActionArchive, ActionNotify:
+		if len(toks) > 1 {
+			return Action{}, fmt.Errorf("unexpected %q after %s", toks[1].text, kind)
+		}
+		return Action{Kind: kind}, nil
+
+	case ActionTag:
+		if len(toks) != 2 || (toks[1].kind != tokWord && toks[1].kind != tokString) {
+			return Action{}, fmt.Errorf("tag needs exactly one tag name")
+		}
+		tag, err := NormalizeTag(toks[1].text)
+		if err != nil {
+			return Action{}, err
+		}
+		return Action{Kind: kind, Tag: tag}, nil
+
+	default:
+		return Action{}, fmt.Errorf("unknown action %q: use star, read, archive, notify or tag <name>", toks[0].text)
+	}
+}
+
+// The grammar, loosest binding first:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field op value
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func parseTokens(toks []token) (Condition, error) {
+	if len(toks) == 0 {
+		return Condition{}, fmt.Errorf("empty condition")
+	}
+
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return Condition{}, err
+	}
+	if p.pos < len(p.toks) {
+		t := p.toks[p.pos]
+		return Condition{}, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+	return Condition{root: root}, nil
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.toks) {
+		return token{}, false
+	}
+	return p.toks[p.pos], true
+}
+
+func (p *parser) keyword(word string) bool {
+	t, ok := p.peek()
+	if ok && t.kind == tokWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.keyword("not") {
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("condition ends too early")
+	}
+	if t.kind == tokLParen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if next, ok := p.peek(); !ok || next.kind != tokRParen {
+			return nil, fmt.Errorf("missing ) for the ( at %d", t.pos)
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	field, _ := p.peek()
+	if field.kind != tokWord || !slices.Contains(Fields, strings.ToLower(field.text)) {
+		return nil, fmt.Errorf("unknown field %q at %d: use one of %s", field.text, field.pos, strings.Join(Fields, ", "))
+	}
+	p.pos++
+
+	opTok, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("missing operator after %s", field.text)
+	}
+	op := strings.ToLower(opTok.text)
+	switch {
+	case opTok.kind == tokOp, opTok.kind == tokWord && (op == "contains" || op == "matches"):
+	default:
+		return nil, fmt.Errorf("unknown operator %q at %d: use contains, =, != or matches", opTok.text, opTok.pos)
+	}
+	p.pos++
+
+	value, ok := p.peek()
+	if !ok || (value.kind != tokString && value.kind != tokWord) {
+		return nil, fmt.Errorf("missing value after %s %s", field.text, opTok.text)
+	}
+	p.pos++
+
+	cmp := cmpNode{field: strings.ToLower(field.text), op: op, value: value.text}
+	if op == "matches" {
+		re, err := regexp.Compile(value.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", value.text, err)
+		}
+		cmp.re = re
+	}
+	return cmp, nil
+}
+
+type node interface {
+	match(p *Post) bool
+	String() string
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(p *Post) bool { return n.left.match(p) && n.right.match(p) }
+func (n andNode) String() string     { return group(n.left, n) + " and " + group(n.right, n) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(p *Post) bool { return n.left.match(p) || n.right.match(p) }
+func (n orNode) String() string     { return group(n.left, n) + " or " + group(n.right, n) }
+
+type notNode struct{ inner node }
+
+func (n notNode) match(p *Post) bool { return !n.inner.match(p) }
+func (n notNode) String() string {
+	if _, ok := n.inner.(cmpNode); ok {
+		return "not " + n.inner.String()
+	}
+	return "not (" + n.inner.String() + ")"
+}
+
+// group parenthesizes an or inside an and, the only place the canonical
+// form needs parentheses to keep its meaning.
+func group(child, parent node) string {
+	_, childOr := child.(orNode)
+	_, parentAnd := parent.(andNode)
+	if childOr && parentAnd {
+		return "(" + child.String() + ")"
+	}
+	return child.String()
+}
+
+type cmpNode struct {
+	field string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+func (n cmpNode) String() string {
+	return n.field + " " + n.op + " " + quote(n.value)
+}
+
+func (n cmpNode) match(p *Post) bool {
+	if n.field == "category" {
+		// != means no category is equal; the other operators match if any
+		// category does.
+		if n.op == "!=" {
+			return !slices.ContainsFunc(p.Categories, func(c string) bool { return strings.EqualFold(c, n.value) })
+		}
+		return slices.ContainsFunc(p.Categories, n.matchValue)
+	}
+	return n.matchValue(n.fieldValue(p))
+}
+
+func (n cmpNode) matchValue(v string) bool {
+	switch n.op {
+	case "contains":
+		return strings.Contains(strings.ToLower(v), strings.ToLower(n.value))
+	case "=":
+		return strings.EqualFold(v, n.value)
+	case "!=":
+		return !strings.EqualFold(v, n.value)
+	case "matches":
+		return n.re.MatchString(v)
+	}
+	return false
+}
+
+func (n cmpNode) fieldValue(p *Post) string {
+	switch n.field {
+	case "title":
+		return p.Title
+	case "description":
+		return p.Description
+	case "content":
+		return p.Content
+	case "url":
+		return p.URL
+	case "author":
+		return p.Author
+	case "feed":
+		return p.Feed
+	case "feed_url":
+		return p.FeedURL
+	}
+	return ""
+}
@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+var testPost = Post{
+	Title:       "Go 1.24 is released",
+	Description: "Generic type aliases, faster maps and more.",
+	URL:         "https://go.dev/blog/go1.24",
+	Author:      "The Go Team",
+	Feed:        "The Go Blog",
+	FeedURL:     "https://go.dev/blog/feed.atom",
+	Categories:  []string{"Release", "golang"},
+}
+
+func TestMatch(t *testing.T) {
+	tests := map[string]bool{
+		`title contains "go 1.24"`:                                           true,
+		`title contains kubernetes`:                                          false,
+		`title = "go 1.24 is released"`:                                      true,
+		`author != "The Go Team"`:                                            false,
+		`feed = "The Go Blog" and title matches "^Go 1\.\d+"`:                true,
+		`feed = "The Go Blog" and title matches "^go 1\.\d+"`:                false,
+		`title matches "(?i)^go 1\.\d+"`:                                     true,
+		`category = golang`:                                                  true,
+		`category = GOLANG`:                                                  true,
+		`category contains rel`:                                              true,
+		`category != golang`:                                                 false,
+		`category != rust`:                                                   true,
+		`not title contains go`:                                              false,
+		`title contains rust or description contains maps`:                   true,
+		`title contains rust or title contains zig and url contains go.dev`:  false,
+		`(title contains rust or title contains go) and url contains go.dev`: true,
+		`feed_url contains "go.dev"`:                                         true,
+		`content contains anything`:                                          false,
+		`TITLE CONTAINS go AND NOT (author = bob)`:                           true,
+	}
+
+	for src, want := range tests {
+		cond, err := ParseCondition(src)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", src, err)
+		}
+		if got := cond.Match(testPost); got != want {
+			t.Fatalf("%s: expected %v, got %v", src, want, got)
+		}
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		src    string
+		action Action
+	}{
+		{`title contains X -> star`, Action{Kind: ActionStar}},
+		{`feed = "Hacker News" and title matches "(?i)\bask hn\b" -> read`, Action{Kind: ActionRead}},
+		{`category = "Kubernetes" → tag k8s`, Action{Kind: ActionTag, Tag: "k8s"}},
+		{`url contains sponsored->archive`, Action{Kind: ActionArchive}},
+		{`author = "Russ Cox" -> NOTIFY`, Action{Kind: ActionNotify}},
+		{`author = bob -> tag "From Bob"`, Action{}},
+	}
+
+	for _, tt := range tests {
+		rule, err := ParseRule(tt.src)
+		if tt.action == (Action{}) {
+			if err == nil {
+				t.Fatalf("%s: expected an error", tt.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.src, err)
+		}
+		if rule.Action != tt.action {
+			t.Fatalf("%s: expected %v, got %v", tt.src, tt.action, rule.Action)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		`title contains go`:                   `missing "->"`,
+		`-> star`:                             "empty condition",
+		`body contains go -> star`:            "unknown field",
+		`title has go -> star`:                "unknown operator",
+		`title contains -> star`:              "missing value",
+		`(title contains go -> star`:          "missing )",
+		`title matches "(" -> star`:           "invalid regular expression",
+		`title contains "go -> star`:          "unterminated string",
+		`title contains go -> explode`:        "unknown action",
+		`title contains go -> tag`:            "exactly one tag",
+		`title contains go and -> star`:       "ends too early",
+		`title contains go title = x -> star`: "unexpected",
+		`!title contains go -> star`:          "use not",
+	}
+
+	for src, want := range tests {
+		_, err := ParseRule(src)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected an error containing %q, got %v", src, want, err)
+		}
+	}
+}
+
+func TestStringRoundTrips(t *testing.T) {
+	tests := []string{
+		`title contains "go" and (feed = "a" or feed = "b")`,
+		`not (title contains "x" or url matches "\.pdf$")`,
+		`description contains "say \"hi\"" or author = "back\\slash\\"`,
+	}
+
+	for _, src := range tests {
+		cond, err := ParseCondition(src)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", src, err)
+		}
+		again, err := ParseCondition(cond.String())
+		if err != nil {
+			t.Fatalf("%s: could not parse %s: %v", src, cond.String(), err)
+		}
+		if again.String() != cond.String() {
+			t.Fatalf("expected %s, got %s", cond.String(), again.String())
+		}
+	}
+}
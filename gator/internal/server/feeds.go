@@ -0,0 +1,171 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/google/uuid"
+)
+
+type userRes struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+}
+
+type feedRes struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	URL     string    `json:"url"`
+	AddedBy string    `json:"added_by,omitempty"`
+}
+
+type followRes struct {
+	FeedID     uuid.UUID `json:"feed_id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Category   string    `json:"category,omitempty"`
+	Tags       []string  `json:"tags"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request, user database.User) {
+	respondWithJSON(w, http.StatusOK, userRes{ID: user.ID, CreatedAt: user.CreatedAt, Name: user.Name})
+}
+
+func (s *Server) handleListFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	feeds, err := s.db.GetFeeds(r.Context())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not get feeds")
+		return
+	}
+
+	res := make([]feedRes, 0, len(feeds))
+	for _, feed := range feeds {
+		res = append(res, feedRes{ID: feed.ID, Name: feed.FeedName, URL: feed.Url, AddedBy: feed.UserName})
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handleAddFeed adds a feed if gator doesn't know its URL yet, and follows
+// it for the caller either way.
+func (s *Server) handleAddFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+
+	params := parameters{}
+	if err := decodeJSON(r, &params); err != nil || params.Name == "" || params.URL == "" {
+		respondWithError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+	if u, err := url.Parse(params.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		respondWithError(w, http.StatusBadRequest, "url must be an http or https URL")
+		return
+	}
+
+	feed, err := s.db.GetFeedByUrl(r.Context(), params.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		feed, err = s.db.CreateFeed(r.Context(), database.CreateFeedParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			Name:      params.Name,
+			Url:       params.URL,
+			UserID:    user.ID,
+		})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not add feed")
+		return
+	}
+
+	if !s.follow(w, r, user, feed.ID) {
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, feedRes{ID: feed.ID, Name: feed.Name, URL: feed.Url})
+}
+
+func (s *Server) handleListFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := s.db.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not get follows")
+		return
+	}
+
+	res := make([]followRes, 0, len(follows))
+	for _, follow := range follows {
+		res = append(res, followRes{
+			FeedID:     follow.FeedID,
+			Name:       follow.FeedName,
+			URL:        follow.FeedUrl,
+			Category:   follow.Category.String,
+			Tags:       follow.Tags,
+			FollowedAt: follow.CreatedAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) handleFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	type parameters struct {
+		FeedID uuid.UUID `json:"feed_id"`
+	}
+
+	params := parameters{}
+	if err := decodeJSON(r, &params); err != nil || params.FeedID == uuid.Nil {
+		respondWithError(w, http.StatusBadRequest, "feed_id is required")
+		return
+	}
+
+	_, err := s.db.GetFeedById(r.Context(), params.FeedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, http.StatusNotFound, "Feed not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not get feed")
+		return
+	}
+
+	if s.follow(w, r, user, params.FeedID) {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// follow makes user follow feedID, writing an error response and returning
+// false if that fails.
+func (s *Server) follow(w http.ResponseWriter, r *http.Request, user database.User, feedID uuid.UUID) bool {
+	_, err := s.db.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    feedID,
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not follow feed")
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleUnfollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	feedID, err := uuid.Parse(r.PathValue("feedID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid feed id")
+		return
+	}
+
+	err = s.db.UnfollowFeed(r.Context(), database.UnfollowFeedParams{UserID: user.ID, FeedID: feedID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not unfollow feed")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,252 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/plaintext"
+	"github.com/google/uuid"
+)
+
+type postRes struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Description string     `json:"description,omitempty"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Read        bool       `json:"read"`
+	Starred     bool       `json:"starred"`
+	Tags        []string   `json:"tags"`
+	// ContentHTML and ContentText are the saved article, if any.
+	ContentHTML string `json:"content_html,omitempty"`
+	ContentText string `json:"content_text,omitempty"`
+}
+
+type searchResultRes struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	PublishedAt *time.Time `json:"published_at"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Rank        float32    `json:"rank"`
+	// Snippet is plain text with matches wrapped in « and ».
+	Snippet string `json:"snippet"`
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// handleListPosts lists the caller's posts, newest first. It takes the same
+// filters as "gator browse": unread, starred, archived, feed_id, folder and
+// tag.
+func (s *Server) handleListPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	params := database.GetPostsForUserParams{UserID: user.ID}
+
+	var err error
+	if params.RowLimit, params.RowOffset, err = page(r); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.FeedID, err = feedIDParam(r); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if folder := strings.Trim(r.URL.Query().Get("folder"), "/"); folder != "" {
+		params.Folder = sql.NullString{String: folder, Valid: true}
+	}
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		params.Tag = sql.NullString{String: strings.ToLower(tag), Valid: true}
+	}
+	for name, flag := range map[string]*bool{
+		"unread":   &params.UnreadOnly,
+		"starred":  &params.StarredOnly,
+		"archived": &params.IncludeArchived,
+	} {
+		if *flag, err = boolParam(r, name); err != nil {
+			respondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	posts, err := s.db.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not get posts")
+		return
+	}
+
+	res := make([]postRes, 0, len(posts))
+	for _, post := range posts {
+		res = append(res, postRes{
+			ID:          post.ID,
+			Title:       post.Title,
+			URL:         post.Url,
+			Description: post.Description.String,
+			PublishedAt: nullTime(post.PublishedAt),
+			FeedID:      post.FeedID,
+			FeedName:    post.FeedName,
+			Read:        post.IsRead,
+			Starred:     post.IsStarred,
+			Tags:        post.Tags,
+			ContentHTML: post.ContentHtml.String,
+			ContentText: post.ContentText.String,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+// handleUpdatePostState sets any of read, starred and archived on a post.
+// Fields left out of the body are not changed.
+func (s *Server) handleUpdatePostState(w http.ResponseWriter, r *http.Request, user database.User) {
+	postID, err := uuid.Parse(r.PathValue("postID"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid post id")
+		return
+	}
+
+	type parameters struct {
+		Read     *bool `json:"read"`
+		Starred  *bool `json:"starred"`
+		Archived *bool `json:"archived"`
+	}
+	params := parameters{}
+	if err := decodeJSON(r, &params); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	// Only posts from followed feeds are visible to the caller.
+	posts, err := s.db.FindPostsForUserByPrefix(r.Context(), database.FindPostsForUserByPrefixParams{
+		UserID: user.ID,
+		Prefix: postID.String(),
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not get post")
+		return
+	}
+	if len(posts) == 0 {
+		respondWithError(w, http.StatusNotFound, "Post not found")
+		return
+	}
+
+	ctx := r.Context()
+	if params.Read != nil {
+		err = s.db.SetPostRead(ctx, database.SetPostReadParams{UserID: user.ID, PostID: postID, Read: *params.Read})
+	}
+	if err == nil && params.Starred != nil {
+		err = s.db.SetPostStarred(ctx, database.SetPostStarredParams{UserID: user.ID, PostID: postID, Starred: *params.Starred})
+	}
+	if err == nil && params.Archived != nil {
+		err = s.db.SetPostArchived(ctx, database.SetPostArchivedParams{UserID: user.ID, PostID: postID, Archived: *params.Archived})
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not update post")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSearch runs a full-text search like "gator search". q uses web
+// search syntax; since and until are dates and sort is rank or date.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+	params := database.SearchPostsParams{
+		Query:  strings.TrimSpace(query.Get("q")),
+		UserID: user.ID,
+	}
+	if params.Query == "" {
+		respondWithError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+
+	switch query.Get("sort") {
+	case "", "rank":
+	case "date":
+		params.ByDate = true
+	default:
+		respondWithError(w, http.StatusBadRequest, "sort must be rank or date")
+		return
+	}
+
+	var err error
+	if params.RowLimit, params.RowOffset, err = page(r); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.FeedID, err = feedIDParam(r); err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if params.Since, err = dateParam(query.Get("since"), false); err != nil {
+		respondWithError(w, http.StatusBadRequest, "since must be a date")
+		return
+	}
+	if params.Until, err = dateParam(query.Get("until"), true); err != nil {
+		respondWithError(w, http.StatusBadRequest, "until must be a date")
+		return
+	}
+
+	results, err := s.db.SearchPosts(r.Context(), params)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Could not search posts")
+		return
+	}
+
+	res := make([]searchResultRes, 0, len(results))
+	for _, result := range results {
+		res = append(res, searchResultRes{
+			ID:          result.ID,
+			Title:       result.Title,
+			URL:         result.Url,
+			PublishedAt: nullTime(result.PublishedAt),
+			FeedID:      result.FeedID,
+			FeedName:    result.FeedName,
+			Rank:        result.Rank,
+			Snippet:     strings.Join(strings.Fields(plaintext.FromHTML(result.Snippet)), " "),
+		})
+	}
+	respondWithJSON(w, http.StatusOK, res)
+}
+
+func feedIDParam(r *http.Request) (uuid.NullUUID, error) {
+	v := r.URL.Query().Get("feed_id")
+	if v == "" {
+		return uuid.NullUUID{}, nil
+	}
+	id, err := uuid.Parse(v)
+	if err != nil {
+		return uuid.NullUUID{}, errors.New("feed_id must be a feed id")
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+// dateParam parses a YYYY-MM-DD or RFC 3339 date. A plain date used as an
+// upper bound covers the whole of that day.
+func dateParam(value string, endOfDay bool) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return sql.NullTime{Time: t, Valid: true}, nil
+}
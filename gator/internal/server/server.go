@@ -0,0 +1,158 @@
+// Package server exposes gator's reader over a JSON HTTP API, so web and
+// mobile clients can use the same database as the CLI.
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/ckm54/go-projects/gator/internal/auth"
+	"github.com/ckm54/go-projects/gator/internal/database"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Server struct {
+	db *database.Queries
+}
+
+func New(db *database.Queries) *Server {
+	return &Server{db: db}
+}
+
+// Handler returns the API's routes. Everything under /api needs a token
+// made with "gator token create".
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		respondWithJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	mux.HandleFunc("GET /api/me", s.authed(s.handleMe))
+	mux.HandleFunc("GET /api/feeds", s.authed(s.handleListFeeds))
+	mux.HandleFunc("POST /api/feeds", s.authed(s.handleAddFeed))
+	mux.HandleFunc("GET /api/follows", s.authed(s.handleListFollows))
+	mux.HandleFunc("POST /api/follows", s.authed(s.handleFollow))
+	mux.HandleFunc("DELETE /api/follows/{feedID}", s.authed(s.handleUnfollow))
+	mux.HandleFunc("GET /api/posts", s.authed(s.handleListPosts))
+	mux.HandleFunc("PATCH /api/posts/{postID}", s.authed(s.handleUpdatePostState))
+	mux.HandleFunc("GET /api/search", s.authed(s.handleSearch))
+
+	return logRequests(mux)
+}
+
+type authedHandler func(w http.ResponseWriter, r *http.Request, user database.User)
+
+// authed resolves the bearer token to its user before calling next.
+func (s *Server) authed(next authedHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		user, err := s.db.GetUserByAPIToken(r.Context(), auth.HashToken(token))
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Could not check token")
+			return
+		}
+
+		next(w, r, user)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		log.Printf("%s %s %d", r.Method, r.URL.Path, recorder.status)
+	})
+}
+
+type errorRes struct {
+	Error string `json:"error"`
+}
+
+func respondWithError(w http.ResponseWriter, code int, msg string) {
+	respondWithJSON(w, code, errorRes{Error: msg})
+}
+
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error": "Error marshaling json"}`))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// page reads the limit and offset query parameters.
+func page(r *http.Request) (limit, offset int32, err error) {
+	limit, offset = defaultPageSize, 0
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, errors.New("limit must be between 1 and 100")
+		}
+		limit = int32(n)
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		// Parsed as 32 bits so a huge offset is refused rather than wrapping.
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("offset must be between 0 and %d", math.MaxInt32)
+		}
+		offset = int32(n)
+	}
+	return limit, offset, nil
+}
+
+// boolParam reports whether a query flag such as ?unread=true is set.
+func boolParam(r *http.Request, name string) (bool, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.New(name + " must be true or false")
+	}
+	return b, nil
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+)
+
+func TestHealthz(t *testing.T) {
+	srv := New(database.New(nil)).Handler()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	srv := New(database.New(nil)).Handler()
+
+	tests := []struct {
+		method string
+		path   string
+		header string
+	}{
+		{http.MethodGet, "/api/me", ""},
+		{http.MethodGet, "/api/posts", "Token abc"},
+		{http.MethodPatch, "/api/posts/6f1c1f0e-5f5e-4a55-9b9e-2d0d4f3b1c11", ""},
+		{http.MethodGet, "/api/search?q=go", "Bearer "},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s %s: expected 401, got %d", tt.method, tt.path, rec.Code)
+		}
+	}
+}
+
+func TestPage(t *testing.T) {
+	tests := []struct {
+		query  string
+		limit  int32
+		offset int32
+		ok     bool
+	}{
+		{"", defaultPageSize, 0, true},
+		{"?limit=5&offset=10", 5, 10, true},
+		{"?limit=0", 0, 0, false},
+		{"?limit=101", 0, 0, false},
+		{"?offset=-1", 0, 0, false},
+		{"?offset=2147483647", defaultPageSize, 2147483647, true},
+		{"?offset=2147483648", 0, 0, false},
+		{"?offset=9223372036854775807", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		limit, offset, err := page(httptest.NewRequest(http.MethodGet, "/api/posts"+tt.query, nil))
+		if (err == nil) != tt.ok {
+			t.Fatalf("%q: unexpected error %v", tt.query, err)
+		}
+		if tt.ok && (limit != tt.limit || offset != tt.offset) {
+			t.Fatalf("%q: expected %d/%d, got %d/%d", tt.query, tt.limit, tt.offset, limit, offset)
+		}
+	}
+}
@@ -0,0 +1,469 @@
+// Package tui is gator's interactive terminal reader: a feed list, a post
+// list and a pane to read the selected post.
+package tui
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+
+	"github.com/ckm54/go-projects/gator/internal/database"
+	"github.com/ckm54/go-projects/gator/internal/plaintext"
+)
+
+// postPageSize is how many posts are loaded for the selected feed.
+const postPageSize = 200
+
+const helpText = "tab switch pane · ↑/↓ move · enter read · r read/unread · s star · o open in browser · q quit"
+
+type pane int
+
+const (
+	feedsPane pane = iota
+	postsPane
+	readerPane
+)
+
+var (
+	paneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	focusedStyle = paneStyle.BorderForeground(lipgloss.Color("69"))
+	cursorStyle  = lipgloss.NewStyle().Reverse(true)
+	readStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("245"))
+	titleStyle   = lipgloss.NewStyle().Bold(true)
+	statusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+)
+
+// Run starts the reader for user and blocks until they quit.
+func Run(db *database.Queries, user database.User) error {
+	m := &model{db: db, user: user}
+	_, err := tea.NewProgram(m, tea.WithAltScreen()).Run()
+	return err
+}
+
+// feedEntry is a row of the feed list. The first row, with no ID, shows
+// posts from every followed feed.
+type feedEntry struct {
+	ID     uuid.NullUUID
+	Name   string
+	Unread int64
+}
+
+type model struct {
+	db   *database.Queries
+	user database.User
+
+	feeds      []feedEntry
+	feedCursor int
+	posts      []database.GetPostsForUserRow
+	postCursor int
+
+	focus  pane
+	scroll int
+	width  int
+	height int
+	status string
+}
+
+type feedsLoadedMsg []feedEntry
+
+// feedCountsMsg refreshes the feed list without reloading the posts.
+type feedCountsMsg []feedEntry
+
+type postsLoadedMsg struct {
+	feedID uuid.NullUUID
+	posts  []database.GetPostsForUserRow
+}
+
+type statusMsg string
+
+type errMsg struct{ err error }
+
+func (m *model) Init() tea.Cmd {
+	return m.loadFeeds
+}
+
+func (m *model) loadFeeds() tea.Msg {
+	rows, err := m.db.GetFollowedFeedsWithUnread(context.Background(), m.user.ID)
+	if err != nil {
+		return errMsg{fmt.Errorf("could not load feeds: %w", err)}
+	}
+
+	all := feedEntry{Name: "All feeds"}
+	feeds := []feedEntry{all}
+	for _, row := range rows {
+		feeds[0].Unread += row.Unread
+		feeds = append(feeds, feedEntry{
+			ID:     uuid.NullUUID{UUID: row.ID, Valid: true},
+			Name:   row.Name,
+			Unread: row.Unread,
+		})
+	}
+	return feedsLoadedMsg(feeds)
+}
+
+// loadPosts loads the posts of the selected feed. Commands run on their
+// own goroutine, so the feed is read here rather than inside it.
+func (m *model) loadPosts() tea.Cmd {
+	params := database.GetPostsForUserParams{
+		UserID:   m.user.ID,
+		FeedID:   m.selectedFeed().ID,
+		RowLimit: postPageSize,
+	}
+	return func() tea.Msg {
+		posts, err := m.db.GetPostsForUser(context.Background(), params)
+		if err != nil {
+			return errMsg{fmt.Errorf("could not load posts: %w", err)}
+		}
+		return postsLoadedMsg{feedID: params.FeedID, posts: posts}
+	}
+}
+
+func (m *model) selectedFeed() feedEntry {
+	if m.feedCursor < len(m.feeds) {
+		return m.feeds[m.feedCursor]
+	}
+	return feedEntry{}
+}
+
+func (m *model) selectedPost() (*database.GetPostsForUserRow, bool) {
+	if m.postCursor < len(m.posts) {
+		return &m.posts[m.postCursor], true
+	}
+	return nil, false
+}
+
+func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case feedsLoadedMsg:
+		m.feeds = msg
+		m.feedCursor = min(m.feedCursor, len(m.feeds)-1)
+		return m, m.loadPosts()
+
+	case feedCountsMsg:
+		m.feeds = msg
+		m.feedCursor = min(m.feedCursor, len(m.feeds)-1)
+		return m, nil
+
+	case postsLoadedMsg:
+		// Ignore posts for a feed the cursor has already moved past.
+		if msg.feedID != m.selectedFeed().ID {
+			return m, nil
+		}
+		m.posts = msg.posts
+		m.postCursor = min(m.postCursor, max(len(m.posts)-1, 0))
+		return m, nil
+
+	case statusMsg:
+		m.status = string(msg)
+		return m, nil
+
+	case errMsg:
+		m.status = "⚠️ " + msg.err.Error()
+		return m, nil
+
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m *model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m, tea.Quit
+
+	case "tab":
+		switch m.focus {
+		case feedsPane:
+			m.focus = postsPane
+		case postsPane:
+			m.focus = feedsPane
+		}
+		return m, nil
+
+	case "esc", "left", "h":
+		switch m.focus {
+		case readerPane:
+			m.focus = postsPane
+		case postsPane:
+			m.focus = feedsPane
+		}
+		return m, nil
+
+	case "up", "k":
+		m.move(-1)
+		return m, m.afterMove()
+
+	case "down", "j":
+		m.move(1)
+		return m, m.afterMove()
+
+	case "pgup":
+		m.move(-m.listHeight())
+		return m, m.afterMove()
+
+	case "pgdown", " ":
+		m.move(m.listHeight())
+		return m, m.afterMove()
+
+	case "enter", "right", "l":
+		switch m.focus {
+		case feedsPane:
+			m.focus = postsPane
+		case postsPane:
+			if post, ok := m.selectedPost(); ok {
+				m.focus = readerPane
+				m.scroll = 0
+				if !post.IsRead {
+					return m, m.setRead(post, true)
+				}
+			}
+		}
+		return m, nil
+
+	case "r":
+		if post, ok := m.selectedPost(); ok && m.focus != feedsPane {
+			return m, m.setRead(post, !post.IsRead)
+		}
+
+	case "s":
+		if post, ok := m.selectedPost(); ok && m.focus != feedsPane {
+			return m, m.setStarred(post, !post.IsStarred)
+		}
+
+	case "o":
+		if post, ok := m.selectedPost(); ok && m.focus != feedsPane {
+			url := post.Url
+			cmds := []tea.Cmd{func() tea.Msg {
+				if err := openBrowser(url); err != nil {
+					return errMsg{fmt.Errorf("could not open browser: %w", err)}
+				}
+				return statusMsg("opened " + url)
+			}}
+			if !post.IsRead {
+				cmds = append(cmds, m.setRead(post, true))
+			}
+			return m, tea.Batch(cmds...)
+		}
+	}
+	return m, nil
+}
+
+// move moves the cursor of the focused pane, or scrolls the reader.
+func (m *model) move(delta int) {
+	switch m.focus {
+	case feedsPane:
+		m.feedCursor = clamp(m.feedCursor+delta, 0, len(m.feeds)-1)
+	case postsPane:
+		m.postCursor = clamp(m.postCursor+delta, 0, len(m.posts)-1)
+	case readerPane:
+		m.scroll = max(m.scroll+delta, 0)
+	}
+}
+
+// afterMove reloads the post list when a different feed is selected.
+func (m *model) afterMove() tea.Cmd {
+	if m.focus != feedsPane {
+		return nil
+	}
+	m.postCursor = 0
+	return m.loadPosts()
+}
+
+// setRead updates the post in place so the list reflects it straight away,
+// and refreshes the unread counts once it is saved.
+func (m *model) setRead(post *database.GetPostsForUserRow, read bool) tea.Cmd {
+	post.IsRead = read
+	params := database.SetPostReadParams{UserID: m.user.ID, PostID: post.ID, Read: read}
+	return func() tea.Msg {
+		if err := m.db.SetPostRead(context.Background(), params); err != nil {
+			return errMsg{fmt.Errorf("could not update post: %w", err)}
+		}
+		return m.loadFeedCounts()
+	}
+}
+
+func (m *model) setStarred(post *database.GetPostsForUserRow, starred bool) tea.Cmd {
+	post.IsStarred = starred
+	params := database.SetPostStarredParams{UserID: m.user.ID, PostID: post.ID, Starred: starred}
+	return func() tea.Msg {
+		if err := m.db.SetPostStarred(context.Background(), params); err != nil {
+			return errMsg{fmt.Errorf("could not update post: %w", err)}
+		}
+		if starred {
+			return statusMsg("⭐ starred")
+		}
+		return statusMsg("unstarred")
+	}
+}
+
+// loadFeedCounts reloads the feed list for its unread counts, keeping the
+// cursor on the post being read.
+func (m *model) loadFeedCounts() tea.Msg {
+	msg := m.loadFeeds()
+	feeds, ok := msg.(feedsLoadedMsg)
+	if !ok {
+		return msg
+	}
+	return feedCountsMsg(feeds)
+}
+
+func (m *model) View() string {
+	if m.width == 0 {
+		return "loading…"
+	}
+
+	status := m.status
+	if status == "" {
+		status = helpText
+	}
+	status = statusStyle.Render(ansi.Truncate(status, m.width, "…"))
+
+	if m.focus == readerPane {
+		return lipgloss.JoinVertical(lipgloss.Left, m.viewReader(), status)
+	}
+
+	feedsWidth := max(m.width/3, 20)
+	postsWidth := max(m.width-feedsWidth, 20)
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, m.viewFeeds(feedsWidth), m.viewPosts(postsWidth)),
+		status,
+	)
+}
+
+// listHeight is the number of rows inside a pane's border.
+func (m *model) listHeight() int {
+	return max(m.height-3, 1)
+}
+
+func (m *model) viewFeeds(width int) string {
+	inner := width - 2
+	lines := make([]string, 0, len(m.feeds))
+	for _, feed := range m.feeds {
+		line := feed.Name
+		if feed.Unread > 0 {
+			line = fmt.Sprintf("%s (%d)", feed.Name, feed.Unread)
+		}
+		lines = append(lines, ansi.Truncate(line, inner, "…"))
+	}
+
+	return m.renderPane(lines, m.feedCursor, inner, m.focus == feedsPane)
+}
+
+func (m *model) viewPosts(width int) string {
+	inner := width - 2
+	lines := make([]string, 0, len(m.posts))
+	for _, post := range m.posts {
+		marker := "● "
+		if post.IsRead {
+			marker = "  "
+		}
+		if post.IsStarred {
+			marker = "★ "
+		}
+
+		line := ansi.Truncate(marker+post.Title, inner, "…")
+		if post.IsRead {
+			line = readStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, readStyle.Render("no posts yet - try running gator agg 1m"))
+	}
+
+	return m.renderPane(lines, m.postCursor, inner, m.focus == postsPane)
+}
+
+// renderPane draws the window of lines around cursor that fits the pane.
+func (m *model) renderPane(lines []string, cursor, width int, focused bool) string {
+	height := m.listHeight()
+	start := clamp(cursor-height/2, 0, max(len(lines)-height, 0))
+	end := min(start+height, len(lines))
+
+	visible := make([]string, 0, height)
+	for i := start; i < end; i++ {
+		line := lines[i]
+		if i == cursor && focused {
+			line = cursorStyle.Render(ansi.Strip(line))
+		}
+		visible = append(visible, line)
+	}
+
+	style := paneStyle
+	if focused {
+		style = focusedStyle
+	}
+	return style.Width(width).Height(height).Render(strings.Join(visible, "\n"))
+}
+
+func (m *model) viewReader() string {
+	post, ok := m.selectedPost()
+	if !ok {
+		return ""
+	}
+	inner := m.width - 2
+
+	header := []string{titleStyle.Render(post.Title), post.FeedName}
+	if post.PublishedAt.Valid {
+		header[1] += " · " + post.PublishedAt.Time.Format("Mon 2 Jan 2006 15:04")
+	}
+	header = append(header, post.Url, "")
+
+	// Prefer the saved article over the feed's description, which is
+	// often just a teaser.
+	body := post.ContentText.String
+	if body == "" {
+		body = plaintext.FromHTML(post.Description.String)
+	}
+	if body == "" {
+		body = readStyle.Render("This post has no description. Press o to open it in the browser.")
+	}
+
+	text := strings.Join(header, "\n") + "\n" + body
+	lines := strings.Split(ansi.Wordwrap(text, inner, ""), "\n")
+
+	height := m.listHeight()
+	m.scroll = min(m.scroll, max(len(lines)-height, 0))
+	lines = lines[m.scroll:min(m.scroll+height, len(lines))]
+
+	return focusedStyle.Width(inner).Height(height).Render(strings.Join(lines, "\n"))
+}
+
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
+
+// openBrowser opens rawURL with the system's URL handler. Post links come
+// from feeds, so anything but an absolute http(s) URL is refused rather than
+// handed to a program that would also open files and other schemes.
+func openBrowser(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("not a web link: %q", rawURL)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", u.String())
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", u.String())
+	default:
+		cmd = exec.Command("xdg-open", u.String())
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,19 @@
+package tui
+
+import "testing"
+
+// Test that links which aren't web pages are never handed to the system.
+func TestOpenBrowserRefusesNonWebLinks(t *testing.T) {
+	for _, link := range []string{
+		"file:///etc/passwd",
+		"javascript:alert(1)",
+		"/relative/post",
+		"https://",
+		"-a calc.exe",
+		"smb://host/share",
+	} {
+		if err := openBrowser(link); err == nil {
+			t.Fatalf("expected %q to be refused", link)
+		}
+	}
+}
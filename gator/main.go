@@ -28,7 +28,9 @@ func main() {
 	state := &commands.State{Config: &configuration, DB: dbQueries}
 	cmds := &commands.Commands{}
 	cmds.Register("login", commands.HandlerLogin)
+	cmds.Register("logout", commands.HandlerLogout)
 	cmds.Register("register", commands.HandlerRegister)
+	cmds.Register("passwd", commands.HandlerPasswd)
 	cmds.Register("reset", commands.HandlerReset)
 	cmds.Register("users", commands.HandlerGetUsers)
 	cmds.Register("agg", commands.HandlerAggregate)
@@ -37,7 +39,23 @@ func main() {
 	cmds.Register("follow", middlewareLoggedIn(commands.HandlerFollowFeed))
 	cmds.Register("following", middlewareLoggedIn(commands.HandlerFollowing))
 	cmds.Register("unfollow", middlewareLoggedIn(commands.HandlerUnfollowFeed))
+	cmds.Register("folder", middlewareLoggedIn(commands.HandlerFolder))
+	cmds.Register("tag", middlewareLoggedIn(commands.HandlerFeedTag))
 	cmds.Register("browse", middlewareLoggedIn(commands.HandlerBrowse))
+	cmds.Register("read", middlewareLoggedIn(commands.HandlerRead))
+	cmds.Register("unread", middlewareLoggedIn(commands.HandlerUnread))
+	cmds.Register("star", middlewareLoggedIn(commands.HandlerStar))
+	cmds.Register("unstar", middlewareLoggedIn(commands.HandlerUnstar))
+	cmds.Register("archive", middlewareLoggedIn(commands.HandlerArchive))
+	cmds.Register("search", middlewareLoggedIn(commands.HandlerSearch))
+	cmds.Register("extract", middlewareLoggedIn(commands.HandlerExtract))
+	cmds.Register("rule", middlewareLoggedIn(commands.HandlerRule))
+	cmds.Register("notify", middlewareLoggedIn(commands.HandlerNotify))
+	cmds.Register("tui", middlewareLoggedIn(commands.HandlerTUI))
+	cmds.Register("import", middlewareLoggedIn(commands.HandlerImport))
+	cmds.Register("export", middlewareLoggedIn(commands.HandlerExport))
+	cmds.Register("serve", commands.HandlerServe)
+	cmds.Register("token", middlewareLoggedIn(commands.HandlerToken))
 
 	args := os.Args
 	if len(args) < 2 {
@@ -2,17 +2,31 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/ckm54/go-projects/gator/internal/auth"
 	"github.com/ckm54/go-projects/gator/internal/commands"
 	"github.com/ckm54/go-projects/gator/internal/database"
 )
 
 func middlewareLoggedIn(handler func(s *commands.State, cmd commands.Command, user database.User) error) func(*commands.State, commands.Command) error {
 	return func(s *commands.State, c commands.Command) error {
-		user, err := s.DB.GetUser(context.Background(), s.Config.CurrentUserName)
+		if s.Config.SessionToken == "" {
+			return errors.New("not logged in, run: gator login <username>")
+		}
+		if !s.Config.SessionExpiresAt.IsZero() && time.Now().After(s.Config.SessionExpiresAt) {
+			return errors.New("session expired, run: gator login <username>")
+		}
+
+		user, err := s.DB.GetUserBySession(context.Background(), auth.HashToken(s.Config.SessionToken))
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("session expired or ended, run: gator login <username>")
+		}
 		if err != nil {
-			return fmt.Errorf("not logged in: %w", err)
+			return fmt.Errorf("could not check session: %w", err)
 		}
 
 		return handler(s, c, user)
@@ -27,13 +27,65 @@ SELECT
   ff.id,
   ff.created_at,
   ff.updated_at,
+  ff.feed_id,
+  ff.category,
+  ff.tags,
   u.name AS user_name,
-  f.name AS feed_name
+  f.name AS feed_name,
+  f.url AS feed_url
 FROM feed_follows ff
 JOIN users u ON ff.user_id = u.id
 JOIN feeds f ON ff.feed_id = f.id
 WHERE ff.user_id = $1
-ORDER BY ff.created_at DESC;
+ORDER BY ff.category NULLS FIRST, f.name;
 
 -- name: UnfollowFeed :exec
-DELETE FROM feed_follows WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = $2;
\ No newline at end of file
+DELETE FROM feed_follows WHERE feed_follows.user_id = $1 AND feed_follows.feed_id = $2;
+
+-- name: SetFeedFollowCategory :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    category = $3
+WHERE user_id = $1 AND feed_id = $2;
+
+-- name: MoveFeedFollowFolder :execrows
+-- Moves a folder, along with the folders inside it.
+UPDATE feed_follows
+SET updated_at = NOW(),
+    category = @new_folder::text || substr(category, length(@old_folder::text) + 1)
+WHERE user_id = @user_id
+  AND (category = @old_folder OR starts_with(category, @old_folder || '/'));
+
+-- name: AddFeedFollowTags :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    tags = ARRAY(SELECT DISTINCT unnest(tags || @tags::text[]) ORDER BY 1)
+WHERE user_id = @user_id AND feed_id = @feed_id;
+
+-- name: RemoveFeedFollowTags :execrows
+UPDATE feed_follows
+SET updated_at = NOW(),
+    tags = ARRAY(SELECT tag FROM unnest(tags) AS tag WHERE tag <> ALL(@tags::text[]) ORDER BY tag)
+WHERE user_id = @user_id AND feed_id = @feed_id;
+
+-- name: GetFeedFollowsForExport :many
+SELECT f.name, f.url, ff.category, ff.tags
+FROM feed_follows ff
+JOIN feeds f ON ff.feed_id = f.id
+WHERE ff.user_id = $1
+ORDER BY ff.category NULLS FIRST, f.name;
+
+-- name: GetFollowedFeedsWithUnread :many
+SELECT
+  feeds.id,
+  feeds.name,
+  COUNT(feed_posts.post_id) FILTER (
+    WHERE post_states.read_at IS NULL AND post_states.archived_at IS NULL
+  ) AS unread
+FROM feed_follows
+JOIN feeds ON feed_follows.feed_id = feeds.id
+LEFT JOIN feed_posts ON feed_posts.feed_id = feeds.id
+LEFT JOIN post_states ON post_states.post_id = feed_posts.post_id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+GROUP BY feeds.id, feeds.name
+ORDER BY feeds.name;
@@ -12,14 +12,40 @@ ORDER BY f.created_at DESC;
 -- name: GetFeedByUrl :one
 SELECT * FROM feeds WHERE url = $1;
 
--- name: MarkFeedFetched :exec
+-- name: GetFeedById :one
+SELECT * FROM feeds WHERE id = $1;
+
+-- name: ClaimNextFeed :one
+UPDATE feeds
+SET updated_at = NOW(),
+    last_fetched_at = NOW(),
+    next_fetch_at = NOW() + make_interval(secs => fetch_interval_seconds)
+WHERE id = (
+  SELECT id
+  FROM feeds
+  WHERE active
+    AND (next_fetch_at IS NULL OR next_fetch_at <= NOW())
+  ORDER BY next_fetch_at ASC NULLS FIRST
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: RecordFeedFetch :exec
 UPDATE feeds
 SET updated_at = NOW(),
-    last_fetched_at = NOW()
+    etag = $2,
+    last_modified = $3,
+    last_status = $4,
+    error_count = $5,
+    last_error = $6,
+    next_fetch_at = $7,
+    active = $8,
+    fetch_interval_seconds = $9
 WHERE id = $1;
 
--- name: GetNextFeedToFetch :one
-SELECT *
-FROM feeds
-ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
+-- name: UpdateFeedUrl :exec
+UPDATE feeds
+SET updated_at = NOW(),
+    url = $2
+WHERE id = $1;
@@ -0,0 +1,75 @@
+-- name: CreateNotificationSink :one
+INSERT INTO notification_sinks (id, created_at, user_id, kind, target, args, digest_interval_seconds)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetNotificationSinksForUser :many
+SELECT
+  notification_sinks.*,
+  (SELECT COUNT(*) FROM notification_queue WHERE notification_queue.sink_id = notification_sinks.id) AS queued
+FROM notification_sinks
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: DeleteNotificationSink :exec
+DELETE FROM notification_sinks WHERE id = $1 AND user_id = $2;
+
+-- name: QueueNotification :exec
+INSERT INTO notification_queue (sink_id, post_id)
+SELECT id, @post_id FROM notification_sinks WHERE user_id = @user_id
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimDueNotificationSink :one
+-- Claims a sink whose digest is due, holding it for ten minutes so other
+-- gator processes leave it alone while the digest is sent.
+UPDATE notification_sinks
+SET next_digest_at = NOW() + INTERVAL '10 minutes'
+WHERE id = (
+  SELECT id
+  FROM notification_sinks
+  WHERE next_digest_at <= NOW()
+    AND kind = ANY(@kinds::text[])
+    AND EXISTS (SELECT 1 FROM notification_queue WHERE notification_queue.sink_id = notification_sinks.id)
+  ORDER BY next_digest_at
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: GetQueuedNotifications :many
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  COALESCE(source.feed_name, '')::text AS feed_name
+FROM notification_queue
+JOIN posts ON notification_queue.post_id = posts.id
+LEFT JOIN LATERAL (
+  SELECT feeds.name AS feed_name
+  FROM feed_posts
+  JOIN feeds ON feed_posts.feed_id = feeds.id
+  WHERE feed_posts.post_id = posts.id
+  ORDER BY feed_posts.created_at
+  LIMIT 1
+) source ON TRUE
+WHERE notification_queue.sink_id = $1
+ORDER BY notification_queue.created_at, posts.id;
+
+-- name: DeleteQueuedNotifications :exec
+DELETE FROM notification_queue WHERE sink_id = @sink_id AND post_id = ANY(@post_ids::uuid[]);
+
+-- name: RecordDigestSent :exec
+UPDATE notification_sinks
+SET last_sent_at = NOW(),
+    next_digest_at = NOW() + make_interval(secs => digest_interval_seconds),
+    error_count = 0,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: RecordDigestFailure :exec
+UPDATE notification_sinks
+SET next_digest_at = $2,
+    error_count = $3,
+    last_error = $4
+WHERE id = $1;
@@ -0,0 +1,28 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (user_id, post_id, read_at)
+VALUES (@user_id, @post_id, CASE WHEN @read::bool THEN NOW() END)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET updated_at = NOW(),
+    read_at = CASE WHEN @read::bool THEN COALESCE(post_states.read_at, NOW()) END;
+
+-- name: SetPostStarred :exec
+INSERT INTO post_states (user_id, post_id, starred_at)
+VALUES (@user_id, @post_id, CASE WHEN @starred::bool THEN NOW() END)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET updated_at = NOW(),
+    starred_at = CASE WHEN @starred::bool THEN COALESCE(post_states.starred_at, NOW()) END;
+
+-- name: SetPostArchived :exec
+INSERT INTO post_states (user_id, post_id, archived_at)
+VALUES (@user_id, @post_id, CASE WHEN @archived::bool THEN NOW() END)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET updated_at = NOW(),
+    archived_at = CASE WHEN @archived::bool THEN COALESCE(post_states.archived_at, NOW()) END;
+
+-- name: FindPostsForUserByPrefix :many
+SELECT DISTINCT posts.id, posts.title, posts.url
+FROM posts
+JOIN feed_posts ON posts.id = feed_posts.post_id
+JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+WHERE feed_follows.user_id = @user_id AND posts.id::text LIKE @prefix::text || '%'
+LIMIT 2;
@@ -5,15 +5,60 @@ INSERT INTO posts (
   updated_at,
   title,
   url,
+  normalized_url,
+  content_hash,
   description,
   published_at,
-  feed_id
+  author,
+  categories
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (normalized_url) DO NOTHING
 RETURNING *;
 
+-- name: GetPostByNormalizedUrl :one
+SELECT * FROM posts WHERE normalized_url = $1;
+
+-- name: GetFeedPostByGuid :one
+SELECT post_id FROM feed_posts WHERE feed_id = $1 AND guid = $2;
+
+-- name: GetFeedPostByContentHash :one
+SELECT feed_posts.post_id
+FROM feed_posts
+JOIN posts ON feed_posts.post_id = posts.id
+WHERE feed_posts.feed_id = $1 AND posts.content_hash = $2
+LIMIT 1;
+
+-- name: LinkPostToFeed :execrows
+INSERT INTO feed_posts (feed_id, post_id, created_at, guid)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (feed_id, post_id) DO NOTHING;
+
+-- name: UpdateFeedPostGuid :exec
+UPDATE feed_posts SET guid = @guid
+WHERE feed_id = @feed_id AND post_id = @post_id AND guid <> ALL(@fetched_guids::text[]);
+
+-- name: GetPostsToBackfill :many
+SELECT id, title, url, description FROM posts
+WHERE content_hash = ''
+ORDER BY id
+LIMIT $1;
+
+-- name: BackfillPostKeys :exec
+UPDATE posts
+SET content_hash = @content_hash,
+    normalized_url = CASE
+      WHEN EXISTS (
+        SELECT 1 FROM posts other
+        WHERE other.normalized_url = @normalized_url AND other.id <> posts.id
+      ) THEN posts.normalized_url
+      ELSE @normalized_url
+    END
+WHERE id = @id;
+
 -- name: GetPostsForUser :many
-SELECT 
+-- A post's tags are its own and those of the feeds it came from.
+SELECT
   posts.id,
   posts.created_at,
   posts.updated_at,
@@ -21,11 +66,107 @@ SELECT
   posts.url,
   posts.description,
   posts.published_at,
-  posts.feed_id,
-  feeds.name as feed_name
+  posts.content_html,
+  posts.content_text,
+  source.feed_id,
+  source.feed_name,
+  post_states.read_at IS NOT NULL AS is_read,
+  post_states.starred_at IS NOT NULL AS is_starred,
+  ARRAY(
+    SELECT post_tags.tag FROM post_tags
+    WHERE post_tags.post_id = posts.id AND post_tags.user_id = @user_id
+    UNION
+    SELECT unnest(feed_follows.tags) FROM feed_posts
+    JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+    WHERE feed_posts.post_id = posts.id AND feed_follows.user_id = @user_id
+    ORDER BY 1
+  )::text[] AS tags
+FROM posts
+JOIN LATERAL (
+  SELECT feeds.id AS feed_id, feeds.name AS feed_name
+  FROM feed_posts
+  JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+  JOIN feeds ON feed_posts.feed_id = feeds.id
+  WHERE feed_posts.post_id = posts.id
+    AND feed_follows.user_id = @user_id
+    AND (sqlc.narg('feed_id')::uuid IS NULL OR feed_posts.feed_id = sqlc.narg('feed_id'))
+    AND (sqlc.narg('folder')::text IS NULL
+      OR feed_follows.category = sqlc.narg('folder')
+      OR starts_with(feed_follows.category, sqlc.narg('folder') || '/'))
+  ORDER BY feed_posts.created_at
+  LIMIT 1
+) source ON TRUE
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = @user_id
+WHERE (NOT @unread_only::bool OR post_states.read_at IS NULL)
+  AND (NOT @starred_only::bool OR post_states.starred_at IS NOT NULL)
+  AND (@include_archived::bool OR post_states.archived_at IS NULL)
+  AND (sqlc.narg('tag')::text IS NULL
+    OR EXISTS (
+      SELECT 1 FROM post_tags
+      WHERE post_tags.post_id = posts.id AND post_tags.user_id = @user_id AND post_tags.tag = sqlc.narg('tag')
+    )
+    OR EXISTS (
+      SELECT 1 FROM feed_posts
+      JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+      WHERE feed_posts.post_id = posts.id AND feed_follows.user_id = @user_id AND sqlc.narg('tag') = ANY(feed_follows.tags)
+    ))
+ORDER BY posts.published_at DESC NULLS LAST, posts.id
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: SearchPosts :many
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  source.feed_id,
+  source.feed_name,
+  ts_rank_cd(posts.search, query)::real AS rank,
+  ts_headline(
+    'english',
+    coalesce(posts.description, ''),
+    query,
+    'MaxFragments=1, MinWords=10, MaxWords=25, StartSel=«, StopSel=»'
+  ) AS snippet
 FROM posts
-JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
-JOIN feeds ON posts.feed_id = feeds.id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC NULLS LAST
-LIMIT $2;
\ No newline at end of file
+CROSS JOIN websearch_to_tsquery('english', @query) AS query
+JOIN LATERAL (
+  SELECT feeds.id AS feed_id, feeds.name AS feed_name
+  FROM feed_posts
+  JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+  JOIN feeds ON feed_posts.feed_id = feeds.id
+  WHERE feed_posts.post_id = posts.id
+    AND feed_follows.user_id = @user_id
+    AND (sqlc.narg('feed_id')::uuid IS NULL OR feed_posts.feed_id = sqlc.narg('feed_id'))
+  ORDER BY feed_posts.created_at
+  LIMIT 1
+) source ON TRUE
+WHERE posts.search @@ query
+  AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since'))
+  AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until'))
+ORDER BY
+  CASE WHEN @by_date::bool THEN posts.published_at END DESC NULLS LAST,
+  rank DESC,
+  posts.published_at DESC NULLS LAST
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: ClaimPostsForExtraction :many
+UPDATE posts
+SET extract_attempted_at = NOW()
+WHERE id IN (
+  SELECT id FROM posts
+  WHERE extract_attempted_at IS NULL
+  ORDER BY created_at DESC
+  LIMIT $1
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, title, url;
+
+-- name: SetPostContent :exec
+UPDATE posts
+SET content_html = $2,
+    content_text = $3,
+    extracted_at = NOW(),
+    extract_attempted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
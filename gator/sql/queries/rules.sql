@@ -0,0 +1,47 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, user_id, condition, action, tag)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT * FROM rules WHERE user_id = $1 ORDER BY created_at;
+
+-- name: DeleteRule :exec
+DELETE FROM rules WHERE id = $1 AND user_id = $2;
+
+-- name: GetRulesForFeed :many
+SELECT rules.*
+FROM rules
+JOIN feed_follows ON feed_follows.user_id = rules.user_id
+WHERE feed_follows.feed_id = $1
+ORDER BY rules.user_id, rules.created_at;
+
+-- name: GetPostsForRuleTest :many
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.content_text,
+  posts.author,
+  posts.categories,
+  source.feed_name,
+  source.feed_url
+FROM posts
+JOIN LATERAL (
+  SELECT feeds.name AS feed_name, feeds.url AS feed_url
+  FROM feed_posts
+  JOIN feed_follows ON feed_posts.feed_id = feed_follows.feed_id
+  JOIN feeds ON feed_posts.feed_id = feeds.id
+  WHERE feed_posts.post_id = posts.id
+    AND feed_follows.user_id = @user_id
+  ORDER BY feed_posts.created_at
+  LIMIT 1
+) source ON TRUE
+ORDER BY posts.published_at DESC NULLS LAST, posts.id
+LIMIT @row_limit;
+
+-- name: TagPost :exec
+INSERT INTO post_tags (user_id, post_id, tag)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
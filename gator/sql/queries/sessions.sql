@@ -0,0 +1,18 @@
+-- name: CreateSession :one
+INSERT INTO sessions (token_hash, created_at, user_id, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetUserBySession :one
+SELECT users.* FROM sessions
+JOIN users ON users.id = sessions.user_id
+WHERE sessions.token_hash = $1 AND sessions.expires_at > NOW();
+
+-- name: DeleteSession :exec
+DELETE FROM sessions WHERE token_hash = $1;
+
+-- name: DeleteExpiredSessions :exec
+DELETE FROM sessions WHERE expires_at <= NOW();
+
+-- name: DeleteUserSessions :exec
+DELETE FROM sessions WHERE user_id = $1;
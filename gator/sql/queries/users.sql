@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, name)
-VALUES ($1, $2, $3, $4)
+INSERT INTO users (id, created_at, updated_at, name, hashed_password)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetUser :one
@@ -9,5 +9,13 @@ SELECT * FROM users WHERE name = $1;
 -- name: GetUsers :many
 SELECT * FROM users;
 
+-- name: SetUserPassword :exec
+UPDATE users SET hashed_password = $2, updated_at = NOW() WHERE id = $1;
+
 -- name: DeleteUsers :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: OwnsUsersTable :one
+SELECT pg_has_role(current_user, tableowner, 'MEMBER')::boolean
+FROM pg_tables
+WHERE schemaname = current_schema() AND tablename = 'users';
@@ -0,0 +1,19 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN etag TEXT,
+ADD COLUMN last_modified TEXT,
+ADD COLUMN last_status INTEGER,
+ADD COLUMN error_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN last_error TEXT,
+ADD COLUMN next_fetch_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN active BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN etag,
+DROP COLUMN last_modified,
+DROP COLUMN last_status,
+DROP COLUMN error_count,
+DROP COLUMN last_error,
+DROP COLUMN next_fetch_at,
+DROP COLUMN active;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN fetch_interval_seconds INTEGER NOT NULL DEFAULT 3600;
+
+CREATE INDEX feeds_next_fetch_at_idx ON feeds (next_fetch_at) WHERE active;
+
+-- +goose Down
+DROP INDEX feeds_next_fetch_at_idx;
+
+ALTER TABLE feeds
+DROP COLUMN fetch_interval_seconds;
@@ -0,0 +1,50 @@
+-- +goose Up
+CREATE TABLE feed_posts (
+  feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  guid TEXT NOT NULL,
+  PRIMARY KEY (feed_id, post_id),
+  UNIQUE (feed_id, guid)
+);
+
+INSERT INTO feed_posts (feed_id, post_id, created_at, guid)
+SELECT feed_id, id, created_at, url FROM posts;
+
+ALTER TABLE posts
+DROP CONSTRAINT posts_url_key,
+DROP COLUMN feed_id,
+ADD COLUMN normalized_url TEXT,
+ADD COLUMN content_hash TEXT NOT NULL DEFAULT '';
+
+-- URLs can only be normalized the way gator does it in Go. The aggregator
+-- replaces these raw URLs and fills in content_hash for every post whose
+-- hash is still empty when it starts (see backfillPostKeys).
+UPDATE posts SET normalized_url = url;
+
+ALTER TABLE posts
+ALTER COLUMN normalized_url SET NOT NULL,
+ADD CONSTRAINT posts_normalized_url_key UNIQUE (normalized_url);
+
+CREATE INDEX posts_content_hash_idx ON posts (content_hash);
+
+-- +goose Down
+ALTER TABLE posts
+ADD COLUMN feed_id UUID REFERENCES feeds(id) ON DELETE CASCADE;
+
+UPDATE posts SET feed_id = (
+  SELECT feed_id FROM feed_posts
+  WHERE feed_posts.post_id = posts.id
+  ORDER BY created_at
+  LIMIT 1
+);
+
+DELETE FROM posts WHERE feed_id IS NULL;
+
+ALTER TABLE posts
+ALTER COLUMN feed_id SET NOT NULL,
+DROP COLUMN normalized_url,
+DROP COLUMN content_hash,
+ADD CONSTRAINT posts_url_key UNIQUE (url);
+
+DROP TABLE feed_posts;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- category holds the follow's folder path, such as "Tech/Go".
+ALTER TABLE feed_follows
+ADD COLUMN category TEXT;
+
+-- +goose Down
+ALTER TABLE feed_follows
+DROP COLUMN category;
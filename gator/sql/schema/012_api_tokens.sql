@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+  id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  token_hash TEXT UNIQUE NOT NULL,
+  last_used_at TIMESTAMP WITH TIME ZONE
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Existing users have no password yet; the database owner sets one with
+-- `gator passwd` before they can log in.
+ALTER TABLE users ADD COLUMN hashed_password TEXT;
+
+CREATE TABLE sessions (
+  token_hash TEXT PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+-- +goose Down
+DROP TABLE sessions;
+ALTER TABLE users DROP COLUMN hashed_password;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE posts
+  ADD COLUMN content_html TEXT,
+  ADD COLUMN content_text TEXT,
+  ADD COLUMN extracted_at TIMESTAMP WITH TIME ZONE,
+  ADD COLUMN extract_attempted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX posts_extract_pending_idx ON posts (created_at) WHERE extract_attempted_at IS NULL;
+
+-- +goose Down
+DROP INDEX posts_extract_pending_idx;
+ALTER TABLE posts
+  DROP COLUMN content_html,
+  DROP COLUMN content_text,
+  DROP COLUMN extracted_at,
+  DROP COLUMN extract_attempted_at;
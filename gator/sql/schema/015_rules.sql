@@ -0,0 +1,30 @@
+-- +goose Up
+ALTER TABLE posts
+  ADD COLUMN author TEXT,
+  ADD COLUMN categories TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE rules (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  condition TEXT NOT NULL,
+  action TEXT NOT NULL,
+  tag TEXT
+);
+
+CREATE INDEX rules_user_id_idx ON rules (user_id);
+
+CREATE TABLE post_tags (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  tag TEXT NOT NULL,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  PRIMARY KEY (user_id, post_id, tag)
+);
+
+-- +goose Down
+DROP TABLE post_tags;
+DROP TABLE rules;
+ALTER TABLE posts
+  DROP COLUMN author,
+  DROP COLUMN categories;
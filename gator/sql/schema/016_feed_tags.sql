@@ -0,0 +1,13 @@
+-- +goose Up
+-- tags are the user's labels for a feed they follow; unlike its folder, a
+-- follow can have any number of them.
+ALTER TABLE feed_follows
+ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX feed_follows_user_id_category_idx ON feed_follows (user_id, category);
+
+-- +goose Down
+DROP INDEX feed_follows_user_id_category_idx;
+
+ALTER TABLE feed_follows
+DROP COLUMN tags;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE notification_sinks (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  -- kind is webhook, email or command. target is the URL, the address or
+  -- the program, and args the program's arguments.
+  kind TEXT NOT NULL,
+  target TEXT NOT NULL,
+  args TEXT[] NOT NULL DEFAULT '{}',
+  digest_interval_seconds INTEGER NOT NULL DEFAULT 0,
+  next_digest_at TIMESTAMP WITH TIME ZONE DEFAULT NOW() NOT NULL,
+  last_sent_at TIMESTAMP WITH TIME ZONE,
+  error_count INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT
+);
+
+CREATE INDEX notification_sinks_user_id_idx ON notification_sinks (user_id);
+
+-- Posts waiting to go out in a sink's next digest.
+CREATE TABLE notification_queue (
+  sink_id UUID NOT NULL REFERENCES notification_sinks(id) ON DELETE CASCADE,
+  post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITHOUT TIME ZONE DEFAULT NOW() NOT NULL,
+  PRIMARY KEY (sink_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE notification_queue;
+DROP TABLE notification_sinks;